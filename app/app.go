@@ -3,22 +3,38 @@ package app
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+
+	wiki_controller "wiki-names/controllers"
+	wiki_locale "wiki-names/locales"
+	wiki_rpc "wiki-names/rpcs"
+	wiki_watch "wiki-names/watchers"
+	wiki_webhook "wiki-names/webhooks"
 )
 
 var (
 	router  = gin.Default()
 	address = ":8080"
+	// grpcAddress is where the gRPC service listens, GRPC_ADDRESS overrides it
+	grpcAddress = ":9090"
 )
 
 func RunApp() {
-	srv := SetupRouter(address)
+	srv, auth := SetupRouter(address)
+	// The gRPC calls go through the same API key, bearer token, tenant, scope and rate limit checks
+	grpcServer, grpcHealth := wiki_rpc.NewServer(auth)
+	if value := os.Getenv("GRPC_ADDRESS"); value != "" {
+		grpcAddress = value
+	}
 
 	// Create context that listens for the interrupt signal from the OS.
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -26,6 +42,44 @@ func RunApp() {
 
 	log.Printf("Listening on port %v\n", address)
 
+	// Swap the bundled locale snapshot for the live list of Wikipedia editions
+	go func() {
+		if err := wiki_locale.Locales.Load(); err != nil {
+			log.Printf("Using bundled locale snapshot, sitematrix failed: %s", err.Error())
+		}
+	}()
+
+	// Keep the configured hot names warm until the server is asked to stop
+	if scheduler := NewHotNamesSchedulerFromEnv(); scheduler != nil {
+		scheduler.Handler = srv.Handler
+		go scheduler.Run(ctx)
+	}
+
+	// Poll the names /watch subscribers are following, WATCH_* overrides the defaults
+	watcher := wiki_watch.Watcher
+	watcher.Interval = envDuration("WATCH_INTERVAL", watcher.Interval)
+	watcher.Heartbeat = envDuration("WATCH_HEARTBEAT", watcher.Heartbeat)
+	watcher.MaxSubscribers = envInt("WATCH_MAX_SUBSCRIBERS", watcher.MaxSubscribers)
+	watcher.MaxNames = envInt("WATCH_MAX_NAMES", watcher.MaxNames)
+	for _, origin := range strings.Split(os.Getenv("WATCH_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			wiki_controller.WatchOrigins = append(wiki_controller.WatchOrigins, origin)
+		}
+	}
+	go watcher.Run(ctx)
+
+	// Webhooks follow their names on the same hub and are kept in WEBHOOKS_FILE across restarts
+	webhooks := wiki_webhook.Webhooks
+	webhooks.Store = &wiki_webhook.FileStore{Path: envString("WEBHOOKS_FILE", "webhooks.json")}
+	if err := webhooks.Load(); err != nil {
+		log.Fatalf("webhooks: %s\n", err)
+	}
+	webhooksStopped := make(chan struct{})
+	go func(ctx context.Context) {
+		webhooks.Run(ctx)
+		close(webhooksStopped)
+	}(ctx)
+
 	// Initializing the server in a goroutine so that
 	// it won't block the graceful shutdown handling below
 	go func() {
@@ -35,9 +89,21 @@ func RunApp() {
 		}
 	}()
 
+	// The gRPC service shares the WikiProvider with the HTTP one
+	go func() {
+		listener, err := net.Listen("tcp", grpcAddress)
+		if err != nil {
+			log.Fatalf("grpc listen: %s\n", err)
+		}
+		log.Printf("gRPC listening on port %v\n", grpcAddress)
+		if err := grpcServer.Serve(listener); err != nil && err != grpc.ErrServerStopped {
+			log.Fatalf("grpc serve: %s\n", err)
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server with
 	// a timeout of 5 seconds.
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	// kill (no param) default send syscall.SIGTERM
 	// kill -2 is syscall.SIGINT
 	// kill -9 is syscall. SIGKILL but can"t be catch, so don't need add it
@@ -47,9 +113,29 @@ func RunApp() {
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
+	// Tell health checks we are going away, then let the in-flight RPCs finish
+	grpcHealth.Shutdown()
+	// End the /watch streams, Shutdown would otherwise wait on them until the timeout
+	watcher.Close()
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server Shutdown:", err)
 	}
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		// Streams still open after the timeout are cut
+		grpcServer.Stop()
+	}
+	// The signal has stopped the webhooks too, wait for the deliveries in flight to be saved
+	select {
+	case <-webhooksStopped:
+	case <-ctx.Done():
+	}
 	// catching ctx.Done(). timeout of 5 seconds.
 	select {
 	case <-ctx.Done():
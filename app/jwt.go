@@ -0,0 +1,42 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	wiki_token "wiki-names/tokens"
+)
+
+// NewVerifierFromEnv reads the JWT_* settings, it returns nil when neither JWT_JWKS_URL nor
+// JWT_JWKS_FILE is set. JWT_ISSUER and JWT_AUDIENCE are required with them.
+func NewVerifierFromEnv() (*wiki_token.Verifier, error) {
+	url, file := os.Getenv("JWT_JWKS_URL"), os.Getenv("JWT_JWKS_FILE")
+	if url == "" && file == "" {
+		return nil, nil
+	}
+	if url != "" && file != "" {
+		return nil, errors.New("set JWT_JWKS_URL or JWT_JWKS_FILE, not both")
+	}
+	issuer, audience := os.Getenv("JWT_ISSUER"), os.Getenv("JWT_AUDIENCE")
+	if issuer == "" || audience == "" {
+		return nil, errors.New("JWT_ISSUER and JWT_AUDIENCE are required")
+	}
+	keys := wiki_token.NewKeySet(url, file)
+	if value := os.Getenv("JWT_JWKS_REFRESH"); value != "" {
+		refresh, err := time.ParseDuration(value)
+		if err != nil || refresh <= 0 {
+			return nil, fmt.Errorf("JWT_JWKS_REFRESH must be a duration like 1h, not %q", value)
+		}
+		keys.Refresh = refresh
+	}
+	verifier := wiki_token.NewVerifier(keys, issuer, audience)
+	if claim := os.Getenv("JWT_TENANT_CLAIM"); claim != "" {
+		verifier.TenantClaim = claim
+	}
+	if claim := os.Getenv("JWT_SCOPE_CLAIM"); claim != "" {
+		verifier.ScopeClaim = claim
+	}
+	return verifier, nil
+}
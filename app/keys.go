@@ -0,0 +1,53 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis/v8"
+
+	wiki_key "wiki-names/keys"
+)
+
+var (
+	redisOnce   sync.Once
+	redisClient *redis.Client
+)
+
+// sharedRedis is the one client on REDISHOST for everything kept in Redis
+func sharedRedis() *redis.Client {
+	redisOnce.Do(func() {
+		redisClient = redis.NewClient(&redis.Options{
+			Network: "tcp",
+			Addr:    os.Getenv("REDISHOST"),
+		})
+	})
+	return redisClient
+}
+
+// NewKeyManagerFromEnv reads the API_KEYS_* settings, it returns nil when API_KEYS_STORE isn't set.
+// API_KEYS_STORE is file:<path>, with the counters in memory, or redis, with the counters in Redis too.
+func NewKeyManagerFromEnv() (*wiki_key.Manager, error) {
+	source := os.Getenv("API_KEYS_STORE")
+	var manager *wiki_key.Manager
+	switch {
+	case source == "":
+		return nil, nil
+	case source == "redis":
+		client := sharedRedis()
+		manager = wiki_key.NewManager(&wiki_key.RedisStore{Client: client}, &wiki_key.RedisCounters{Client: client})
+	case strings.HasPrefix(source, "file:"):
+		store, err := wiki_key.NewFileStore(strings.TrimPrefix(source, "file:"))
+		if err != nil {
+			return nil, err
+		}
+		manager = wiki_key.NewManager(store, wiki_key.NewMemoryCounters())
+	default:
+		return nil, fmt.Errorf("API_KEYS_STORE must be file:<path> or redis, not %q", source)
+	}
+	manager.DefaultRateLimit = envInt("API_KEYS_RATE_LIMIT", manager.DefaultRateLimit)
+	manager.DefaultDailyQuota = envInt("API_KEYS_DAILY_QUOTA", manager.DefaultDailyQuota)
+	return manager, nil
+}
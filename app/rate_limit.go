@@ -0,0 +1,43 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	wiki_key "wiki-names/keys"
+	wiki_middleware "wiki-names/middlewares"
+)
+
+// NewRateLimiterFromEnv reads the RATE_LIMIT_* settings, it returns nil when RATE_LIMIT_BY isn't set.
+// RATE_LIMIT_STORE=redis shares the counts between instances, they are kept in memory otherwise.
+func NewRateLimiterFromEnv() (*wiki_middleware.RateLimiter, error) {
+	by := os.Getenv("RATE_LIMIT_BY")
+	switch by {
+	case "":
+		return nil, nil
+	case wiki_middleware.LimitByIP, wiki_middleware.LimitByAPIKey, wiki_middleware.LimitByForwarded:
+	default:
+		return nil, fmt.Errorf("RATE_LIMIT_BY must be ip, api_key or forwarded, not %q", by)
+	}
+	var counters wiki_key.Counters = wiki_key.NewMemoryCounters()
+	if os.Getenv("RATE_LIMIT_STORE") == "redis" {
+		counters = &wiki_key.RedisCounters{Client: sharedRedis()}
+	}
+	limiter := wiki_middleware.NewRateLimiter(counters, by)
+	limiter.TrustedHops = envInt("RATE_LIMIT_TRUSTED_HOPS", limiter.TrustedHops)
+	if value := os.Getenv("RATE_LIMIT_DEFAULT"); value != "" {
+		limit, err := wiki_middleware.ParseRateLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("RATE_LIMIT_DEFAULT: %w", err)
+		}
+		limiter.Default = limit
+	}
+	routes, err := wiki_middleware.ParseRateLimits(os.Getenv("RATE_LIMIT_ROUTES"))
+	if err != nil {
+		return nil, fmt.Errorf("RATE_LIMIT_ROUTES: %w", err)
+	}
+	for route, limit := range routes {
+		limiter.Routes[route] = limit
+	}
+	return limiter, nil
+}
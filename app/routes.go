@@ -1,54 +1,218 @@
 package app
 
 import (
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	cache "github.com/chenyahui/gin-cache"
 	"github.com/chenyahui/gin-cache/persist"
 	"github.com/gin-gonic/gin"
-	"github.com/go-redis/redis/v8"
 	"github.com/joho/godotenv"
 
 	"wiki-names/controllers"
 	"wiki-names/docs"
+	"wiki-names/domains"
+	"wiki-names/keys"
+	"wiki-names/middlewares"
+	"wiki-names/rpcs"
+	"wiki-names/tenants"
+	"wiki-names/tokens"
 
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-func SetupRouter(address string) *http.Server {
+// SetupRouter builds the HTTP server, and the checks the gRPC server shares with its middleware
+func SetupRouter(address string) (*http.Server, *wiki_rpc.Auth) {
 	log.Println("Starting server...")
 	router := gin.Default()
+	router.Use(wiki_middleware.RequestID())
 	docs.SwaggerInfo.BasePath = "/"
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatal("Error loading .env file")
 	}
 
+	// API keys are checked before the cache, so a cached response still needs a key and counts against its limits
+	keys, err := NewKeyManagerFromEnv()
+	if err != nil {
+		log.Fatalf("API keys: %s", err)
+	}
+	wiki_key.Keys = keys
+	api := router.Group("")
+	auth := &wiki_rpc.Auth{Keys: keys, KeysRequired: os.Getenv("API_KEYS_REQUIRED") == "true", Tenants: wiki_tenant.Tenants}
+	if keys != nil {
+		api.Use(wiki_middleware.APIKey(keys, auth.KeysRequired))
+	}
+	// Bearer tokens from our identity provider, checked against its JWKS, carry a tenant and scopes
+	verifier, err := NewVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("JWT: %s", err)
+	}
+	if verifier != nil {
+		api.Use(wiki_middleware.JWT(verifier))
+		auth.Verifier = verifier
+	}
+	// Each key's tenant picks its cache TTL, locales, facets and batch size, from TENANTS_FILE
+	if path := os.Getenv("TENANTS_FILE"); path != "" {
+		if err := wiki_tenant.Tenants.Load(path); err != nil {
+			log.Fatalf("Tenants: %s", err)
+		}
+	}
+	api.Use(wiki_middleware.Tenant(wiki_tenant.Tenants))
+	// Rate limits apply to cached responses too, bots shouldn't get to hammer us through the cache
+	limiter, err := NewRateLimiterFromEnv()
+	if err != nil {
+		log.Fatalf("Rate limit: %s", err)
+	}
+	if limiter != nil {
+		api.Use(limiter.Handler())
+		auth.Limiter = limiter
+	}
+
 	// Setup a results cache for 2 minutes per URI, stored in Memory (DEV and PRE) or Redis in Production
-	var memoryStore *persist.MemoryStore
-	var redisStore *persist.RedisStore
+	var cached gin.HandlerFunc
 	if os.Getenv("APP_ENV") == "dev" {
-		memoryStore = persist.NewMemoryStore(1 * time.Minute)
-		router.Use(cache.CacheByRequestURI(memoryStore, 2*time.Second))
+		cached = newCache(persist.NewMemoryStore(1 * time.Minute))
 	} else {
 		// In Production, speed up caching by using Redis storage instead
-		redisStore = persist.NewRedisStore(redis.NewClient(&redis.Options{
-			Network: "tcp",
-			Addr:    os.Getenv("REDISHOST"),
-		}))
-		router.Use(cache.CacheByRequestURI(redisStore, 2*time.Second))
-	}
-	router.GET("search/:name", wiki_controller.GetContentSummary)
-	router.GET("extract/:name", wiki_controller.GetExtract)
-	router.GET("extract/:name/:locale", wiki_controller.GetExtract)
+		cached = newCache(persist.NewRedisStore(sharedRedis()))
+	}
+	// With bearer tokens configured, each group needs its scope, checked before the cache
+	lookups := api.Group("", requireScope(verifier, wiki_domain.ScopeLookupRead), cached)
+	batches := api.Group("", requireScope(verifier, wiki_domain.ScopeBatchWrite), cached)
+	lookups.GET("search/:name", wiki_controller.GetContentSummary)
+	batches.POST("search/batch", wiki_controller.SearchBatch)
+	lookups.GET("extract/:name", wiki_controller.GetExtract)
+	lookups.GET("extract/:name/:locale", wiki_controller.GetExtract)
+	lookups.GET("bio/:name", wiki_controller.GetBio)
+	lookups.GET("history/:name", wiki_controller.GetHistory)
+	lookups.GET("history/:name/:locale", wiki_controller.GetHistory)
+	lookups.GET("categories/:name", wiki_controller.GetCategories)
+	lookups.GET("categories/:name/:locale", wiki_controller.GetCategories)
+	lookups.GET("category/:category/members", wiki_controller.GetCategoryMembers)
+	lookups.GET("graphql", wiki_controller.GraphQL)
+	lookups.POST("graphql", wiki_controller.GraphQL)
+	lookups.GET("watch", wiki_controller.Watch)
+	webhookRoutes(api)
+	admin := router.Group("admin")
+	if verifier != nil {
+		// A token granting the admin scope opens the admin routes as well as ADMIN_TOKEN does
+		admin.Use(wiki_middleware.JWT(verifier))
+	}
+	admin.Use(wiki_middleware.AdminToken(os.Getenv("ADMIN_TOKEN")))
+	admin.GET("webhooks", wiki_controller.ListWebhooks)
+	admin.GET("deliveries", wiki_controller.ListDeliveries)
+	admin.GET("dead-letters", wiki_controller.ListDeadLetters)
+	admin.POST("dead-letters/:id/retry", wiki_controller.RetryDeadLetter)
+	admin.DELETE("dead-letters/:id", wiki_controller.DiscardDeadLetter)
+	admin.POST("keys", wiki_controller.IssueKey)
+	admin.GET("keys", wiki_controller.ListKeys)
+	admin.GET("keys/:id", wiki_controller.GetKey)
+	admin.POST("keys/:id/rotate", wiki_controller.RotateKey)
+	admin.DELETE("keys/:id", wiki_controller.RevokeKey)
+	admin.GET("keys/:id/usage", wiki_controller.GetKeyUsage)
+	router.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
 	return &http.Server{
 		Addr:    address,
 		Handler: router,
+	}, auth
+}
+
+// newCache caches the responses chosen by cacheStrategy in the store, without their per request headers.
+// A warm-up always refreshes its entry, which is kept until after the next warm-up is due.
+func newCache(store persist.CacheStore) gin.HandlerFunc {
+	responses := &responseStore{store}
+	return cache.Cache(responses, 2*time.Second, cache.WithCacheStrategyByRequest(func(c *gin.Context) (bool, cache.Strategy) {
+		cached, strategy := cacheStrategy(c)
+		if ttl, ok := warmTTL(c.Request.Context()); ok && cached {
+			strategy.CacheStore = &refreshStore{responses}
+			if ttl > strategy.CacheDuration {
+				strategy.CacheDuration = ttl
+			}
+		}
+		return cached, strategy
+	}))
+}
+
+// refreshStore never finds a response, so the request is served again and replaces the stored one
+type refreshStore struct {
+	persist.CacheStore
+}
+
+func (s *refreshStore) Get(string, interface{}) error {
+	return persist.ErrCacheMiss
+}
+
+// perRequestHeaders are set by the middleware before the cache for each request. A cached
+// response replays the headers it was stored with over the current ones, so they are left out.
+var perRequestHeaders = []string{wiki_middleware.RequestIDHeader, "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"}
+
+// responseStore drops the per request headers of the responses it stores. The response is only
+// shared with the requests waiting on it once it is stored, so it is changed in place.
+type responseStore struct {
+	persist.CacheStore
+}
+
+func (s *responseStore) Set(key string, value interface{}, expire time.Duration) error {
+	if response, ok := value.(*cache.ResponseCache); ok {
+		for _, header := range perRequestHeaders {
+			response.Header.Del(header)
+		}
+	}
+	return s.CacheStore.Set(key, value, expire)
+}
+
+// webhookRoutes registers /webhooks, outside the cache. Webhooks belong to a tenant and make us
+// call out to their URLs, so unlike the other groups they always need an API key or a bearer token
+// granting webhook:write, even when neither is configured.
+func webhookRoutes(api *gin.RouterGroup) {
+	webhooks := api.Group("", wiki_middleware.RequireScope(wiki_domain.ScopeWebhookWrite))
+	webhooks.POST("webhooks", wiki_controller.CreateWebhook)
+	webhooks.GET("webhooks/:id", wiki_controller.GetWebhook)
+	webhooks.DELETE("webhooks/:id", wiki_controller.DeleteWebhook)
+	webhooks.GET("webhooks/:id/deliveries", wiki_controller.GetWebhookDeliveries)
+}
+
+// requireScope is the scope check of a route group, which lets everything through without bearer tokens
+func requireScope(verifier *wiki_token.Verifier, scope string) gin.HandlerFunc {
+	if verifier == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return wiki_middleware.RequireScope(scope)
+}
+
+// uncachedPrefixes are the GET routes that are never cached: /watch streams for as long as it is
+// open. The webhook and admin routes are outside the cached groups altogether.
+var uncachedPrefixes = []string{"/watch"}
+
+// cacheStrategy keys cached responses by URI, Accept-Language and Accept, since the language
+// and format negotiated from the headers change the body served for the same URI, and by tenant,
+// whose settings change it too. Only GETs are cached, a POSTed GraphQL query is in the body.
+func cacheStrategy(c *gin.Context) (bool, cache.Strategy) {
+	if c.Request.Method != http.MethodGet {
+		return false, cache.Strategy{}
+	}
+	for _, prefix := range uncachedPrefixes {
+		if strings.HasPrefix(c.Request.URL.Path, prefix) {
+			return false, cache.Strategy{}
+		}
+	}
+	strategy := cache.Strategy{
+		CacheKey: c.Request.RequestURI + "|" + c.GetHeader("Accept-Language") + "|" + c.GetHeader("Accept"),
+	}
+	if tenant := wiki_domain.TenantFrom(c.Request.Context()); tenant != nil {
+		if tenant.CacheTTL < 0 {
+			return false, cache.Strategy{}
+		}
+		strategy.CacheKey += "|" + tenant.ID
+		strategy.CacheDuration = time.Duration(tenant.CacheTTL) * time.Second
 	}
+	return true, strategy
 }
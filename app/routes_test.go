@@ -0,0 +1,116 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+	wiki_middleware "wiki-names/middlewares"
+	wiki_webhook "wiki-names/webhooks"
+
+	"github.com/chenyahui/gin-cache/persist"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func strategyFor(method string, target string, tenant *wiki_domain.Tenant) (bool, time.Duration, string) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(method, target, nil)
+	if tenant != nil {
+		c.Request = c.Request.WithContext(wiki_domain.WithTenant(c.Request.Context(), tenant))
+	}
+	cached, strategy := cacheStrategy(c)
+	return cached, strategy.CacheDuration, strategy.CacheKey
+}
+
+func TestCacheStrategy(t *testing.T) {
+	cached, ttl, key := strategyFor(http.MethodGet, "/search/Yoshua_Bengio", nil)
+	assert.True(t, cached)
+	assert.Zero(t, ttl)
+	assert.EqualValues(t, "/search/Yoshua_Bengio||", key)
+
+	// Tenants get their own entries, kept for their own TTL
+	cached, ttl, key = strategyFor(http.MethodGet, "/search/Yoshua_Bengio", &wiki_domain.Tenant{ID: "acme", CacheTTL: 600})
+	assert.True(t, cached)
+	assert.EqualValues(t, 10*time.Minute, ttl)
+	assert.EqualValues(t, "/search/Yoshua_Bengio|||acme", key)
+	cached, _, _ = strategyFor(http.MethodGet, "/search/Yoshua_Bengio", &wiki_domain.Tenant{ID: "acme", CacheTTL: -1})
+	assert.False(t, cached)
+
+	cached, _, _ = strategyFor(http.MethodPost, "/graphql", nil)
+	assert.False(t, cached)
+}
+
+func TestCacheKeepsRateLimitHeaders(t *testing.T) {
+	store, err := wiki_key.NewFileStore(filepath.Join(t.TempDir(), "keys.json"))
+	assert.Nil(t, err)
+	keys := wiki_key.NewManager(store, wiki_key.NewMemoryCounters())
+	issued, apiError := keys.Issue(wiki_domain.APIKeyRequest{Name: "ci"})
+	assert.Nil(t, apiError)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	served := 0
+	router.GET("/search/:name", wiki_middleware.APIKey(keys, true), newCache(persist.NewMemoryStore(time.Minute)), func(c *gin.Context) {
+		served++
+		c.String(http.StatusOK, c.Param("name"))
+	})
+	for _, remaining := range []string{"59", "58", "57"} {
+		request := httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil)
+		request.Header.Set(wiki_middleware.APIKeyHeader, issued.Key)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		assert.EqualValues(t, "Yoshua_Bengio", recorder.Body.String())
+		assert.EqualValues(t, remaining, recorder.Header().Get("RateLimit-Remaining"))
+	}
+	assert.EqualValues(t, 1, served)
+}
+
+func TestCacheKeepsRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/search/:name", wiki_middleware.RequestID(), newCache(persist.NewMemoryStore(time.Minute)), func(c *gin.Context) {
+		c.String(http.StatusOK, c.Param("name"))
+	})
+	for _, requestID := range []string{"first", "second"} {
+		request := httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil)
+		request.Header.Set(wiki_middleware.RequestIDHeader, requestID)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		assert.EqualValues(t, requestID, recorder.Header().Get(wiki_middleware.RequestIDHeader))
+	}
+}
+
+func TestWebhookRoutesNeedCredentials(t *testing.T) {
+	original := wiki_webhook.Webhooks
+	wiki_webhook.Webhooks = wiki_webhook.NewManager()
+	defer func() { wiki_webhook.Webhooks = original }()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// What JWT would have set, with the scopes named by the test
+	api := router.Group("", func(c *gin.Context) {
+		if scopes := c.GetHeader("X-Test-Scopes"); scopes != "" {
+			c.Set(wiki_middleware.ClaimsContextKey, &wiki_domain.Claims{Subject: "reports", Tenant: "acme", Scopes: strings.Split(scopes, " ")})
+			c.Request = c.Request.WithContext(wiki_domain.WithTenant(c.Request.Context(), &wiki_domain.Tenant{ID: "acme"}))
+		}
+	})
+	webhookRoutes(api)
+	serve := func(scopes string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"url":"https://example.org/hook","names":["a"]}`))
+		request.Header.Set("X-Test-Scopes", scopes)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Even without keys or tokens configured, an anonymous request is refused
+	assert.EqualValues(t, http.StatusUnauthorized, serve("").Code)
+	assert.EqualValues(t, http.StatusForbidden, serve(wiki_domain.ScopeLookupRead).Code)
+	assert.EqualValues(t, http.StatusCreated, serve(wiki_domain.ScopeLookupRead+" "+wiki_domain.ScopeWebhookWrite).Code)
+}
@@ -0,0 +1,254 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	wiki_client "wiki-names/clients"
+	wiki_middleware "wiki-names/middlewares"
+)
+
+// Progress and failure counters for the hot names job, published on /debug/vars
+var (
+	hotNamesRuns      = expvar.NewInt("hot_names_runs")
+	hotNamesLoaded    = expvar.NewInt("hot_names_loaded")
+	hotNamesRefreshed = expvar.NewInt("hot_names_refreshed")
+	hotNamesFailed    = expvar.NewInt("hot_names_failed")
+	hotNamesLastRun   = expvar.NewString("hot_names_last_run")
+)
+
+// HotNamesScheduler keeps a known list of names warm by requesting them
+// through the router at startup and then on every Interval, so they land in its cache.
+type HotNamesScheduler struct {
+	Source      string
+	Interval    time.Duration
+	Concurrency int
+	Jitter      time.Duration
+	// Handler serves the warm-up requests, RunApp gives it the router
+	Handler http.Handler
+	// APIKey is sent with the warm-up requests to pick their tenant, they aren't counted against it
+	APIKey string
+
+	names []string
+}
+
+// NewHotNamesSchedulerFromEnv reads the HOT_NAMES_* settings, it returns nil when no source is configured
+func NewHotNamesSchedulerFromEnv() *HotNamesScheduler {
+	source := os.Getenv("HOT_NAMES_SOURCE")
+	if source == "" {
+		return nil
+	}
+	return &HotNamesScheduler{
+		Source:      source,
+		Interval:    envDuration("HOT_NAMES_INTERVAL", 10*time.Minute),
+		Concurrency: envInt("HOT_NAMES_CONCURRENCY", 4),
+		Jitter:      envDuration("HOT_NAMES_JITTER", 2*time.Second),
+		APIKey:      os.Getenv("HOT_NAMES_API_KEY"),
+	}
+}
+
+// Run refreshes the list once straight away and then on every tick until the context is cancelled
+func (s *HotNamesScheduler) Run(ctx context.Context) {
+	log.Printf("Hot names: warming from %s every %s", s.Source, s.Interval)
+	s.refresh(ctx)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Hot names: scheduler stopped")
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+func (s *HotNamesScheduler) refresh(ctx context.Context) {
+	hotNamesRuns.Add(1)
+	names, err := loadHotNames(ctx, s.Source)
+	if err != nil {
+		// Keep warming the last good list rather than dropping everything on a bad reload
+		log.Printf("Hot names: error loading %s: %s", s.Source, err.Error())
+	} else {
+		s.names = names
+		hotNamesLoaded.Set(int64(len(names)))
+	}
+
+	concurrency := s.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	started := time.Now()
+	var refreshed, failed int64
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for _, name := range s.names {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case sem <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			// Spread the requests out so we don't hit Wikimedia with a burst every interval
+			if s.Jitter > 0 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(time.Duration(rand.Int63n(int64(s.Jitter)))):
+				}
+			}
+			status := s.warm(ctx, name)
+			mu.Lock()
+			defer mu.Unlock()
+			if status != http.StatusOK {
+				failed++
+				hotNamesFailed.Add(1)
+				log.Printf("Hot names: failed to refresh %s: %d", name, status)
+				return
+			}
+			refreshed++
+			hotNamesRefreshed.Add(1)
+		}(name)
+	}
+	wg.Wait()
+	hotNamesLastRun.Set(started.UTC().Format(time.RFC3339))
+	log.Printf("Hot names: refreshed %d of %d names (%d failed) in %s", refreshed, len(s.names), failed, time.Since(started))
+}
+
+type warmTTLKey struct{}
+
+// warmTTL is how long the cache keeps the response to a warm-up, set on its request context
+func warmTTL(ctx context.Context) (time.Duration, bool) {
+	ttl, ok := ctx.Value(warmTTLKey{}).(time.Duration)
+	return ttl, ok
+}
+
+// warm requests the name the way a client does, so its response is cached under the key a client's
+// request looks up: a GET of /search/<name> without Accept-Language or Accept headers. It's an
+// internal request, so it isn't rate limited or counted against the API key, and its response is
+// kept for two intervals, so one failed refresh doesn't drop the name from the cache.
+func (s *HotNamesScheduler) warm(ctx context.Context, name string) int {
+	ctx = wiki_middleware.Internal(context.WithValue(ctx, warmTTLKey{}, 2*s.Interval+s.Jitter))
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "/search/"+url.PathEscape(name), nil)
+	if err != nil {
+		return http.StatusBadRequest
+	}
+	// The cache keys by RequestURI, which only the server sets on the requests it reads
+	request.RequestURI = request.URL.RequestURI()
+	if s.APIKey != "" {
+		request.Header.Set(wiki_middleware.APIKeyHeader, s.APIKey)
+	}
+	writer := &discardWriter{header: http.Header{}}
+	s.Handler.ServeHTTP(writer, request)
+	if writer.status == 0 {
+		return http.StatusOK
+	}
+	return writer.status
+}
+
+// discardWriter keeps the status of a warm-up response and drops its body, the cache has its own copy
+type discardWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *discardWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardWriter) Write(body []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return len(body), nil
+}
+
+func (w *discardWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// loadHotNames reads one name per line from a local file or an http(s) URL, blank lines and # comments are skipped
+func loadHotNames(ctx context.Context, source string) ([]string, error) {
+	var reader io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		response, err := wiki_client.Client.Get(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status code %d", response.StatusCode)
+		}
+		reader = response.Body
+	} else {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+	return parseHotNames(reader)
+}
+
+func parseHotNames(reader io.Reader) ([]string, error) {
+	var names []string
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := strings.ReplaceAll(line, " ", "_")
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, scanner.Err()
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func envString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"wiki-names/controllers"
+	wiki_domain "wiki-names/domains"
+	wiki_middleware "wiki-names/middlewares"
+	wiki_provider "wiki-names/providers"
+
+	"github.com/chenyahui/gin-cache/persist"
+	"github.com/gin-gonic/gin"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type providerMock struct {
+	wiki_provider.WikiProviderStruct
+	mu    sync.Mutex
+	names []string
+}
+
+// We are mocking the provider method "GetContentSummary"
+func (pm *providerMock) GetContentSummary(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.names = append(pm.names, request.Name)
+	if request.Name == "Missing_Person" {
+		return nil, &wiki_domain.WikiError{Code: http.StatusNotFound, ErrorMessage: "not found"}
+	}
+	return &wiki_domain.Response{ShortDescription: "description"}, nil
+}
+
+func TestParseHotNames(t *testing.T) {
+	names, err := parseHotNames(strings.NewReader("# people we always show\nYoshua_Bengio\n\n  Geoffrey Hinton  \nYoshua_Bengio\n"))
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Yoshua_Bengio", "Geoffrey_Hinton"}, names)
+}
+
+// warmRouter serves /search the way SetupRouter does, through a cache
+func warmRouter(apiKeys *[]string) http.Handler {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		*apiKeys = append(*apiKeys, c.GetHeader(wiki_middleware.APIKeyHeader))
+	})
+	router.GET("/search/:name", newCache(persist.NewMemoryStore(time.Minute)), wiki_controller.GetContentSummary)
+	return router
+}
+
+func TestHotNamesSchedulerRefresh(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "names.txt")
+	assert.Nil(t, os.WriteFile(source, []byte("Yoshua_Bengio\nMissing_Person\nAlan_Turing\n"), 0o600))
+
+	mock := &providerMock{}
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = mock
+	defer func() { wiki_provider.WikiProvider = original }()
+
+	failedBefore := hotNamesFailed.Value()
+	refreshedBefore := hotNamesRefreshed.Value()
+	var apiKeys []string
+	router := warmRouter(&apiKeys)
+	scheduler := &HotNamesScheduler{Source: source, Concurrency: 1, Handler: router, APIKey: "wk_hot"}
+	scheduler.refresh(context.Background())
+
+	assert.ElementsMatch(t, []string{"Yoshua_Bengio", "Missing_Person", "Alan_Turing"}, mock.names)
+	assert.EqualValues(t, []string{"wk_hot", "wk_hot", "wk_hot"}, apiKeys)
+	assert.EqualValues(t, 1, hotNamesFailed.Value()-failedBefore)
+	assert.EqualValues(t, 2, hotNamesRefreshed.Value()-refreshedBefore)
+	assert.EqualValues(t, 3, hotNamesLoaded.Value())
+
+	// A client asking for a warmed name is served from the cache
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "description")
+	assert.Len(t, mock.names, 3)
+}
+
+func TestHotNamesSchedulerWarmTTL(t *testing.T) {
+	mock := &providerMock{}
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = mock
+	defer func() { wiki_provider.WikiProvider = original }()
+
+	store := persist.NewMemoryStore(time.Minute)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/search/:name", newCache(store), wiki_controller.GetContentSummary)
+	scheduler := &HotNamesScheduler{Interval: 10 * time.Minute, Handler: router}
+
+	// Every warm-up is served again rather than from the cache, so the entry is refreshed
+	assert.EqualValues(t, http.StatusOK, scheduler.warm(context.Background(), "Alan_Turing"))
+	assert.EqualValues(t, http.StatusOK, scheduler.warm(context.Background(), "Alan_Turing"))
+	assert.Len(t, mock.names, 2)
+
+	// And it is kept past the cache's 2 second default, for the scheduler's interval
+	time.Sleep(3 * time.Second)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/search/Alan_Turing", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Len(t, mock.names, 2)
+}
+
+func TestHotNamesSchedulerKeepsLastGoodList(t *testing.T) {
+	mock := &providerMock{}
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = mock
+	defer func() { wiki_provider.WikiProvider = original }()
+
+	var apiKeys []string
+	scheduler := &HotNamesScheduler{Source: filepath.Join(t.TempDir(), "missing.txt"), Handler: warmRouter(&apiKeys), names: []string{"Alan_Turing"}}
+	scheduler.refresh(context.Background())
+
+	assert.EqualValues(t, []string{"Alan_Turing"}, mock.names)
+}
@@ -0,0 +1,315 @@
+package wiki_bio
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	wiki_domain "wiki-names/domains"
+)
+
+var months = []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+
+var (
+	// The verb that opens the "is a/was a" clause after the name and its parenthetical
+	verbRe = regexp.MustCompile(`\b(?:is|was|are|were)\s+(?:an?|the)\s+`)
+	// Where the description of what the person is stops and the "known for" part starts
+	clauseEndRe = regexp.MustCompile(`\s+(?:who|whose|which|that|best|known|most|noted|widely|from|since|born|considered|regarded|recognized|recognised|famous|with)\b|[;:(]|\.\s*$`)
+	// Occupations are listed as "a, b, and c" or "a and b"
+	listSeparatorRe = regexp.MustCompile(`\s*,\s*(?:and\s+|or\s+)?|\s+(?:and|or|&)\s+`)
+	dateRe          = buildDateRe()
+	dashRe          = regexp.MustCompile(`^\s*(?:[–—-]|to)\s*$`)
+	// "d." and "c." are whole words, not the end of "Ltd." or "etc."
+	diedRe  = regexp.MustCompile(`(?i)\b(?:died|d\.)`)
+	circaRe = regexp.MustCompile(`(?i)\b(?:c\.|circa|fl\.)`)
+	// Post-nominal letters we don't know yet are short runs of capitals such as OC or FRS
+	postNominalRe = regexp.MustCompile(`^[A-Z]{2,6}$`)
+	romanRe       = regexp.MustCompile(`^[IVX]+$`)
+)
+
+var honorifics = map[string]bool{
+	"sir": true, "dame": true, "lord": true, "lady": true, "dr": true, "prof": true, "professor": true,
+	"rev": true, "reverend": true, "saint": true, "st": true, "sheikh": true, "baron": true, "baroness": true,
+	"count": true, "countess": true, "prince": true, "princess": true, "king": true, "queen": true,
+}
+
+var knownPostNominals = map[string]bool{
+	"OC": true, "CC": true, "CQ": true, "FRS": true, "FRSC": true, "FRSE": true, "FREng": true, "FRSA": true,
+	"FBCS": true, "FBA": true, "FMedSci": true, "FAA": true, "OM": true, "CH": true, "KG": true, "KT": true,
+	"GBE": true, "KBE": true, "DBE": true, "CBE": true, "OBE": true, "MBE": true, "KCB": true, "GCB": true,
+	"KCMG": true, "CMG": true, "AC": true, "AO": true, "AM": true, "QC": true, "KC": true, "PC": true,
+	"MP": true, "MEP": true, "MSP": true, "TD": true, "PhD": true, "FAcSS": true, "FIEEE": true, "FRAS": true,
+}
+
+var knownDemonyms = map[string]bool{
+	"American": true, "Argentine": true, "Argentinian": true, "Australian": true, "Austrian": true, "Belgian": true,
+	"Brazilian": true, "British": true, "Bulgarian": true, "Canadian": true, "Chilean": true, "Chinese": true,
+	"Colombian": true, "Croatian": true, "Cuban": true, "Czech": true, "Danish": true, "Dutch": true,
+	"Egyptian": true, "English": true, "Estonian": true, "Ethiopian": true, "Filipino": true, "Finnish": true,
+	"French": true, "German": true, "Ghanaian": true, "Greek": true, "Hungarian": true, "Icelandic": true,
+	"Indian": true, "Indonesian": true, "Iranian": true, "Iraqi": true, "Irish": true, "Israeli": true,
+	"Italian": true, "Jamaican": true, "Japanese": true, "Kenyan": true, "Korean": true, "Latvian": true,
+	"Lebanese": true, "Lithuanian": true, "Malaysian": true, "Mexican": true, "Moroccan": true, "New": true,
+	"Nigerian": true, "Norwegian": true, "Pakistani": true, "Peruvian": true, "Polish": true, "Portuguese": true,
+	"Romanian": true, "Russian": true, "Saudi": true, "Scottish": true, "Serbian": true, "Singaporean": true,
+	"Slovak": true, "Slovenian": true, "South": true, "Soviet": true, "Spanish": true, "Swedish": true,
+	"Swiss": true, "Syrian": true, "Taiwanese": true, "Thai": true, "Turkish": true, "Ukrainian": true,
+	"Venezuelan": true, "Vietnamese": true, "Welsh": true, "Zealand": true, "African": true, "Northern": true,
+}
+
+var demonymSuffixes = []string{"an", "ian", "ese", "ish", "ch", "ic", "i"}
+
+func buildDateRe() *regexp.Regexp {
+	month := "(" + strings.Join(months, "|") + ")"
+	return regexp.MustCompile(`\b(?:` +
+		`(\d{1,2})\s+` + month + `\s+(\d{1,4})` + // 23 June 1912
+		`|` + month + `\s+(\d{1,2}),?\s+(\d{1,4})` + // March 5, 1964
+		`|` + month + `\s+(\d{3,4})` + // June 1912
+		`|(\d{3,4})` + // 1912
+		`)(\s*BCE?)?\b`)
+}
+
+type foundDate struct {
+	value string
+	text  string
+	start int
+	end   int
+}
+
+// ParseLead pulls the name, dates, nationality and occupations out of an English biography's first sentence
+func ParseLead(sentence string) *wiki_domain.Bio {
+	bio := &wiki_domain.Bio{
+		Honorifics:   []wiki_domain.BioField{},
+		PostNominals: []wiki_domain.BioField{},
+		Occupations:  []wiki_domain.BioField{},
+		LeadSentence: sentence,
+	}
+	head, parenthetical, rest := splitParenthetical(sentence)
+	clauseStart := -1
+	if loc := verbRe.FindStringIndex(rest); loc != nil {
+		clauseStart = loc[1]
+		if parenthetical == "" {
+			head, rest = rest[:loc[0]], rest[loc[1]:]
+			clauseStart = 0
+		}
+	}
+	parseHead(bio, head)
+	parseDates(bio, parenthetical)
+	if clauseStart >= 0 {
+		parseClause(bio, rest[clauseStart:])
+	}
+	return bio
+}
+
+// splitParenthetical cuts the sentence around the first bracketed group, if it comes before the verb
+func splitParenthetical(sentence string) (string, string, string) {
+	open := strings.Index(sentence, "(")
+	if open < 0 {
+		return "", "", sentence
+	}
+	if loc := verbRe.FindStringIndex(sentence); loc != nil && loc[0] < open {
+		return "", "", sentence
+	}
+	depth := 0
+	for i, r := range sentence[open:] {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return sentence[:open], sentence[open+1 : open+i], sentence[open+i+1:]
+			}
+		}
+	}
+	return sentence[:open], sentence[open+1:], ""
+}
+
+func parseHead(bio *wiki_domain.Bio, head string) {
+	tokens := strings.Fields(strings.Trim(strings.TrimSpace(head), ","))
+	for len(tokens) > 1 && honorifics[strings.ToLower(strings.TrimSuffix(tokens[0], "."))] {
+		bio.Honorifics = append(bio.Honorifics, wiki_domain.BioField{Value: tokens[0], Confidence: 0.9})
+		tokens = tokens[1:]
+	}
+	var postNominals []wiki_domain.BioField
+	for len(tokens) > 2 {
+		last := strings.Trim(tokens[len(tokens)-1], ",")
+		if !knownPostNominals[last] && (!postNominalRe.MatchString(last) || romanRe.MatchString(last)) {
+			break
+		}
+		confidence := 0.6
+		if knownPostNominals[last] {
+			confidence = 0.95
+		}
+		postNominals = append([]wiki_domain.BioField{{Value: last, Confidence: confidence}}, postNominals...)
+		tokens = tokens[:len(tokens)-1]
+	}
+	bio.PostNominals = append(bio.PostNominals, postNominals...)
+	if len(tokens) > 0 {
+		confidence := 0.9
+		if len(tokens) == 1 {
+			confidence = 0.6
+		}
+		bio.Name = &wiki_domain.BioField{Value: strings.Trim(strings.Join(tokens, " "), ","), Confidence: confidence}
+	}
+}
+
+// parseDates reads "born X", "died Y" and "X – Y" out of the ;-separated parts of the parenthetical
+func parseDates(bio *wiki_domain.Bio, parenthetical string) {
+	for _, piece := range strings.Split(parenthetical, ";") {
+		dates := findDates(piece)
+		if len(dates) == 0 {
+			continue
+		}
+		confidence := 1.0
+		if circaRe.MatchString(piece) {
+			confidence = 0.5
+		}
+		// Lowered after slicing, lowering can change the length and the offsets are piece's
+		before := piece[:dates[0].start]
+		switch {
+		case strings.Contains(strings.ToLower(before), "born"):
+			setDate(&bio.BirthDate, dates[0], 0.95*confidence)
+		case diedRe.MatchString(before):
+			setDate(&bio.DeathDate, dates[0], 0.95*confidence)
+		case len(dates) >= 2 && dashRe.MatchString(piece[dates[0].end:dates[1].start]):
+			setDate(&bio.BirthDate, dates[0], 0.9*confidence)
+			setDate(&bio.DeathDate, dates[1], 0.9*confidence)
+		default:
+			setDate(&bio.BirthDate, dates[0], 0.6*confidence)
+		}
+	}
+}
+
+func setDate(field **wiki_domain.BioField, date foundDate, confidence float64) {
+	if *field != nil {
+		return
+	}
+	*field = &wiki_domain.BioField{Value: date.value, Text: date.text, Confidence: confidence}
+}
+
+// findDates returns the dates in text, as ISO 8601 to the precision they were written in
+func findDates(text string) []foundDate {
+	var dates []foundDate
+	for _, m := range dateRe.FindAllStringSubmatchIndex(text, -1) {
+		group := func(i int) string {
+			if m[2*i] < 0 {
+				return ""
+			}
+			return text[m[2*i]:m[2*i+1]]
+		}
+		var value string
+		switch {
+		case group(1) != "":
+			value = isoDate(group(3), group(2), group(1))
+		case group(4) != "":
+			value = isoDate(group(6), group(4), group(5))
+		case group(7) != "":
+			value = isoDate(group(8), group(7), "")
+		default:
+			value = isoDate(group(9), "", "")
+		}
+		if group(10) != "" {
+			value = "-" + value
+		}
+		dates = append(dates, foundDate{value: value, text: strings.TrimSpace(text[m[0]:m[1]]), start: m[0], end: m[1]})
+	}
+	return dates
+}
+
+func isoDate(year string, month string, day string) string {
+	value := strings.Repeat("0", 4-len(year)) + year
+	for i, name := range months {
+		if name == month {
+			value += fmt.Sprintf("-%02d", i+1)
+		}
+	}
+	if day != "" {
+		value += "-" + strings.Repeat("0", 2-len(day)) + day
+	}
+	return value
+}
+
+// parseClause reads "Canadian computer scientist" style clauses into a demonym and a list of occupations
+func parseClause(bio *wiki_domain.Bio, clause string) {
+	if loc := clauseEndRe.FindStringIndex(clause); loc != nil {
+		clause = clause[:loc[0]]
+	}
+	clause = strings.TrimRight(strings.TrimSpace(clause), ",.")
+	words := strings.Fields(clause)
+
+	i := 0
+	var demonyms []string
+	demonymConfidence := 0.0
+	for i < len(words)-1 {
+		word := strings.Trim(words[i], ",")
+		if strings.HasSuffix(strings.ToLower(word), "-born") {
+			// "Polish-born American": the birthplace is not the nationality
+			i++
+			continue
+		}
+		if word == "and" && len(demonyms) > 0 && i+1 < len(words)-1 && isDemonym(lastHyphenPart(words[i+1])) > 0 {
+			i++
+			continue
+		}
+		confidence := isDemonym(lastHyphenPart(word))
+		if confidence == 0 {
+			break
+		}
+		if len(demonyms) == 0 {
+			demonymConfidence = confidence
+		}
+		demonyms = append(demonyms, word)
+		i++
+	}
+	if len(demonyms) > 0 {
+		bio.Nationality = &wiki_domain.BioField{Value: demonymValue(demonyms[0]), Text: strings.Join(demonyms, " "), Confidence: demonymConfidence}
+		// Multi word demonyms like "South African" or "New Zealand"
+		if (demonyms[0] == "South" || demonyms[0] == "New" || demonyms[0] == "Northern") && len(demonyms) > 1 {
+			bio.Nationality.Value = demonyms[0] + " " + demonyms[1]
+		}
+	}
+
+	for _, occupation := range listSeparatorRe.Split(strings.Join(words[i:], " "), -1) {
+		occupation = strings.TrimSpace(occupation)
+		occupation = strings.TrimPrefix(strings.TrimPrefix(occupation, "a "), "an ")
+		if occupation == "" {
+			continue
+		}
+		confidence := 0.8
+		if len(strings.Fields(occupation)) > 5 {
+			confidence = 0.5
+		}
+		bio.Occupations = append(bio.Occupations, wiki_domain.BioField{Value: occupation, Confidence: confidence})
+	}
+}
+
+// isDemonym returns how sure we are that word is a nationality adjective, 0 when it is not one
+func isDemonym(word string) float64 {
+	if word == "" || !unicode.IsUpper([]rune(word)[0]) {
+		return 0
+	}
+	if knownDemonyms[word] {
+		return 0.9
+	}
+	for _, suffix := range demonymSuffixes {
+		if strings.HasSuffix(word, suffix) {
+			return 0.6
+		}
+	}
+	return 0
+}
+
+func lastHyphenPart(word string) string {
+	parts := strings.Split(strings.Trim(word, ","), "-")
+	return parts[len(parts)-1]
+}
+
+// demonymValue drops qualifiers such as "naturalised-" from the demonym
+func demonymValue(word string) string {
+	if lastHyphenPart(word) != word && !unicode.IsUpper([]rune(word)[0]) {
+		return lastHyphenPart(word)
+	}
+	return word
+}
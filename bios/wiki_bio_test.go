@@ -0,0 +1,113 @@
+package wiki_bio
+
+import (
+	"strings"
+	"testing"
+
+	wiki_domain "wiki-names/domains"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fieldValues(fields []wiki_domain.BioField) []string {
+	values := []string{}
+	for _, field := range fields {
+		values = append(values, field.Value)
+	}
+	return values
+}
+
+func TestParseLeadBengio(t *testing.T) {
+	bio := ParseLead("Yoshua Bengio OC FRS FRSC (born March 5, 1964) is a Canadian computer scientist, most noted for his work on artificial neural networks and deep learning.")
+
+	assert.EqualValues(t, "Yoshua Bengio", bio.Name.Value)
+	assert.EqualValues(t, []string{"OC", "FRS", "FRSC"}, fieldValues(bio.PostNominals))
+	assert.EqualValues(t, 0.95, bio.PostNominals[0].Confidence)
+	assert.EqualValues(t, "1964-03-05", bio.BirthDate.Value)
+	assert.EqualValues(t, "March 5, 1964", bio.BirthDate.Text)
+	assert.EqualValues(t, 0.95, bio.BirthDate.Confidence)
+	assert.Nil(t, bio.DeathDate)
+	assert.EqualValues(t, "Canadian", bio.Nationality.Value)
+	assert.EqualValues(t, 0.9, bio.Nationality.Confidence)
+	assert.EqualValues(t, []string{"computer scientist"}, fieldValues(bio.Occupations))
+}
+
+func TestParseLeadTuring(t *testing.T) {
+	bio := ParseLead("Alan Mathison Turing OBE FRS (/ˈtjʊərɪŋ/; 23 June 1912 – 7 June 1954) was an English mathematician, computer scientist, logician, cryptanalyst, philosopher, and theoretical biologist.")
+
+	assert.EqualValues(t, "Alan Mathison Turing", bio.Name.Value)
+	assert.EqualValues(t, []string{"OBE", "FRS"}, fieldValues(bio.PostNominals))
+	assert.EqualValues(t, "1912-06-23", bio.BirthDate.Value)
+	assert.EqualValues(t, "1954-06-07", bio.DeathDate.Value)
+	assert.EqualValues(t, 0.9, bio.DeathDate.Confidence)
+	assert.EqualValues(t, "English", bio.Nationality.Value)
+	assert.EqualValues(t, []string{"mathematician", "computer scientist", "logician", "cryptanalyst", "philosopher", "theoretical biologist"}, fieldValues(bio.Occupations))
+}
+
+func TestParseLeadHonorific(t *testing.T) {
+	bio := ParseLead("Sir Timothy John Berners-Lee OM KBE FRS FREng FRSA FBCS (born 8 June 1955), also known as TimBL, is an English computer scientist best known as the inventor of the World Wide Web.")
+
+	assert.EqualValues(t, []string{"Sir"}, fieldValues(bio.Honorifics))
+	assert.EqualValues(t, "Timothy John Berners-Lee", bio.Name.Value)
+	assert.EqualValues(t, []string{"OM", "KBE", "FRS", "FREng", "FRSA", "FBCS"}, fieldValues(bio.PostNominals))
+	assert.EqualValues(t, "1955-06-08", bio.BirthDate.Value)
+	assert.EqualValues(t, []string{"computer scientist"}, fieldValues(bio.Occupations))
+}
+
+func TestParseLeadSeveralNationalities(t *testing.T) {
+	bio := ParseLead("Marie Salomea Skłodowska–Curie (born Maria Salomea Skłodowska; 7 November 1867 – 4 July 1934) was a Polish and naturalised-French physicist and chemist who conducted pioneering research on radioactivity.")
+
+	assert.EqualValues(t, "1867-11-07", bio.BirthDate.Value)
+	assert.EqualValues(t, "1934-07-04", bio.DeathDate.Value)
+	assert.EqualValues(t, "Polish", bio.Nationality.Value)
+	assert.EqualValues(t, "Polish naturalised-French", bio.Nationality.Text)
+	assert.EqualValues(t, []string{"physicist", "chemist"}, fieldValues(bio.Occupations))
+}
+
+func TestParseLeadGenerationalSuffix(t *testing.T) {
+	bio := ParseLead("Barack Hussein Obama II (born August 4, 1961) is an American politician who served as the 44th president of the United States from 2009 to 2017.")
+
+	assert.EqualValues(t, "Barack Hussein Obama II", bio.Name.Value)
+	assert.Empty(t, bio.PostNominals)
+	assert.EqualValues(t, "1961-08-04", bio.BirthDate.Value)
+	assert.EqualValues(t, "American", bio.Nationality.Value)
+	assert.EqualValues(t, []string{"politician"}, fieldValues(bio.Occupations))
+}
+
+func TestParseLeadYearsAndCirca(t *testing.T) {
+	bio := ParseLead("Albert Einstein (1879–1955) was a German-born theoretical physicist.")
+	assert.EqualValues(t, "1879", bio.BirthDate.Value)
+	assert.EqualValues(t, "1955", bio.DeathDate.Value)
+	assert.Nil(t, bio.Nationality)
+	assert.EqualValues(t, []string{"theoretical physicist"}, fieldValues(bio.Occupations))
+
+	bio = ParseLead("Joan of Arc (c. 1412 – 30 May 1431) was a French heroine.")
+	assert.EqualValues(t, "1412", bio.BirthDate.Value)
+	assert.EqualValues(t, 0.45, bio.BirthDate.Confidence)
+	assert.EqualValues(t, "1431-05-30", bio.DeathDate.Value)
+}
+
+func TestParseLeadCaseFolding(t *testing.T) {
+	// The Kelvin sign lowers to a shorter k, the date offsets must still be read from the original
+	bio := ParseLead("Alan Turing (" + strings.Repeat("\u212a", 20) + " 23 June 1912) was a mathematician.")
+	assert.EqualValues(t, "1912-06-23", bio.BirthDate.Value)
+	bio = ParseLead("Alan Turing (İİİİİİİİİİ born 23 June 1912) was a mathematician.")
+	assert.EqualValues(t, "1912-06-23", bio.BirthDate.Value)
+	assert.EqualValues(t, 0.95, bio.BirthDate.Confidence)
+
+	// "d." in "Ltd." or "and." isn't "died"
+	bio = ParseLead("John Smith (founder of Smith Ltd. 4 May 1950) is an English businessman.")
+	assert.EqualValues(t, "1950-05-04", bio.BirthDate.Value)
+	assert.Nil(t, bio.DeathDate)
+	bio = ParseLead("John Smith (d. 4 May 1990) was an English businessman.")
+	assert.EqualValues(t, "1990-05-04", bio.DeathDate.Value)
+	assert.Nil(t, bio.BirthDate)
+}
+
+func TestParseLeadNoParenthetical(t *testing.T) {
+	bio := ParseLead("John Smith is a South African cricketer.")
+	assert.EqualValues(t, "John Smith", bio.Name.Value)
+	assert.Nil(t, bio.BirthDate)
+	assert.EqualValues(t, "South African", bio.Nationality.Value)
+	assert.EqualValues(t, []string{"cricketer"}, fieldValues(bio.Occupations))
+}
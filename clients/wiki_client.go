@@ -1,19 +1,21 @@
 package wiki_client
 
 import (
+	"context"
 	"net/http"
 )
 
 type clientStruct struct{}
 
 type ClientInterface interface {
-	Get(string) (*http.Response, error)
+	Get(context.Context, string) (*http.Response, error)
 }
 
 var Client ClientInterface = &clientStruct{}
 
-func (ci *clientStruct) Get(url string) (*http.Response, error) {
-	request, err := http.NewRequest(http.MethodGet, url, nil)
+// Get gives up on the upstream call once ctx is done, so a client hanging up stops our requests too
+func (ci *clientStruct) Get(ctx context.Context, url string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return nil, err
 	}
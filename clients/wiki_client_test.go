@@ -2,8 +2,10 @@ package wiki_client
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -51,3 +53,17 @@ func TestClientStructGet(t *testing.T) {
 	assert.Equal(t, response, actualResponse)
 	assert.Equal(t, nil, actualError)
 }
+
+func TestClientStructGetCancelled(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Client.Get(ctx, server.URL)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, called)
+}
@@ -0,0 +1,70 @@
+package wiki_controller
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+	wiki_middleware "wiki-names/middlewares"
+	wiki_provider "wiki-names/providers"
+)
+
+// batchWorkers is how many names of a batch are looked up at once, like the gRPC BatchLookup,
+// maxBatchNames the most names a batch can have, as BatchRequest validates
+const (
+	batchWorkers  = 8
+	maxBatchNames = 500
+)
+
+// SearchBatch looks up to 500 names up like /search does, fewer when the tenant's batches are
+// smaller, answering with a result per name in the order they were sent. A name that fails has
+// its problem in place of the result.
+func SearchBatch(c *gin.Context) {
+	var request wiki_domain.BatchRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	tenant := wiki_domain.TenantFrom(c.Request.Context())
+	if max := tenant.BatchSize(maxBatchNames); len(request.Names) > max {
+		renderError(c, wiki_domain.NewBadRequestError(fmt.Sprintf("at most %d names can be looked up at once", max)))
+		return
+	}
+	results := make([]wiki_domain.BatchResult, len(request.Names))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < batchWorkers && i < len(request.Names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				results[index] = batchResult(c, tenant, request, request.Names[index])
+			}
+		}()
+	}
+	for index := range request.Names {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+	render(c, http.StatusOK, results)
+}
+
+func batchResult(c *gin.Context, tenant *wiki_domain.Tenant, request wiki_domain.BatchRequest, name string) wiki_domain.BatchResult {
+	query := wiki_domain.RequestQuery{
+		Tenant:  tenant,
+		Name:    strings.ReplaceAll(name, " ", "_"),
+		Locale:  request.Locale,
+		Type:    request.Type,
+		Include: request.Include,
+	}
+	result, apiError := wiki_provider.WikiProvider.GetContentSummary(c.Request.Context(), query)
+	if apiError != nil {
+		return wiki_domain.BatchResult{Name: name, Error: wiki_domain.NewProblemDetails(apiError, c.Request.URL.RequestURI(), c.GetString(wiki_middleware.RequestIDKey))}
+	}
+	return wiki_domain.BatchResult{Name: name, Result: result}
+}
@@ -0,0 +1,105 @@
+package wiki_controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_provider "wiki-names/providers"
+)
+
+type batchMock struct {
+	wiki_provider.WikiProviderStruct
+}
+
+// We are mocking the provider method "GetContentSummary", every name but Nobody has a description,
+// in the tenant's default locale when none was asked for
+func (bm *batchMock) GetContentSummary(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	if request.Name == "Nobody" {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, "Page Nobody does not exist")
+	}
+	locale := request.Locale
+	if locale == "" {
+		locale = request.Tenant.LocaleOr("en")
+	}
+	return &wiki_domain.Response{ShortDescription: "about " + request.Name, Language: locale}, nil
+}
+
+func serveBatch(t *testing.T, body string) *httptest.ResponseRecorder {
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = &batchMock{}
+	t.Cleanup(func() { wiki_provider.WikiProvider = original })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("search/batch", SearchBatch)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/search/batch", strings.NewReader(body)))
+	return recorder
+}
+
+func TestSearchBatch(t *testing.T) {
+	names := []string{"Yoshua Bengio", "Nobody", "Geoffrey_Hinton", "Alan Kay", "Ada Lovelace", "Grace Hopper", "Edsger Dijkstra", "Barbara Liskov", "Donald Knuth", "Tim Berners-Lee"}
+	body, _ := json.Marshal(wiki_domain.BatchRequest{Names: names, Locale: "fr"})
+	recorder := serveBatch(t, string(body))
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	var results []wiki_domain.BatchResult
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &results))
+	assert.Len(t, results, len(names))
+	for i, result := range results {
+		assert.EqualValues(t, names[i], result.Name)
+	}
+	assert.EqualValues(t, "about Yoshua_Bengio", results[0].Result.ShortDescription)
+	assert.EqualValues(t, "fr", results[0].Result.Language)
+	assert.Nil(t, results[1].Result)
+	assert.EqualValues(t, wiki_domain.ErrPageNotFound, results[1].Error.Reason)
+	assert.EqualValues(t, http.StatusNotFound, results[1].Error.Status)
+}
+
+func TestSearchBatchBadRequest(t *testing.T) {
+	assert.EqualValues(t, http.StatusBadRequest, serveBatch(t, `{"names":[]}`).Code)
+	assert.EqualValues(t, http.StatusBadRequest, serveBatch(t, `{"names":["a"],"type":"cat"}`).Code)
+	assert.EqualValues(t, http.StatusBadRequest, serveBatch(t, `{"names":["a"`).Code)
+	names := make([]string, 501)
+	for i := range names {
+		names[i] = "a"
+	}
+	body, _ := json.Marshal(wiki_domain.BatchRequest{Names: names})
+	assert.EqualValues(t, http.StatusBadRequest, serveBatch(t, string(body)).Code)
+}
+
+func TestSearchBatchTenantSize(t *testing.T) {
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = &batchMock{}
+	t.Cleanup(func() { wiki_provider.WikiProvider = original })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("search/batch", SearchBatch)
+	tenant := &wiki_domain.Tenant{ID: "acme", MaxBatchSize: 2, DefaultLocale: "de"}
+	send := func(body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/search/batch", strings.NewReader(body))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request.WithContext(wiki_domain.WithTenant(request.Context(), tenant)))
+		return recorder
+	}
+
+	recorder := send(`{"names":["a","b","c"]}`)
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "at most 2 names")
+
+	recorder = send(`{"names":["a","b"]}`)
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	var results []wiki_domain.BatchResult
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &results))
+	// The lookups carry the tenant
+	assert.EqualValues(t, "de", results[0].Result.Language)
+}
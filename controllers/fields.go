@@ -0,0 +1,47 @@
+package wiki_controller
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// selectFields keeps only the comma separated fields of value's JSON, nested ones are picked
+// with a dot ("image.thumbnail"). Fields the response doesn't have are ignored.
+func selectFields(value interface{}, fields string) (map[string]interface{}, error) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]interface{}
+	if err := json.Unmarshal(bytes, &full); err != nil {
+		return nil, err
+	}
+	selected := map[string]interface{}{}
+	for _, field := range strings.Split(fields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			copyField(full, selected, strings.Split(field, "."))
+		}
+	}
+	return selected, nil
+}
+
+func copyField(from map[string]interface{}, to map[string]interface{}, path []string) {
+	value, ok := from[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		to[path[0]] = value
+		return
+	}
+	nested, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	target, ok := to[path[0]].(map[string]interface{})
+	if !ok {
+		target = map[string]interface{}{}
+		to[path[0]] = target
+	}
+	copyField(nested, target, path[1:])
+}
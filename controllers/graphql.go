@@ -0,0 +1,39 @@
+package wiki_controller
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+	wiki_graph "wiki-names/graphs"
+)
+
+// GraphQL runs a query against the lookup facets, POSTed as JSON or passed in the query string
+// (with the variables as a JSON string). A query that could not run at all is a 400, errors while
+// resolving it come back next to the partial data with a 200, as GraphQL clients expect.
+func GraphQL(c *gin.Context) {
+	var request wiki_domain.GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		if err := c.ShouldBindQuery(&request); err != nil {
+			renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+			return
+		}
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				renderError(c, wiki_domain.NewBadRequestError("variables must be a JSON object: "+err.Error()))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&request); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	result, executed := wiki_graph.Execute(c.Request.Context(), request)
+	if !executed {
+		c.JSON(http.StatusBadRequest, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,43 @@
+package wiki_controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveGraphQL(request *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("graphql", GraphQL)
+	router.POST("graphql", GraphQL)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestGraphQL(t *testing.T) {
+	recorder := serveGraphQL(httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"query Kind($name: String!) { __type(name: $name) { name } }","variables":{"name":"Person"}}`)))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":{"__type":{"name":"Person"}}}`, recorder.Body.String())
+
+	recorder = serveGraphQL(httptest.NewRequest(http.MethodGet, `/graphql?query=%7B__typename%7D`, nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"data":{"__typename":"Query"}}`, recorder.Body.String())
+}
+
+func TestGraphQLBadRequest(t *testing.T) {
+	recorder := serveGraphQL(httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ person(name: \"x\") { horoscope } }"}`)))
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `Cannot query field \"horoscope\"`)
+
+	recorder = serveGraphQL(httptest.NewRequest(http.MethodGet, `/graphql?query=%7B__typename%7D&variables=nope`, nil))
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = serveGraphQL(httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{}`)))
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+}
@@ -0,0 +1,130 @@
+package wiki_controller
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+)
+
+// keys is the API key manager, or a 404 when API keys aren't configured
+func keys(c *gin.Context) *wiki_key.Manager {
+	if wiki_key.Keys == nil {
+		renderError(c, &wiki_domain.WikiError{Code: http.StatusNotFound, ErrorMessage: "API keys are not configured, set API_KEYS_STORE"})
+	}
+	return wiki_key.Keys
+}
+
+// IssueKey creates an API key, the response is the only time the key itself is shown
+func IssueKey(c *gin.Context) {
+	manager := keys(c)
+	if manager == nil {
+		return
+	}
+	var request wiki_domain.APIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	issued, apiError := manager.Issue(request)
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	c.Header("Location", "/admin/keys/"+issued.ID)
+	render(c, http.StatusCreated, issued)
+}
+
+// ListKeys is every API key, or a tenant's with ?tenant=
+func ListKeys(c *gin.Context) {
+	manager := keys(c)
+	if manager == nil {
+		return
+	}
+	listed, apiError := manager.List(c.Query("tenant"))
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, listed)
+}
+
+func GetKey(c *gin.Context) {
+	manager := keys(c)
+	if manager == nil {
+		return
+	}
+	key, apiError := manager.Get(c.Param("id"))
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, key)
+}
+
+// RotateKey issues a new key in place of the old one, which keeps working for the grace period given
+func RotateKey(c *gin.Context) {
+	manager := keys(c)
+	if manager == nil {
+		return
+	}
+	var request wiki_domain.RotateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+			return
+		}
+	}
+	var grace time.Duration
+	if request.Grace != "" {
+		var err error
+		if grace, err = time.ParseDuration(request.Grace); err != nil || grace < 0 {
+			renderError(c, wiki_domain.NewBadRequestError("grace must be a duration such as 24h"))
+			return
+		}
+	}
+	issued, apiError := manager.Rotate(c.Param("id"), grace)
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, issued)
+}
+
+func RevokeKey(c *gin.Context) {
+	manager := keys(c)
+	if manager == nil {
+		return
+	}
+	if apiError := manager.Revoke(c.Param("id")); apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetKeyUsage is the key's requests for each of the last ?days= days, 7 by default and 35 at most
+func GetKeyUsage(c *gin.Context) {
+	manager := keys(c)
+	if manager == nil {
+		return
+	}
+	days := 7
+	if value := c.Query("days"); value != "" {
+		var err error
+		if days, err = strconv.Atoi(value); err != nil || days < 1 || days > 35 {
+			renderError(c, wiki_domain.NewBadRequestError("days must be between 1 and 35"))
+			return
+		}
+	}
+	usage, apiError := manager.Usage(c.Param("id"), days)
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, usage)
+}
@@ -0,0 +1,91 @@
+package wiki_controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+)
+
+func serveKeys(t *testing.T, configured bool) func(method string, target string, body string) *httptest.ResponseRecorder {
+	original := wiki_key.Keys
+	wiki_key.Keys = nil
+	if configured {
+		store, err := wiki_key.NewFileStore(filepath.Join(t.TempDir(), "keys.json"))
+		assert.Nil(t, err)
+		wiki_key.Keys = wiki_key.NewManager(store, wiki_key.NewMemoryCounters())
+	}
+	t.Cleanup(func() { wiki_key.Keys = original })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("admin/keys", IssueKey)
+	router.GET("admin/keys", ListKeys)
+	router.GET("admin/keys/:id", GetKey)
+	router.POST("admin/keys/:id/rotate", RotateKey)
+	router.DELETE("admin/keys/:id", RevokeKey)
+	router.GET("admin/keys/:id/usage", GetKeyUsage)
+	return func(method string, target string, body string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return recorder
+	}
+}
+
+func TestKeys(t *testing.T) {
+	serve := serveKeys(t, true)
+
+	recorder := serve(http.MethodPost, "/admin/keys", `{"name":"ci","tenant":"acme","rate_limit":10}`)
+	assert.EqualValues(t, http.StatusCreated, recorder.Code)
+	var issued wiki_domain.IssuedKey
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &issued))
+	assert.NotEmpty(t, issued.Key)
+	assert.EqualValues(t, 10, issued.RateLimit)
+	assert.EqualValues(t, "/admin/keys/"+issued.ID, recorder.Header().Get("Location"))
+	assert.NotContains(t, recorder.Body.String(), "hash")
+
+	recorder = serve(http.MethodGet, "/admin/keys?tenant=acme", "")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), issued.Key)
+	assert.Contains(t, recorder.Body.String(), issued.ID)
+	assert.JSONEq(t, `[]`, serve(http.MethodGet, "/admin/keys?tenant=someone-else", "").Body.String())
+
+	recorder = serve(http.MethodPost, "/admin/keys/"+issued.ID+"/rotate", `{"grace":"1h"}`)
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	var rotated wiki_domain.IssuedKey
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &rotated))
+	assert.NotEqual(t, issued.Key, rotated.Key)
+	assert.NotNil(t, rotated.PreviousExpiresAt)
+	assert.EqualValues(t, http.StatusBadRequest, serve(http.MethodPost, "/admin/keys/"+issued.ID+"/rotate", `{"grace":"soon"}`).Code)
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodPost, "/admin/keys/"+issued.ID+"/rotate", "").Code)
+
+	recorder = serve(http.MethodGet, "/admin/keys/"+issued.ID+"/usage?days=3", "")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	var usage wiki_domain.KeyUsage
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &usage))
+	assert.Len(t, usage.Days, 3)
+	assert.EqualValues(t, http.StatusBadRequest, serve(http.MethodGet, "/admin/keys/"+issued.ID+"/usage?days=90", "").Code)
+
+	assert.EqualValues(t, http.StatusNoContent, serve(http.MethodDelete, "/admin/keys/"+issued.ID, "").Code)
+	recorder = serve(http.MethodGet, "/admin/keys/"+issued.ID, "")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "revoked_at")
+	assert.EqualValues(t, http.StatusConflict, serve(http.MethodPost, "/admin/keys/"+issued.ID+"/rotate", "").Code)
+	assert.EqualValues(t, http.StatusNotFound, serve(http.MethodGet, "/admin/keys/missing", "").Code)
+}
+
+func TestKeysNotConfigured(t *testing.T) {
+	serve := serveKeys(t, false)
+
+	recorder := serve(http.MethodGet, "/admin/keys", "")
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+	assert.EqualValues(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+}
@@ -0,0 +1,333 @@
+package wiki_controller
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	ginrender "github.com/gin-gonic/gin/render"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// serializer renders a response in one format, Name is its ?format= value and the first
+// of ContentTypes the type it answers with
+type serializer struct {
+	Name         string
+	ContentTypes []string
+	Render       func(c *gin.Context, status int, value interface{}) error
+}
+
+// serializers are every format the API speaks, in order of preference when the client accepts several equally
+var serializers = []serializer{
+	{Name: "json", ContentTypes: []string{"application/json"}, Render: renderJSON},
+	{Name: "xml", ContentTypes: []string{"application/xml", "text/xml"}, Render: renderXML},
+	{Name: "yaml", ContentTypes: []string{"application/yaml", "application/x-yaml", "text/yaml"}, Render: renderYAML},
+	{Name: "csv", ContentTypes: []string{"text/csv"}, Render: renderCSV},
+	{Name: "msgpack", ContentTypes: []string{"application/msgpack", "application/x-msgpack"}, Render: renderMsgPack},
+}
+
+// render writes value in the format picked by ?format= or the Accept header, 406 when we speak none of them
+func render(c *gin.Context, status int, value interface{}) {
+	c.Writer.Header().Add("Vary", "Accept")
+	s := negotiateFormat(c)
+	if s == nil {
+		offered := make([]string, 0, len(serializers))
+		for _, s := range serializers {
+			offered = append(offered, s.ContentTypes[0])
+		}
+		renderError(c, wiki_domain.NewTypedError(wiki_domain.ErrNotAcceptable, "supported formats are "+strings.Join(offered, ", ")))
+		return
+	}
+	if err := s.Render(c, status, value); err != nil {
+		renderError(c, wiki_domain.NewTypedError(wiki_domain.ErrInternal, fmt.Sprintf("error rendering %s: %s", s.Name, err.Error())))
+	}
+}
+
+func negotiateFormat(c *gin.Context) *serializer {
+	if format := c.Query("format"); format != "" {
+		for i := range serializers {
+			if serializers[i].Name == strings.ToLower(format) {
+				return &serializers[i]
+			}
+		}
+		return nil
+	}
+	header := c.GetHeader("Accept")
+	if strings.TrimSpace(header) == "" {
+		return &serializers[0]
+	}
+	for _, accepted := range parseAccept(header) {
+		for i := range serializers {
+			for _, contentType := range serializers[i].ContentTypes {
+				if mediaTypeMatches(accepted, contentType) {
+					return &serializers[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseAccept returns the media types of an Accept header, best quality first, leaving out q=0
+func parseAccept(header string) []string {
+	type mediaType struct {
+		name    string
+		quality float64
+	}
+	var types []mediaType
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(params[0]))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			types = append(types, mediaType{name, quality})
+		}
+	}
+	sort.SliceStable(types, func(i, j int) bool { return types[i].quality > types[j].quality })
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, t.name)
+	}
+	return names
+}
+
+func mediaTypeMatches(accepted string, contentType string) bool {
+	if accepted == "*/*" || accepted == contentType {
+		return true
+	}
+	return strings.HasSuffix(accepted, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(accepted, "*"))
+}
+
+func renderJSON(c *gin.Context, status int, value interface{}) error {
+	c.JSON(status, value)
+	return nil
+}
+
+func renderYAML(c *gin.Context, status int, value interface{}) error {
+	tree, err := toTree(value)
+	if err != nil {
+		return err
+	}
+	c.Render(status, ginrender.YAML{Data: tree})
+	return nil
+}
+
+func renderMsgPack(c *gin.Context, status int, value interface{}) error {
+	tree, err := toTree(value)
+	if err != nil {
+		return err
+	}
+	c.Render(status, ginrender.MsgPack{Data: tree})
+	return nil
+}
+
+// toTree turns value into the maps, slices and scalars of its JSON form, so every format uses the JSON field names
+func toTree(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return numbers(tree), nil
+}
+
+// numbers swaps json.Number for int64 or float64, which the binary encoders understand
+func numbers(tree interface{}) interface{} {
+	switch value := tree.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			value[key] = numbers(v)
+		}
+	case []interface{}:
+		for i, v := range value {
+			value[i] = numbers(v)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	}
+	return tree
+}
+
+var xmlNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+func renderXML(c *gin.Context, status int, value interface{}) error {
+	tree, err := toTree(value)
+	if err != nil {
+		return err
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buffer)
+	if err := encodeXML(encoder, "response", nil, tree); err != nil {
+		return err
+	}
+	if err := encoder.Flush(); err != nil {
+		return err
+	}
+	c.Data(status, "application/xml; charset=utf-8", buffer.Bytes())
+	return nil
+}
+
+// encodeXML writes objects as one element per field and lists as repeated <item> elements. Keys that
+// are not valid element names (infobox fields can be anything) become <entry key="...">.
+func encodeXML(encoder *xml.Encoder, name string, attrs []xml.Attr, tree interface{}) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	switch value := tree.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child, childAttrs := key, []xml.Attr(nil)
+			if !xmlNameRe.MatchString(key) || strings.HasPrefix(strings.ToLower(key), "xml") {
+				child, childAttrs = "entry", []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}}
+			}
+			if err := encodeXML(encoder, child, childAttrs, value[key]); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range value {
+			if err := encodeXML(encoder, "item", nil, item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := encoder.EncodeToken(xml.CharData(fmt.Sprint(value))); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// renderCSV writes one row per result: the items of a list response (category members, history
+// changes...), or a single row for a lookup. Nested fields become dotted columns.
+func renderCSV(c *gin.Context, status int, value interface{}) error {
+	tree, err := toTree(value)
+	if err != nil {
+		return err
+	}
+	var rows []map[string]string
+	for _, item := range csvItems(tree) {
+		row := map[string]string{}
+		flatten("", item, row)
+		rows = append(rows, row)
+	}
+	columns := map[string]bool{}
+	for _, row := range rows {
+		for column := range row {
+			columns[column] = true
+		}
+	}
+	header := make([]string, 0, len(columns))
+	for column := range columns {
+		header = append(header, column)
+	}
+	sort.Strings(header)
+
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(header))
+		for i, column := range header {
+			record[i] = row[column]
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	c.Data(status, "text/csv; charset=utf-8", buffer.Bytes())
+	return nil
+}
+
+// csvItems picks the rows: a top level list, the only list of objects in an object, or the object itself
+func csvItems(tree interface{}) []interface{} {
+	if list, ok := tree.([]interface{}); ok {
+		return list
+	}
+	object, ok := tree.(map[string]interface{})
+	if !ok {
+		return []interface{}{tree}
+	}
+	var found []interface{}
+	lists := 0
+	for _, value := range object {
+		if list, ok := value.([]interface{}); ok && len(list) > 0 {
+			if _, isObject := list[0].(map[string]interface{}); isObject {
+				found = list
+				lists++
+			}
+		}
+	}
+	if lists == 1 {
+		return found
+	}
+	return []interface{}{tree}
+}
+
+func flatten(prefix string, tree interface{}, row map[string]string) {
+	switch value := tree.(type) {
+	case map[string]interface{}:
+		for key, v := range value {
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flatten(key, v, row)
+		}
+	case []interface{}:
+		parts := make([]string, 0, len(value))
+		for _, item := range value {
+			if text, isString := item.(string); isString {
+				parts = append(parts, text)
+			} else if encoded, err := json.Marshal(item); err == nil {
+				parts = append(parts, string(encoded))
+			}
+		}
+		row[prefix] = strings.Join(parts, "; ")
+	case nil:
+		row[prefix] = ""
+	default:
+		row[prefix] = fmt.Sprint(value)
+	}
+}
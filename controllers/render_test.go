@@ -0,0 +1,84 @@
+package wiki_controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+)
+
+func TestGetContentSummaryFormats(t *testing.T) {
+	setupProvider(t, map[string]string{"en": "Canadian computer scientist"})
+
+	request := httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil)
+	request.Header.Set("Accept", "text/html;q=0.9, application/xml")
+	recorder := serve(request)
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, "application/xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "<response><language>en</language><short_description>Canadian computer scientist</short_description></response>")
+	assert.EqualValues(t, []string{"Accept-Language", "Accept"}, recorder.Header().Values("Vary"))
+
+	recorder = serve(httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio?format=yaml", nil))
+	assert.EqualValues(t, "application/x-yaml; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "short_description: Canadian computer scientist")
+
+	recorder = serve(httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio?format=csv", nil))
+	assert.EqualValues(t, "language,short_description\nen,Canadian computer scientist\n", recorder.Body.String())
+
+	request = httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil)
+	request.Header.Set("Accept", "application/x-msgpack")
+	recorder = serve(request)
+	assert.EqualValues(t, "application/msgpack; charset=utf-8", recorder.Header().Get("Content-Type"))
+	// A fixmap with two entries
+	assert.EqualValues(t, 0x82, recorder.Body.Bytes()[0])
+}
+
+func TestGetContentSummaryNotAcceptable(t *testing.T) {
+	setupProvider(t, map[string]string{"en": "Canadian computer scientist"})
+
+	request := httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil)
+	request.Header.Set("Accept", "text/html, application/json;q=0")
+	recorder := serve(request)
+	assert.EqualValues(t, http.StatusNotAcceptable, recorder.Code)
+	assert.EqualValues(t, wiki_domain.ProblemContentType, recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), `"reason":"not_acceptable"`)
+
+	recorder = serve(httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio?format=pdf", nil))
+	assert.EqualValues(t, http.StatusNotAcceptable, recorder.Code)
+}
+
+func TestRenderCSVList(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/category/Turing_Award_laureates/members?format=csv", nil)
+
+	render(c, http.StatusOK, &wiki_domain.CategoryMembers{
+		Category: "Turing Award laureates",
+		Language: "en",
+		Members: []wiki_domain.CategoryMember{
+			{Pageid: 1, Title: "Alan Kay", ShortDescription: "American computer scientist"},
+			{Pageid: 47749536, Title: "Yoshua Bengio"},
+		},
+	})
+	assert.EqualValues(t, "ns,pageid,short_description,title\n0,1,American computer scientist,Alan Kay\n0,47749536,,Yoshua Bengio\n", recorder.Body.String())
+}
+
+func TestRenderXMLEntries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio?format=xml", nil)
+
+	render(c, http.StatusOK, &wiki_domain.Response{
+		ShortDescription: "Canadian computer scientist",
+		Infobox:          &wiki_domain.Infobox{Type: "scientist", Fields: map[string]string{"birth place": "Paris & France"}},
+		Redirects:        []string{"Bengio"},
+	})
+	assert.Contains(t, recorder.Body.String(), `<infobox><fields><entry key="birth place">Paris &amp; France</entry></fields><type>scientist</type></infobox>`)
+	assert.Contains(t, recorder.Body.String(), `<redirects><item>Bengio</item></redirects>`)
+}
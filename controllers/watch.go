@@ -0,0 +1,151 @@
+package wiki_controller
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	wiki_domain "wiki-names/domains"
+	wiki_watch "wiki-names/watchers"
+)
+
+// writeWait is how long a WebSocket write may block before we give up on the client
+const writeWait = 10 * time.Second
+
+// WatchOrigins are the origins, besides our own, whose pages may open a /watch WebSocket. A "*"
+// allows any page. Requests without an Origin don't come from a browser and are let through.
+var WatchOrigins []string
+
+var upgrader = websocket.Upgrader{CheckOrigin: allowedOrigin}
+
+// allowedOrigin keeps other sites' pages from opening a WebSocket with their visitors' credentials
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range WatchOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchMessage is a description change sent over a WebSocket, with its type like the SSE event name
+type watchMessage struct {
+	Type string `json:"type"`
+	wiki_domain.WatchEvent
+}
+
+// Watch pushes the short descriptions of the names given as they change, as server-sent events or
+// over a WebSocket when the request asks to upgrade. Both send a heartbeat while there is nothing
+// else to send, and end when the hub drops the subscription or shuts down.
+func Watch(c *gin.Context) {
+	var query wiki_domain.WatchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	if websocket.IsWebSocketUpgrade(c.Request) && !allowedOrigin(c.Request) {
+		renderError(c, wiki_domain.NewForbiddenError("origin "+c.GetHeader("Origin")+" may not watch, add it to WATCH_ALLOWED_ORIGINS"))
+		return
+	}
+	subscription, apiError := wiki_watch.Watcher.Subscribe(wiki_domain.TenantFrom(c.Request.Context()), query.Locale, strings.Split(query.Names, ","))
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	defer subscription.Close()
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		watchWebSocket(c, subscription)
+		return
+	}
+	watchEvents(c, subscription)
+}
+
+func watchEvents(c *gin.Context, subscription *wiki_watch.Subscription) {
+	c.Header("Cache-Control", "no-cache")
+	// Tell nginx not to buffer the stream
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("subscribed", gin.H{"language": subscription.Locale, "names": subscription.Names})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(wiki_watch.Watcher.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case change, ok := <-subscription.Events:
+			if !ok {
+				return
+			}
+			c.SSEvent("description", change)
+		case <-heartbeat.C:
+			// A comment line, EventSource clients ignore it
+			if _, err := c.Writer.WriteString(": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// watchWebSocket sends the changes as JSON messages and pings the client on every heartbeat, a
+// client that doesn't answer within two heartbeats is disconnected
+func watchWebSocket(c *gin.Context, subscription *wiki_watch.Subscription) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade has already replied with the error
+		return
+	}
+	defer conn.Close()
+
+	interval := wiki_watch.Watcher.Heartbeat
+	conn.SetReadDeadline(time.Now().Add(2 * interval))
+	conn.SetPongHandler(func(string) error { return conn.SetReadDeadline(time.Now().Add(2 * interval)) })
+	// We don't expect anything from the client, reading is how we notice it went away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := conn.WriteJSON(gin.H{"type": "subscribed", "language": subscription.Locale, "names": subscription.Names}); err != nil {
+		return
+	}
+	heartbeat := time.NewTicker(interval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-gone:
+			return
+		case change, ok := <-subscription.Events:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "subscription closed"), time.Now().Add(writeWait))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := conn.WriteJSON(watchMessage{Type: "description", WatchEvent: change}); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		}
+	}
+}
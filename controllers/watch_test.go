@@ -0,0 +1,146 @@
+package wiki_controller
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_provider "wiki-names/providers"
+	wiki_watch "wiki-names/watchers"
+)
+
+type descriptionsMock struct {
+	wiki_provider.WikiProviderStruct
+}
+
+// We are mocking the provider method "GetShortDescriptions", every name is a computer scientist
+func (dm *descriptionsMock) GetShortDescriptions(ctx context.Context, locale string, titles []string) (map[string]string, *wiki_domain.WikiError) {
+	descriptions := map[string]string{}
+	for _, title := range titles {
+		descriptions[title] = "computer scientist"
+	}
+	return descriptions, nil
+}
+
+// setupWatcher runs a fresh hub behind a real server, /watch streams so a recorder won't do
+func setupWatcher(t *testing.T) (*wiki_watch.Hub, *httptest.Server) {
+	original, originalProvider := wiki_watch.Watcher, wiki_provider.WikiProvider
+	hub := wiki_watch.NewHub()
+	hub.Heartbeat = 20 * time.Millisecond
+	wiki_watch.Watcher, wiki_provider.WikiProvider = hub, &descriptionsMock{}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(stopped)
+	}()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("watch", Watch)
+	server := httptest.NewServer(router)
+	t.Cleanup(func() {
+		cancel()
+		server.Close()
+		// A poll may still be reading the provider
+		<-stopped
+		wiki_watch.Watcher, wiki_provider.WikiProvider = original, originalProvider
+	})
+	return hub, server
+}
+
+func TestWatchEvents(t *testing.T) {
+	hub, server := setupWatcher(t)
+
+	response, err := http.Get(server.URL + "/watch?names=Yoshua_Bengio")
+	assert.Nil(t, err)
+	defer response.Body.Close()
+	assert.EqualValues(t, http.StatusOK, response.StatusCode)
+	assert.EqualValues(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	var lines []string
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() && !strings.HasPrefix(scanner.Text(), ": heartbeat") {
+		lines = append(lines, scanner.Text())
+	}
+	assert.Contains(t, lines, "event:subscribed")
+	assert.Contains(t, lines, "event:description")
+	assert.Contains(t, strings.Join(lines, "\n"), `"name":"Yoshua_Bengio","language":"en","short_description":"computer scientist"`)
+
+	// Closing the hub ends the stream
+	hub.Close()
+	for scanner.Scan() {
+	}
+	assert.EqualValues(t, 0, hub.Subscribers())
+}
+
+func TestWatchWebSocket(t *testing.T) {
+	hub, server := setupWatcher(t)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/watch?names=Yoshua_Bengio,Geoffrey_Hinton&locale=fr", nil)
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	var subscribed map[string]interface{}
+	assert.Nil(t, conn.ReadJSON(&subscribed))
+	assert.EqualValues(t, "subscribed", subscribed["type"])
+	assert.EqualValues(t, "fr", subscribed["language"])
+	names := map[string]bool{}
+	for len(names) < 2 {
+		var message watchMessage
+		assert.Nil(t, conn.ReadJSON(&message))
+		assert.EqualValues(t, "description", message.Type)
+		names[message.Name] = true
+	}
+	assert.EqualValues(t, map[string]bool{"Yoshua_Bengio": true, "Geoffrey_Hinton": true}, names)
+
+	// Shutting down sends a close frame
+	hub.Close()
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway))
+}
+
+func TestWatchWebSocketOrigin(t *testing.T) {
+	_, server := setupWatcher(t)
+	target := "ws" + strings.TrimPrefix(server.URL, "http") + "/watch?names=Yoshua_Bengio&locale=fr"
+	defer func() { WatchOrigins = nil }()
+
+	// Another site's page can't open one
+	_, response, err := websocket.DefaultDialer.Dial(target, http.Header{"Origin": {"https://evil.example"}})
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusForbidden, response.StatusCode)
+
+	// Our own pages, and the allowed origins', can
+	conn, _, err := websocket.DefaultDialer.Dial(target, http.Header{"Origin": {server.URL}})
+	assert.Nil(t, err)
+	conn.Close()
+	WatchOrigins = []string{"https://app.example"}
+	conn, _, err = websocket.DefaultDialer.Dial(target, http.Header{"Origin": {"https://app.example"}})
+	assert.Nil(t, err)
+	conn.Close()
+}
+
+func TestWatchBadRequest(t *testing.T) {
+	hub, server := setupWatcher(t)
+	hub.MaxSubscribers = 0
+
+	response, err := http.Get(server.URL + "/watch")
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, response.StatusCode)
+	response.Body.Close()
+
+	response, err = http.Get(server.URL + "/watch?names=Yoshua_Bengio")
+	assert.Nil(t, err)
+	assert.EqualValues(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.EqualValues(t, "60", response.Header.Get("Retry-After"))
+	response.Body.Close()
+}
@@ -0,0 +1,102 @@
+package wiki_controller
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+	wiki_webhook "wiki-names/webhooks"
+)
+
+// CreateWebhook registers a callback URL, the response is the only time its secret is shown
+func CreateWebhook(c *gin.Context) {
+	var request wiki_domain.WebhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	webhook, apiError := wiki_webhook.Webhooks.Create(wiki_domain.TenantFrom(c.Request.Context()), request)
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	c.Header("Location", "/webhooks/"+webhook.ID)
+	render(c, http.StatusCreated, webhook)
+}
+
+// GetWebhook is a webhook of the request's tenant, the webhooks of other tenants are not found
+func GetWebhook(c *gin.Context) {
+	webhook, apiError := wiki_webhook.Webhooks.Get(wiki_domain.TenantFrom(c.Request.Context()), c.Param("id"))
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, webhook)
+}
+
+func DeleteWebhook(c *gin.Context) {
+	if apiError := wiki_webhook.Webhooks.Delete(wiki_domain.TenantFrom(c.Request.Context()), c.Param("id")); apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries is the delivery log of one webhook, newest first
+func GetWebhookDeliveries(c *gin.Context) {
+	if _, apiError := wiki_webhook.Webhooks.Get(wiki_domain.TenantFrom(c.Request.Context()), c.Param("id")); apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	var query wiki_domain.DeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	query.Webhook = c.Param("id")
+	render(c, http.StatusOK, wiki_webhook.Webhooks.Deliveries(query))
+}
+
+// ListWebhooks is every registered webhook, or a tenant's with ?tenant=
+func ListWebhooks(c *gin.Context) {
+	render(c, http.StatusOK, wiki_webhook.Webhooks.List(c.Query("tenant")))
+}
+
+// ListDeliveries is the delivery log of every webhook, filtered by ?webhook= and ?status=
+func ListDeliveries(c *gin.Context) {
+	var query wiki_domain.DeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	render(c, http.StatusOK, wiki_webhook.Webhooks.Deliveries(query))
+}
+
+// ListDeadLetters is the deliveries that ran out of attempts
+func ListDeadLetters(c *gin.Context) {
+	var query wiki_domain.DeliveryQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	query.Status = wiki_domain.DeliveryDead
+	render(c, http.StatusOK, wiki_webhook.Webhooks.Deliveries(query))
+}
+
+func RetryDeadLetter(c *gin.Context) {
+	delivery, apiError := wiki_webhook.Webhooks.Retry(c.Param("id"))
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusAccepted, delivery)
+}
+
+func DiscardDeadLetter(c *gin.Context) {
+	if apiError := wiki_webhook.Webhooks.Discard(c.Param("id")); apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,102 @@
+package wiki_controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_webhook "wiki-names/webhooks"
+)
+
+func serveWebhooks(t *testing.T) func(method string, target string, body string, tenant ...string) *httptest.ResponseRecorder {
+	original := wiki_webhook.Webhooks
+	wiki_webhook.Webhooks = wiki_webhook.NewManager()
+	t.Cleanup(func() { wiki_webhook.Webhooks = original })
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// The tenant middleware's job, with the tenant named by the test
+	router.Use(func(c *gin.Context) {
+		if id := c.GetHeader("X-Test-Tenant"); id != "" {
+			c.Request = c.Request.WithContext(wiki_domain.WithTenant(c.Request.Context(), &wiki_domain.Tenant{ID: id}))
+		}
+	})
+	router.POST("webhooks", CreateWebhook)
+	router.GET("webhooks/:id", GetWebhook)
+	router.DELETE("webhooks/:id", DeleteWebhook)
+	router.GET("webhooks/:id/deliveries", GetWebhookDeliveries)
+	router.GET("admin/dead-letters", ListDeadLetters)
+	return func(method string, target string, body string, tenant ...string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if len(tenant) > 0 {
+			request.Header.Set("X-Test-Tenant", tenant[0])
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	serve := serveWebhooks(t)
+
+	recorder := serve(http.MethodPost, "/webhooks", `{"url":"https://example.org/hook","names":["Yoshua_Bengio"],"categories":["Turing Award laureates"]}`, "acme")
+	assert.EqualValues(t, http.StatusCreated, recorder.Code)
+	var created wiki_domain.Webhook
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.NotEmpty(t, created.Secret)
+	assert.EqualValues(t, "/webhooks/"+created.ID, recorder.Header().Get("Location"))
+
+	recorder = serve(http.MethodGet, "/webhooks/"+created.ID, "", "acme")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.NotContains(t, recorder.Body.String(), created.Secret)
+
+	recorder = serve(http.MethodGet, "/webhooks/"+created.ID+"/deliveries", "", "acme")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `[]`, recorder.Body.String())
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodGet, "/admin/dead-letters", "").Code)
+
+	assert.EqualValues(t, http.StatusNoContent, serve(http.MethodDelete, "/webhooks/"+created.ID, "", "acme").Code)
+	assert.EqualValues(t, http.StatusNotFound, serve(http.MethodGet, "/webhooks/"+created.ID, "", "acme").Code)
+	assert.EqualValues(t, http.StatusNotFound, serve(http.MethodGet, "/webhooks/"+created.ID+"/deliveries", "", "acme").Code)
+}
+
+func TestCreateWebhookBadRequest(t *testing.T) {
+	serve := serveWebhooks(t)
+
+	assert.EqualValues(t, http.StatusBadRequest, serve(http.MethodPost, "/webhooks", `{"names":["a"]}`, "acme").Code)
+	assert.EqualValues(t, http.StatusBadRequest, serve(http.MethodPost, "/webhooks", `{"url":"not a url","names":["a"]}`, "acme").Code)
+	assert.EqualValues(t, http.StatusBadRequest, serve(http.MethodPost, "/webhooks", `{"url":"https://example.org","names":["a"],"secret":"short"}`, "acme").Code)
+}
+
+func TestWebhookTenants(t *testing.T) {
+	serve := serveWebhooks(t)
+
+	// The tenant comes from the request's credentials, not from the body
+	recorder := serve(http.MethodPost, "/webhooks", `{"url":"https://example.org/hook","names":["a"],"tenant":"globex"}`, "acme")
+	assert.EqualValues(t, http.StatusCreated, recorder.Code)
+	var created wiki_domain.Webhook
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.EqualValues(t, "acme", created.Tenant)
+
+	for _, tenant := range []string{"globex", ""} {
+		recorder = serve(http.MethodGet, "/webhooks/"+created.ID, "", tenant)
+		assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"reason":"not_found"`)
+		assert.EqualValues(t, http.StatusNotFound, serve(http.MethodGet, "/webhooks/"+created.ID+"/deliveries", "", tenant).Code)
+		assert.EqualValues(t, http.StatusNotFound, serve(http.MethodDelete, "/webhooks/"+created.ID, "", tenant).Code)
+	}
+	assert.EqualValues(t, http.StatusOK, serve(http.MethodGet, "/webhooks/"+created.ID, "", "acme").Code)
+	assert.EqualValues(t, http.StatusNoContent, serve(http.MethodDelete, "/webhooks/"+created.ID, "", "acme").Code)
+
+	// Without a tenant there's nothing to scope the webhook to
+	recorder = serve(http.MethodPost, "/webhooks", `{"url":"https://example.org/hook","names":["a"]}`)
+	assert.EqualValues(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"reason":"not_enabled"`)
+}
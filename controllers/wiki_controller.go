@@ -1,45 +1,192 @@
 package wiki_controller
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 
 	wiki_domain "wiki-names/domains"
+	wiki_locale "wiki-names/locales"
+	wiki_middleware "wiki-names/middlewares"
 	wiki_provider "wiki-names/providers"
 )
 
+type lookupFunc func(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError)
+
 func GetContentSummary(c *gin.Context) {
-	var query wiki_domain.RequestQuery
+	query := wiki_domain.RequestQuery{Tenant: wiki_domain.TenantFrom(c.Request.Context())}
 	if err := c.ShouldBindUri(&query); err != nil {
-		log.Fatalln("Missing name in query string")
-		c.JSON(400, gin.H{"msg": err})
+		log.Println("Missing name in query string")
+		renderError(c, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, err.Error()))
+		return
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	if query.Langs != "" {
+		translations, apiError := wiki_provider.WikiProvider.GetTranslations(c.Request.Context(), query)
+		if apiError != nil {
+			renderError(c, apiError)
+			return
+		}
+		render(c, http.StatusOK, translations)
 		return
 	}
-	result, apiError := wiki_provider.WikiProvider.GetContentSummary(query)
+	result, apiError := negotiate(c, query, wiki_provider.WikiProvider.GetContentSummary)
 	if apiError != nil {
-		c.JSON(apiError.Code, apiError)
+		renderError(c, apiError)
 		return
 	}
-	c.JSON(http.StatusOK, result)
+	renderResponse(c, result, query.Fields)
 }
 
 func GetExtract(c *gin.Context) {
-	var query wiki_domain.RequestQuery
+	query := wiki_domain.RequestQuery{Tenant: wiki_domain.TenantFrom(c.Request.Context())}
 	if err := c.ShouldBindUri(&query); err != nil {
-		log.Fatalln("Missing name in query string")
-		c.JSON(400, gin.H{"msg": err})
+		log.Println("Missing name in query string")
+		renderError(c, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, err.Error()))
 		return
 	}
-	// Use a default language if not set in the URL
-	if query.Locale == "" {
-		query.Locale = "en"
+	if err := c.ShouldBindQuery(&query); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
 	}
-	result, apiError := wiki_provider.WikiProvider.GetExtract(query)
+	result, apiError := negotiate(c, query, wiki_provider.WikiProvider.GetExtract)
 	if apiError != nil {
-		c.JSON(apiError.Code, apiError)
+		renderError(c, apiError)
+		return
+	}
+	renderResponse(c, result, query.Fields)
+}
+
+func GetBio(c *gin.Context) {
+	query := wiki_domain.RequestQuery{Tenant: wiki_domain.TenantFrom(c.Request.Context())}
+	if err := c.ShouldBindUri(&query); err != nil {
+		log.Println("Missing name in query string")
+		renderError(c, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, err.Error()))
+		return
+	}
+	result, apiError := wiki_provider.WikiProvider.GetBio(c.Request.Context(), query)
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, result)
+}
+
+func GetHistory(c *gin.Context) {
+	query := wiki_domain.RequestQuery{Tenant: wiki_domain.TenantFrom(c.Request.Context())}
+	if err := c.ShouldBindUri(&query); err != nil {
+		log.Println("Missing name in query string")
+		renderError(c, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, err.Error()))
+		return
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	result, apiError := wiki_provider.WikiProvider.GetHistory(c.Request.Context(), query)
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, result)
+}
+
+func GetCategories(c *gin.Context) {
+	query := wiki_domain.RequestQuery{Tenant: wiki_domain.TenantFrom(c.Request.Context())}
+	if err := c.ShouldBindUri(&query); err != nil {
+		log.Println("Missing name in query string")
+		renderError(c, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, err.Error()))
 		return
 	}
-	c.JSON(http.StatusOK, result)
+	result, apiError := wiki_provider.WikiProvider.GetCategories(c.Request.Context(), query)
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, result)
+}
+
+func GetCategoryMembers(c *gin.Context) {
+	query := wiki_domain.CategoryQuery{Tenant: wiki_domain.TenantFrom(c.Request.Context())}
+	if err := c.ShouldBindUri(&query); err != nil {
+		log.Println("Missing category in query string")
+		renderError(c, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, err.Error()))
+		return
+	}
+	if err := c.ShouldBindQuery(&query); err != nil {
+		renderError(c, wiki_domain.NewBadRequestError(err.Error()))
+		return
+	}
+	result, apiError := wiki_provider.WikiProvider.GetCategoryMembers(c.Request.Context(), query)
+	if apiError != nil {
+		renderError(c, apiError)
+		return
+	}
+	render(c, http.StatusOK, result)
+}
+
+// negotiate looks the name up in the locale from the URL or, when there is none, walks the
+// Accept-Language preferences the tenant allows (falling back to its default locale, or en)
+// until one of them has a description
+func negotiate(c *gin.Context, query wiki_domain.RequestQuery, lookup lookupFunc) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	c.Header("Vary", "Accept-Language")
+	if query.Locale != "" {
+		return lookup(c.Request.Context(), query)
+	}
+	var firstError *wiki_domain.WikiError
+	tags := wiki_locale.ParseAcceptLanguage(c.GetHeader("Accept-Language"))
+	for _, locale := range wiki_locale.Locales.Candidates(tags, query.Tenant.LocaleOr(wiki_locale.DefaultLocale)) {
+		if !query.Tenant.AllowsLocale(locale.Code) {
+			continue
+		}
+		query.Locale, query.Variant = locale.Code, locale.Variant
+		result, apiError := lookup(c.Request.Context(), query)
+		if apiError == nil {
+			return result, nil
+		}
+		if firstError == nil {
+			firstError = apiError
+		}
+		// Only a missing page or description is worth retrying in the next language, and a revision ID only exists in one edition
+		if query.Revid > 0 || (apiError.ErrorCode != wiki_domain.ErrPageNotFound && apiError.ErrorCode != wiki_domain.ErrPageMissingDescription) {
+			return nil, apiError
+		}
+	}
+	if firstError == nil {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrNotEnabled, "none of the accepted languages is enabled for tenant "+query.Tenant.ID)
+	}
+	return nil, firstError
+}
+
+// renderResponse writes the result, pruned to the ?fields= asked for
+func renderResponse(c *gin.Context, result *wiki_domain.Response, fields string) {
+	if result.Language != "" {
+		c.Header("Content-Language", result.Language)
+	}
+	if fields == "" {
+		render(c, http.StatusOK, result)
+		return
+	}
+	selected, err := selectFields(result, fields)
+	if err != nil {
+		renderError(c, &wiki_domain.WikiError{Code: http.StatusInternalServerError, ErrorMessage: err.Error()})
+		return
+	}
+	render(c, http.StatusOK, selected)
+}
+
+// renderError writes apiError as an RFC 7807 application/problem+json response
+func renderError(c *gin.Context, apiError wiki_domain.WikiErrorInterface) {
+	problem := wiki_domain.NewProblemDetails(apiError, c.Request.URL.RequestURI(), c.GetString(wiki_middleware.RequestIDKey))
+	if problem.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(problem.RetryAfter))
+	}
+	c.Header("Content-Type", wiki_domain.ProblemContentType)
+	c.JSON(problem.Status, problem)
 }
@@ -0,0 +1,149 @@
+package wiki_controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_provider "wiki-names/providers"
+)
+
+type providerMock struct {
+	wiki_provider.WikiProviderStruct
+	descriptions map[string]string
+	locales      []string
+	categories   []wiki_domain.CategoryQuery
+}
+
+// We are mocking the provider method "GetContentSummary", only the locales in descriptions have one
+func (pm *providerMock) GetContentSummary(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	pm.locales = append(pm.locales, request.Locale)
+	if description, ok := pm.descriptions[request.Locale]; ok {
+		return &wiki_domain.Response{ShortDescription: description, Language: request.Locale}, nil
+	}
+	return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageMissingDescription, "Missing `Short description` in json response body")
+}
+
+// We are mocking the provider method "GetCategoryMembers", every category is empty
+func (pm *providerMock) GetCategoryMembers(ctx context.Context, query wiki_domain.CategoryQuery) (*wiki_domain.CategoryMembers, *wiki_domain.WikiError) {
+	pm.categories = append(pm.categories, query)
+	return &wiki_domain.CategoryMembers{Category: query.Category, Language: query.Tenant.LocaleOr("en"), Members: []wiki_domain.CategoryMember{}}, nil
+}
+
+func setupProvider(t *testing.T, descriptions map[string]string) *providerMock {
+	mock := &providerMock{descriptions: descriptions}
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = mock
+	t.Cleanup(func() { wiki_provider.WikiProvider = original })
+	return mock
+}
+
+func serve(request *http.Request) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("search/:name", GetContentSummary)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestGetContentSummaryAcceptLanguageFallback(t *testing.T) {
+	mock := setupProvider(t, map[string]string{"fr": "informaticien canadien", "en": "Canadian computer scientist"})
+
+	request := httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil)
+	request.Header.Set("Accept-Language", "de-CH, de;q=0.9, fr;q=0.8")
+	recorder := serve(request)
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, []string{"de", "fr"}, mock.locales)
+	assert.EqualValues(t, "fr", recorder.Header().Get("Content-Language"))
+	var response wiki_domain.Response
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.EqualValues(t, "informaticien canadien", response.ShortDescription)
+	assert.EqualValues(t, "fr", response.Language)
+}
+
+func TestGetContentSummaryNoDescriptionInAnyLanguage(t *testing.T) {
+	mock := setupProvider(t, map[string]string{})
+
+	request := httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil)
+	request.Header.Set("Accept-Language", "it")
+	recorder := serve(request)
+
+	assert.EqualValues(t, []string{"it", "en"}, mock.locales)
+	assert.EqualValues(t, http.StatusNotFound, recorder.Code)
+	assert.EqualValues(t, wiki_domain.ProblemContentType, recorder.Header().Get("Content-Type"))
+	var problem wiki_domain.ProblemDetails
+	assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.EqualValues(t, wiki_domain.ErrPageMissingDescription, problem.Reason)
+	assert.EqualValues(t, "/search/Yoshua_Bengio", problem.Instance)
+}
+
+func TestGetContentSummaryBadAsOf(t *testing.T) {
+	setupProvider(t, map[string]string{"en": "Canadian computer scientist"})
+
+	recorder := serve(httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio?as_of=June+2021", nil))
+	assert.EqualValues(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = serve(httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio?as_of=2021-06-01T00:00:00Z", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+}
+
+func TestGetContentSummaryFields(t *testing.T) {
+	setupProvider(t, map[string]string{"en": "Canadian computer scientist"})
+
+	recorder := serve(httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio?fields=short_description,image.thumbnail", nil))
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"short_description":"Canadian computer scientist"}`, recorder.Body.String())
+}
+
+func TestSelectFields(t *testing.T) {
+	response := &wiki_domain.Response{
+		ShortDescription: "Canadian computer scientist",
+		Language:         "en",
+		Image:            &wiki_domain.Image{File: "Yoshua_Bengio_2017.jpg", Thumbnail: "https://upload.wikimedia.org/320px.jpg", Width: 320},
+	}
+	selected, err := selectFields(response, "language, image.thumbnail,image.width,missing.field")
+	assert.Nil(t, err)
+	assert.EqualValues(t, map[string]interface{}{
+		"language": "en",
+		"image":    map[string]interface{}{"thumbnail": "https://upload.wikimedia.org/320px.jpg", "width": float64(320)},
+	}, selected)
+}
+
+func TestGetContentSummaryTenantLocales(t *testing.T) {
+	mock := setupProvider(t, map[string]string{"de": "kanadischer Informatiker", "en": "Canadian computer scientist"})
+	tenant := &wiki_domain.Tenant{ID: "acme", AllowedLocales: []string{"fr", "de"}, DefaultLocale: "de"}
+
+	request := httptest.NewRequest(http.MethodGet, "/search/Yoshua_Bengio", nil)
+	request.Header.Set("Accept-Language", "it, en;q=0.8, fr;q=0.5")
+	recorder := serve(request.WithContext(wiki_domain.WithTenant(request.Context(), tenant)))
+
+	// Only the tenant's locales are tried, ending with its default rather than en
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, []string{"fr", "de"}, mock.locales)
+	assert.EqualValues(t, "de", recorder.Header().Get("Content-Language"))
+}
+
+func TestGetCategoryMembersTenant(t *testing.T) {
+	mock := setupProvider(t, map[string]string{})
+	tenant := &wiki_domain.Tenant{ID: "acme", AllowedLocales: []string{"fr"}, DefaultLocale: "fr"}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("category/:category/members", GetCategoryMembers)
+	request := httptest.NewRequest(http.MethodGet, "/category/Turing_Award_laureates/members?limit=5", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request.WithContext(wiki_domain.WithTenant(request.Context(), tenant)))
+
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Len(t, mock.categories, 1)
+	assert.EqualValues(t, tenant, mock.categories[0].Tenant)
+	assert.EqualValues(t, 5, mock.categories[0].Limit)
+}
@@ -0,0 +1,16 @@
+package wiki_domain
+
+// BatchRequest looks several names up at once, in the same locale and with the same facets
+type BatchRequest struct {
+	Names   []string `json:"names" binding:"required,min=1,max=500"`
+	Locale  string   `json:"locale"`
+	Type    string   `json:"type" binding:"omitempty,oneof=person"`
+	Include string   `json:"include"`
+}
+
+// BatchResult is one name of a batch, with its lookup or the problem it ran into
+type BatchResult struct {
+	Name   string          `json:"name"`
+	Result *Response       `json:"result,omitempty"`
+	Error  *ProblemDetails `json:"error,omitempty"`
+}
@@ -0,0 +1,20 @@
+package wiki_domain
+
+// BioField is one value pulled out of a lead sentence, Confidence runs from 0 to 1
+type BioField struct {
+	Value      string  `json:"value"`
+	Text       string  `json:"text,omitempty"`
+	Confidence float64 `json:"confidence"`
+}
+
+// Bio is the structured data parsed from a biography's lead sentence
+type Bio struct {
+	Name         *BioField  `json:"name,omitempty"`
+	Honorifics   []BioField `json:"honorifics"`
+	PostNominals []BioField `json:"post_nominals"`
+	BirthDate    *BioField  `json:"birth_date,omitempty"`
+	DeathDate    *BioField  `json:"death_date,omitempty"`
+	Nationality  *BioField  `json:"nationality,omitempty"`
+	Occupations  []BioField `json:"occupations"`
+	LeadSentence string     `json:"lead_sentence"`
+}
@@ -0,0 +1,50 @@
+package wiki_domain
+
+// CategoryQuery is the request for /category/:category/members
+type CategoryQuery struct {
+	Category string `uri:"category" binding:"required"`
+	Locale   string `form:"locale"`
+	// Type is the kind of member listed, pages by default
+	Type  string `form:"type" binding:"omitempty,oneof=page subcat file"`
+	Limit int    `form:"limit" binding:"min=0,max=500"`
+	// Cursor is the cursor of the previous response, to get the next page of members
+	Cursor string `form:"cursor"`
+	// Descriptions adds each member's short description, looked up 50 titles at a time
+	Descriptions bool `form:"descriptions"`
+	// Tenant is whose locales the members are listed in, set by the handler from the request context
+	Tenant *Tenant `uri:"-" form:"-"`
+}
+
+// Categories is a page's visible categories, without the namespace prefix
+type Categories struct {
+	Title      string   `json:"title"`
+	Language   string   `json:"language"`
+	Categories []string `json:"categories"`
+}
+
+type CategoryMember struct {
+	Pageid           int    `json:"pageid"`
+	Ns               int    `json:"ns"`
+	Title            string `json:"title"`
+	ShortDescription string `json:"short_description,omitempty"`
+}
+
+// CategoryMembers is one page of a category's members, Cursor is left out on the last one
+type CategoryMembers struct {
+	Category string           `json:"category"`
+	Language string           `json:"language"`
+	Members  []CategoryMember `json:"members"`
+	Cursor   string           `json:"cursor,omitempty"`
+}
+
+// CategoryMembersResponse is the list=categorymembers response
+type CategoryMembersResponse struct {
+	Query struct {
+		Categorymembers []CategoryMember `json:"categorymembers"`
+	} `json:"query"`
+}
+
+// ShortDescriptions is the prop=pageprops response when asking for the wikibase-shortdesc of several titles
+type ShortDescriptions struct {
+	Query QueryPageRevisionType `json:"query"`
+}
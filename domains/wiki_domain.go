@@ -1,23 +1,118 @@
 package wiki_domain
 
+import (
+	"sort"
+	"strings"
+	"time"
+
+	wiki_sentence "wiki-names/sentences"
+)
+
 type RequestQuery struct {
 	Name   string `uri:"name" binding:"required"`
 	Locale string `uri:"locale"`
+	// Variant is the script/region conversion (e.g. zh-hant) picked out of Locale when it is normalized
+	Variant string `uri:"-"`
+	// Extract length limits, zero means no limit
+	Sentences  int `form:"sentences" binding:"min=0,max=100"`
+	Chars      int `form:"chars" binding:"min=0,max=100000"`
+	Paragraphs int `form:"paragraphs" binding:"min=0,max=100"`
+	// Type=person rejects pages that are not about a human
+	Type string `form:"type" binding:"omitempty,oneof=person"`
+	// Langs is a comma separated list of editions to describe the page in, through its interlanguage links
+	Langs string `form:"langs"`
+	// AsOf and Revid look the page up as it was at that time, or at that revision, instead of its latest revision
+	AsOf  time.Time `form:"as_of" time_format:"2006-01-02T15:04:05Z07:00"`
+	Revid int64     `form:"revid" binding:"min=0"`
+	// Limit caps how many revisions /history reads, newest first
+	Limit int `form:"limit" binding:"min=0,max=500"`
+	// Include is a comma separated list of the optional facets a lookup adds to its response, e.g. image,infobox
+	Include string `form:"include"`
+	// Fields prunes the response to these comma separated fields, nested ones with a dot (image.thumbnail)
+	Fields string `form:"fields"`
+	// Tenant is whose settings the lookup follows, set by the handler from the request context
+	Tenant *Tenant `uri:"-" form:"-"`
+}
+
+// Includes reports whether the optional part was asked for with ?include=
+func (r RequestQuery) Includes(part string) bool {
+	for _, include := range strings.Split(r.Include, ",") {
+		if strings.TrimSpace(include) == part {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RequestQuery) Limits() wiki_sentence.Limits {
+	return wiki_sentence.Limits{Sentences: r.Sentences, Chars: r.Chars, Paragraphs: r.Paragraphs}
 }
+
 type Response struct {
 	ShortDescription string `json:"short_description"`
+	// Language is the edition (or variant) the description was served from
+	Language string `json:"language,omitempty"`
+	// IsPerson is left out when there was no evidence either way
+	IsPerson *bool `json:"is_person,omitempty"`
+	// Name is the title split into given, family... names, for pages that may be about a person
+	Name *PersonName `json:"name,omitempty"`
+	// Revision is the page revision the description was read from
+	Revision *RevisionInfo `json:"revision,omitempty"`
+	// The optional facets, each only looked up when ?include= asks for it
+	Image      *Image            `json:"image,omitempty"`
+	Extract    string            `json:"extract,omitempty"`
+	Infobox    *Infobox          `json:"infobox,omitempty"`
+	Categories []string          `json:"categories,omitempty"`
+	Langlinks  map[string]string `json:"langlinks,omitempty"`
+	Redirects  []string          `json:"redirects,omitempty"`
+}
+
+// RevisionInfo identifies a page revision and who made it
+type RevisionInfo struct {
+	Revid      int64     `json:"revid"`
+	Parentid   int64     `json:"parentid"`
+	Timestamp  time.Time `json:"timestamp"`
+	User       string    `json:"user,omitempty"`
+	Userid     int64     `json:"userid,omitempty"`
+	UserHidden bool      `json:"userhidden,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
 }
 
 type ContentRevision struct {
-	Contentformat string `json:"contentformat"`
-	Contentmodel  string `json:"contentmodel"`
-	Content       string `json:"content"`
+	Revid         int64     `json:"revid,omitempty"`
+	Parentid      int64     `json:"parentid,omitempty"`
+	Timestamp     time.Time `json:"timestamp,omitempty"`
+	User          string    `json:"user,omitempty"`
+	Userid        int64     `json:"userid,omitempty"`
+	UserHidden    bool      `json:"userhidden,omitempty"`
+	Comment       string    `json:"comment,omitempty"`
+	Texthidden    bool      `json:"texthidden,omitempty"`
+	Contentformat string    `json:"contentformat"`
+	Contentmodel  string    `json:"contentmodel"`
+	Content       string    `json:"content"`
 }
 type PageRevision struct {
-	Pageid    int               `json:"pageid"`
-	Ns        int               `json:"ns"`
-	Title     string            `json:"title"`
-	Revisions []ContentRevision `json:"revisions"`
+	Pageid        int               `json:"pageid"`
+	Ns            int               `json:"ns"`
+	Title         string            `json:"title"`
+	Missing       bool              `json:"missing,omitempty"`
+	Invalid       bool              `json:"invalid,omitempty"`
+	InvalidReason string            `json:"invalidreason,omitempty"`
+	Pageprops     PageProps         `json:"pageprops"`
+	Categories    []PageCategory    `json:"categories,omitempty"`
+	Revisions     []ContentRevision `json:"revisions"`
+}
+
+type PageProps struct {
+	WikibaseItem string `json:"wikibase_item,omitempty"`
+	// ShortDescription is the local {{Short description}}, as MediaWiki stores it in the page props
+	ShortDescription string `json:"wikibase-shortdesc,omitempty"`
+}
+
+type PageCategory struct {
+	Ns     int    `json:"ns"`
+	Title  string `json:"title"`
+	Hidden bool   `json:"hidden,omitempty"`
 }
 
 type Normalize struct {
@@ -26,10 +121,14 @@ type Normalize struct {
 	To          string `json:"to"`
 }
 type PageExtract struct {
-	Pageid  int    `json:"pageid"`
-	Ns      int    `json:"ns"`
-	Title   string `json:"title"`
-	Extract string `json:"extract"`
+	Pageid        int       `json:"pageid"`
+	Ns            int       `json:"ns"`
+	Title         string    `json:"title"`
+	Missing       bool      `json:"missing,omitempty"`
+	Invalid       bool      `json:"invalid,omitempty"`
+	InvalidReason string    `json:"invalidreason,omitempty"`
+	Pageprops     PageProps `json:"pageprops"`
+	Extract       string    `json:"extract"`
 }
 
 type ContinueType struct {
@@ -40,10 +139,31 @@ type ContinueType struct {
 type WarningsType struct {
 	Main      WarningsSimpleType `json:"main"`
 	Revisions WarningsSimpleType `json:"revisions"`
+	Extracts  WarningsSimpleType `json:"extracts"`
+}
+
+// Messages lists the non empty warnings, prefixed with the module that raised them
+func (w WarningsType) Messages() []string {
+	var messages []string
+	for module, warning := range map[string]WarningsSimpleType{"main": w.Main, "revisions": w.Revisions, "extracts": w.Extracts} {
+		if warning.Warnings != "" {
+			messages = append(messages, module+": "+warning.Warnings)
+		}
+	}
+	sort.Strings(messages)
+	return messages
 }
+
 type QueryPageRevisionType struct {
 	Normalized []Normalize    `json:"normalized"`
 	Pages      []PageRevision `json:"pages"`
+	// Badrevids lists the revids= that do not exist, keyed by the revision ID
+	Badrevids map[string]BadRevision `json:"badrevids,omitempty"`
+}
+
+type BadRevision struct {
+	Revid   int64 `json:"revid"`
+	Missing bool  `json:"missing"`
 }
 type QueryPageExtractType struct {
 	Normalized []Normalize   `json:"normalized"`
@@ -56,10 +176,18 @@ type WarningsSimpleType struct {
 type Content struct {
 	Continue ContinueType          `json:"continue"`
 	Warnings WarningsType          `json:"warnings"`
+	Error    *MediaWikiError       `json:"error,omitempty"`
 	Query    QueryPageRevisionType `json:"query"`
 }
 
 type Extract struct {
 	Batchcomplete bool                 `json:"batchcomplete"`
+	Warnings      WarningsType         `json:"warnings"`
+	Error         *MediaWikiError      `json:"error,omitempty"`
 	Query         QueryPageExtractType `json:"query"`
 }
+
+// Info is the revision's metadata, without its content
+func (r ContentRevision) Info() *RevisionInfo {
+	return &RevisionInfo{Revid: r.Revid, Parentid: r.Parentid, Timestamp: r.Timestamp, User: r.User, Userid: r.Userid, UserHidden: r.UserHidden, Comment: r.Comment}
+}
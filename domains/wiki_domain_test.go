@@ -84,3 +84,48 @@ func Test_ContentError(t *testing.T) {
 	assert.EqualValues(t, errResult.Code, request.Code)
 	assert.EqualValues(t, errResult.ErrorMessage, request.ErrorMessage)
 }
+
+func Test_ErrorCodeStatus(t *testing.T) {
+	assert.EqualValues(t, 404, ErrPageNotFound.Status())
+	assert.EqualValues(t, 404, ErrPageMissingDescription.Status())
+	assert.EqualValues(t, 502, ErrUpstreamUnavailable.Status())
+	assert.EqualValues(t, 503, ErrUpstreamRateLimited.Status())
+	assert.EqualValues(t, 400, ErrInvalidTitle.Status())
+	assert.EqualValues(t, 400, ErrInvalidLocale.Status())
+	assert.EqualValues(t, 422, ErrAmbiguous.Status())
+	assert.EqualValues(t, 500, ErrorCode("unknown").Status())
+}
+
+func Test_MediaWikiErrorToWikiError(t *testing.T) {
+	var result Extract
+	err := json.Unmarshal([]byte(`{"error":{"code":"invalidtitle","info":"Bad title \"\"."},"warnings":{"main":{"warnings":"Unrecognized parameter: foo."}}}`), &result)
+	assert.Nil(t, err)
+	assert.NotNil(t, result.Error)
+	assert.EqualValues(t, []string{"main: Unrecognized parameter: foo."}, result.Warnings.Messages())
+
+	wikiError := result.Error.ToWikiError()
+	assert.EqualValues(t, 400, wikiError.Code)
+	assert.EqualValues(t, ErrInvalidTitle, wikiError.Reason())
+
+	unknown := (&MediaWikiError{Code: "somethingnew", Info: "?"}).ToWikiError()
+	assert.EqualValues(t, ErrUpstreamUnavailable, unknown.ErrorCode)
+}
+
+func Test_NewProblemDetails(t *testing.T) {
+	wikiError := NewTypedError(ErrUpstreamRateLimited, "ratelimited: slow down").WithUpstream("en.wikipedia.org")
+	wikiError.RetryAfter = 30
+
+	problem := NewProblemDetails(wikiError, "/search/Yoshua_Bengio", "abc123")
+	assert.EqualValues(t, "/problems/upstream_rate_limited", problem.Type)
+	assert.EqualValues(t, "Wikipedia is rate limiting requests", problem.Title)
+	assert.EqualValues(t, 503, problem.Status)
+	assert.EqualValues(t, "ratelimited: slow down", problem.Detail)
+	assert.EqualValues(t, "/search/Yoshua_Bengio", problem.Instance)
+	assert.EqualValues(t, "abc123", problem.RequestID)
+	assert.EqualValues(t, "en.wikipedia.org", problem.Upstream)
+	assert.EqualValues(t, 30, problem.RetryAfter)
+
+	untyped := NewProblemDetails(NewForbiddenError("nope"), "/search/x", "")
+	assert.EqualValues(t, "about:blank", untyped.Type)
+	assert.EqualValues(t, "Forbidden", untyped.Title)
+}
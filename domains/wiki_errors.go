@@ -5,13 +5,80 @@ import (
 	"net/http"
 )
 
+// ErrorCode is the machine-readable reason sent alongside the HTTP status
+type ErrorCode string
+
+const (
+	ErrPageNotFound           ErrorCode = "page_not_found"
+	ErrPageMissingDescription ErrorCode = "page_missing_description"
+	ErrUpstreamUnavailable    ErrorCode = "upstream_unavailable"
+	ErrUpstreamRateLimited    ErrorCode = "upstream_rate_limited"
+	ErrInvalidTitle           ErrorCode = "invalid_title"
+	ErrInvalidLocale          ErrorCode = "invalid_locale"
+	ErrInvalidRequest         ErrorCode = "invalid_request"
+	ErrNotFound               ErrorCode = "not_found"
+	ErrConflict               ErrorCode = "conflict"
+	ErrAmbiguous              ErrorCode = "ambiguous"
+	ErrNotAPerson             ErrorCode = "not_a_person"
+	ErrTooManySubscribers     ErrorCode = "too_many_subscribers"
+	ErrInvalidAPIKey          ErrorCode = "invalid_api_key"
+	ErrRateLimited            ErrorCode = "rate_limited"
+	ErrQuotaExceeded          ErrorCode = "quota_exceeded"
+	ErrNotEnabled             ErrorCode = "not_enabled"
+	ErrInvalidToken           ErrorCode = "invalid_token"
+	ErrInsufficientScope      ErrorCode = "insufficient_scope"
+	ErrNotAcceptable          ErrorCode = "not_acceptable"
+	ErrInternal               ErrorCode = "internal_error"
+	ErrUnavailable            ErrorCode = "unavailable"
+)
+
+var errorCodeStatus = map[ErrorCode]int{
+	ErrPageNotFound:           http.StatusNotFound,
+	ErrPageMissingDescription: http.StatusNotFound,
+	ErrUpstreamUnavailable:    http.StatusBadGateway,
+	ErrUpstreamRateLimited:    http.StatusServiceUnavailable,
+	ErrInvalidTitle:           http.StatusBadRequest,
+	ErrInvalidLocale:          http.StatusBadRequest,
+	ErrInvalidRequest:         http.StatusBadRequest,
+	ErrNotFound:               http.StatusNotFound,
+	ErrConflict:               http.StatusConflict,
+	ErrAmbiguous:              http.StatusUnprocessableEntity,
+	ErrNotAPerson:             http.StatusUnprocessableEntity,
+	ErrTooManySubscribers:     http.StatusServiceUnavailable,
+	ErrInvalidAPIKey:          http.StatusUnauthorized,
+	ErrRateLimited:            http.StatusTooManyRequests,
+	ErrQuotaExceeded:          http.StatusTooManyRequests,
+	ErrNotEnabled:             http.StatusForbidden,
+	ErrInvalidToken:           http.StatusUnauthorized,
+	ErrInsufficientScope:      http.StatusForbidden,
+	ErrNotAcceptable:          http.StatusNotAcceptable,
+	ErrInternal:               http.StatusInternalServerError,
+	ErrUnavailable:            http.StatusServiceUnavailable,
+}
+
+// Status returns the HTTP status code an error of this type is reported with
+func (c ErrorCode) Status() int {
+	if status, ok := errorCodeStatus[c]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
 type WikiErrorInterface interface {
 	Status() int
 	Message() string
+	Reason() ErrorCode
+	Upstream() string
+	RetryAfterSeconds() int
 }
 type WikiError struct {
-	Code      int           `json:"code"`
-	ErrorMessage     string  `json:"error"`
+	Code         int       `json:"code"`
+	ErrorMessage string    `json:"error"`
+	ErrorCode    ErrorCode `json:"reason,omitempty"`
+	// RetryAfter is the number of seconds the upstream asked us to wait, when it told us
+	RetryAfter int `json:"retry_after,omitempty"`
+	// UpstreamWiki is the host of the wiki that caused the error, if any
+	UpstreamWiki string `json:"upstream,omitempty"`
 }
 
 func (w *WikiError) Status() int {
@@ -20,6 +87,21 @@ func (w *WikiError) Status() int {
 func (w *WikiError) Message() string {
 	return w.ErrorMessage
 }
+func (w *WikiError) Reason() ErrorCode {
+	return w.ErrorCode
+}
+func (w *WikiError) Upstream() string {
+	return w.UpstreamWiki
+}
+func (w *WikiError) RetryAfterSeconds() int {
+	return w.RetryAfter
+}
+
+// WithUpstream records which wiki the error came from and returns the same error
+func (w *WikiError) WithUpstream(host string) *WikiError {
+	w.UpstreamWiki = host
+	return w
+}
 
 func NewWikiError(statusCode int, message string) WikiErrorInterface {
 	return &WikiError{
@@ -27,16 +109,26 @@ func NewWikiError(statusCode int, message string) WikiErrorInterface {
 		ErrorMessage: message,
 	}
 }
+
+// NewTypedError builds an error for one of the ErrorCode values, with the matching HTTP status
+func NewTypedError(code ErrorCode, message string) *WikiError {
+	return &WikiError{
+		Code:         code.Status(),
+		ErrorMessage: message,
+		ErrorCode:    code,
+	}
+}
+
 func NewBadRequestError(message string) WikiErrorInterface {
 	return &WikiError{
-		Code: http.StatusBadRequest,
+		Code:         http.StatusBadRequest,
 		ErrorMessage: message,
 	}
 }
 
 func NewForbiddenError(message string) WikiErrorInterface {
 	return &WikiError{
-		Code: http.StatusForbidden,
+		Code:         http.StatusForbidden,
 		ErrorMessage: message,
 	}
 }
@@ -47,4 +139,31 @@ func NewApiErrFromBytes(body []byte) (WikiErrorInterface, error) {
 		return nil, err
 	}
 	return &result, nil
-}
\ No newline at end of file
+}
+
+// MediaWikiError is the `error` object the action API returns instead of a query result
+type MediaWikiError struct {
+	Code string `json:"code"`
+	Info string `json:"info"`
+}
+
+// mediaWikiErrorCodes maps the action API error codes we know about onto our own taxonomy
+var mediaWikiErrorCodes = map[string]ErrorCode{
+	"invalidtitle":                    ErrInvalidTitle,
+	"missingtitle":                    ErrPageNotFound,
+	"nosuchpageid":                    ErrPageNotFound,
+	"nosuchrevid":                     ErrPageNotFound,
+	"ratelimited":                     ErrUpstreamRateLimited,
+	"maxlag":                          ErrUpstreamRateLimited,
+	"readonly":                        ErrUpstreamUnavailable,
+	"internal_api_error_DBQueryError": ErrUpstreamUnavailable,
+}
+
+// ToWikiError converts the MediaWiki error into a typed error, unknown codes are reported as upstream_unavailable
+func (e *MediaWikiError) ToWikiError() *WikiError {
+	code, ok := mediaWikiErrorCodes[e.Code]
+	if !ok {
+		code = ErrUpstreamUnavailable
+	}
+	return NewTypedError(code, e.Code+": "+e.Info)
+}
@@ -0,0 +1,8 @@
+package wiki_domain
+
+// GraphQLRequest is the body of a /graphql request, or its query string for a GET
+type GraphQLRequest struct {
+	Query         string                 `json:"query" form:"query" binding:"required"`
+	OperationName string                 `json:"operationName" form:"operationName"`
+	Variables     map[string]interface{} `json:"variables" form:"-"`
+}
@@ -0,0 +1,17 @@
+package wiki_domain
+
+// DescriptionChange is a revision where the page's short description changed, an empty description means it was removed
+type DescriptionChange struct {
+	ShortDescription string        `json:"short_description"`
+	Revision         *RevisionInfo `json:"revision"`
+}
+
+// History is the timeline of a page's short description, oldest change first
+type History struct {
+	Title    string              `json:"title"`
+	Language string              `json:"language"`
+	Changes  []DescriptionChange `json:"changes"`
+	// Revisions is how many revisions were read, Complete is false when the limit stopped us before the page's first one
+	Revisions int  `json:"revisions"`
+	Complete  bool `json:"complete"`
+}
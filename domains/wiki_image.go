@@ -0,0 +1,68 @@
+package wiki_domain
+
+// Image is the page's lead image, with the license details needed to show it
+type Image struct {
+	File           string `json:"file"`
+	Thumbnail      string `json:"thumbnail"`
+	Width          int    `json:"width"`
+	Height         int    `json:"height"`
+	Original       string `json:"original,omitempty"`
+	OriginalWidth  int    `json:"original_width,omitempty"`
+	OriginalHeight int    `json:"original_height,omitempty"`
+	License        string `json:"license,omitempty"`
+	LicenseUrl     string `json:"license_url,omitempty"`
+	Artist         string `json:"artist,omitempty"`
+	Attribution    string `json:"attribution,omitempty"`
+}
+
+type ImageSource struct {
+	Source string `json:"source"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// PageImages is the prop=pageimages response
+type PageImages struct {
+	Query struct {
+		Pages []struct {
+			Title     string       `json:"title"`
+			Missing   bool         `json:"missing,omitempty"`
+			Pageimage string       `json:"pageimage,omitempty"`
+			Thumbnail *ImageSource `json:"thumbnail,omitempty"`
+			Original  *ImageSource `json:"original,omitempty"`
+		} `json:"pages"`
+	} `json:"query"`
+}
+
+// ImageInfo is the Commons prop=imageinfo response with the file's extmetadata
+type ImageInfo struct {
+	Query struct {
+		Normalized []Normalize     `json:"normalized"`
+		Pages      []ImageInfoPage `json:"pages"`
+	} `json:"query"`
+}
+
+type ImageInfoPage struct {
+	Title     string `json:"title"`
+	Missing   bool   `json:"missing,omitempty"`
+	Imageinfo []struct {
+		Extmetadata map[string]struct {
+			Value  string `json:"value"`
+			Source string `json:"source"`
+		} `json:"extmetadata"`
+	} `json:"imageinfo"`
+}
+
+func (i *ImageInfo) Metadata(field string) string {
+	if len(i.Query.Pages) == 0 {
+		return ""
+	}
+	return i.Query.Pages[0].Metadata(field)
+}
+
+func (p *ImageInfoPage) Metadata(field string) string {
+	if len(p.Imageinfo) == 0 {
+		return ""
+	}
+	return p.Imageinfo[0].Extmetadata[field].Value
+}
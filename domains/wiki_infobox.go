@@ -0,0 +1,7 @@
+package wiki_domain
+
+// Infobox is the first infobox of a page, Type is what follows "Infobox" (person, scientist...)
+type Infobox struct {
+	Type   string            `json:"type"`
+	Fields map[string]string `json:"fields"`
+}
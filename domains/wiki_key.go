@@ -0,0 +1,53 @@
+package wiki_domain
+
+import "time"
+
+// APIKey is an issued key. The key itself is never stored, only its SHA-256 Hash, and Prefix is
+// its first characters so people can tell their keys apart. After a rotation the previous key
+// keeps working until PreviousExpiresAt.
+type APIKey struct {
+	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
+	Tenant string `json:"tenant,omitempty"`
+	Prefix string `json:"prefix"`
+	Hash   string `json:"hash,omitempty"`
+	// RateLimit is how many requests a minute the key may make, DailyQuota how many a UTC day, 0 is unlimited
+	RateLimit         int        `json:"rate_limit"`
+	DailyQuota        int        `json:"daily_quota"`
+	PreviousHash      string     `json:"previous_hash,omitempty"`
+	PreviousExpiresAt *time.Time `json:"previous_expires_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	RotatedAt         *time.Time `json:"rotated_at,omitempty"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IssuedKey is an APIKey with the key itself, only returned when it is issued or rotated
+type IssuedKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyRequest issues a key, a rate limit or quota left out takes the defaults
+type APIKeyRequest struct {
+	Name       string `json:"name"`
+	Tenant     string `json:"tenant"`
+	RateLimit  *int   `json:"rate_limit" binding:"omitempty,min=0"`
+	DailyQuota *int   `json:"daily_quota" binding:"omitempty,min=0"`
+}
+
+// RotateRequest is how long the key being replaced keeps working, "0s" or empty retires it straight away
+type RotateRequest struct {
+	Grace string `json:"grace"`
+}
+
+// KeyUsage is a key's requests per UTC day, Rejected is the ones turned away by its limits
+type KeyUsage struct {
+	ID   string     `json:"id"`
+	Days []DayUsage `json:"days"`
+}
+
+type DayUsage struct {
+	Date     string `json:"date"`
+	Requests int64  `json:"requests"`
+	Rejected int64  `json:"rejected"`
+}
@@ -0,0 +1,56 @@
+package wiki_domain
+
+// LangLinks is the prop=langlinks response, the page's title in the other Wikipedia editions
+type LangLinks struct {
+	Warnings WarningsType    `json:"warnings"`
+	Error    *MediaWikiError `json:"error,omitempty"`
+	Query    struct {
+		Pages []PageLangLinks `json:"pages"`
+	} `json:"query"`
+}
+
+type PageLangLinks struct {
+	Pageid        int        `json:"pageid"`
+	Ns            int        `json:"ns"`
+	Title         string     `json:"title"`
+	Missing       bool       `json:"missing,omitempty"`
+	Invalid       bool       `json:"invalid,omitempty"`
+	InvalidReason string     `json:"invalidreason,omitempty"`
+	Langlinks     []LangLink `json:"langlinks,omitempty"`
+}
+
+type LangLink struct {
+	Lang  string `json:"lang"`
+	Title string `json:"title"`
+}
+
+// Translation is the page's extract in one language, under that edition's own title
+type Translation struct {
+	Title string `json:"title"`
+	*Response
+}
+
+// Translations answers /search/:name?langs=..., keyed by the language each description is in
+type Translations struct {
+	Title     string                  `json:"title"`
+	Language  string                  `json:"language"`
+	Languages map[string]*Translation `json:"languages"`
+	// Missing lists the requested languages with no article about the page
+	Missing []string `json:"missing"`
+	// Errors holds the languages whose article exists but could not be fetched
+	Errors map[string]*WikiError `json:"errors,omitempty"`
+}
+
+// PageRedirects is the prop=redirects response, the titles redirecting to the page
+type PageRedirects struct {
+	Query struct {
+		Pages []struct {
+			Title     string `json:"title"`
+			Redirects []struct {
+				Pageid int    `json:"pageid"`
+				Ns     int    `json:"ns"`
+				Title  string `json:"title"`
+			} `json:"redirects,omitempty"`
+		} `json:"pages"`
+	} `json:"query"`
+}
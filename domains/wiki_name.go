@@ -0,0 +1,12 @@
+package wiki_domain
+
+// PersonName is a page title split into the parts of a personal name
+type PersonName struct {
+	Given         []string `json:"given"`
+	Particles     []string `json:"particles"`
+	Family        []string `json:"family"`
+	Suffixes      []string `json:"suffixes"`
+	Disambiguator string   `json:"disambiguator,omitempty"`
+	// Source is "wikidata" when the given and family names came from P735/P734, "rules" otherwise
+	Source string `json:"source"`
+}
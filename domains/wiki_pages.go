@@ -0,0 +1,36 @@
+package wiki_domain
+
+// Page is one title of a batched lookup, under its canonical title, with the facets that were asked for
+type Page struct {
+	Title string `json:"title"`
+	*Response
+}
+
+// PagesQuery is the action=query response of a batched lookup, every prop the facets need of up to 50 titles
+type PagesQuery struct {
+	Warnings WarningsType    `json:"warnings"`
+	Error    *MediaWikiError `json:"error,omitempty"`
+	Query    struct {
+		Normalized []Normalize `json:"normalized"`
+		// Redirects are followed, From is the title asked for and To the page it points at
+		Redirects []Normalize `json:"redirects"`
+		Pages     []struct {
+			Pageid     int               `json:"pageid"`
+			Ns         int               `json:"ns"`
+			Title      string            `json:"title"`
+			Missing    bool              `json:"missing,omitempty"`
+			Invalid    bool              `json:"invalid,omitempty"`
+			Pageprops  PageProps         `json:"pageprops"`
+			Extract    string            `json:"extract,omitempty"`
+			Pageimage  string            `json:"pageimage,omitempty"`
+			Thumbnail  *ImageSource      `json:"thumbnail,omitempty"`
+			Original   *ImageSource      `json:"original,omitempty"`
+			Revisions  []ContentRevision `json:"revisions,omitempty"`
+			Categories []PageCategory    `json:"categories,omitempty"`
+			Langlinks  []LangLink        `json:"langlinks,omitempty"`
+			Redirects  []struct {
+				Title string `json:"title"`
+			} `json:"redirects,omitempty"`
+		} `json:"pages"`
+	} `json:"query"`
+}
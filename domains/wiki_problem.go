@@ -0,0 +1,62 @@
+package wiki_domain
+
+import "net/http"
+
+const ProblemContentType = "application/problem+json"
+
+var errorCodeTitle = map[ErrorCode]string{
+	ErrPageNotFound:           "Page not found",
+	ErrPageMissingDescription: "Page has no short description",
+	ErrUpstreamUnavailable:    "Wikipedia is unavailable",
+	ErrUpstreamRateLimited:    "Wikipedia is rate limiting requests",
+	ErrInvalidTitle:           "Invalid page title",
+	ErrInvalidLocale:          "Invalid locale",
+	ErrInvalidRequest:         "Invalid request",
+	ErrNotFound:               "Not found",
+	ErrConflict:               "Conflict",
+	ErrAmbiguous:              "Ambiguous page title",
+	ErrNotAPerson:             "Page is not about a person",
+	ErrTooManySubscribers:     "Too many watchers",
+	ErrInvalidAPIKey:          "Invalid API key",
+	ErrRateLimited:            "Too many requests",
+	ErrQuotaExceeded:          "Daily quota exceeded",
+	ErrNotEnabled:             "Not enabled for this tenant",
+	ErrInvalidToken:           "Invalid bearer token",
+	ErrInsufficientScope:      "Insufficient scope",
+	ErrNotAcceptable:          "Format not supported",
+	ErrInternal:               "Internal error",
+	ErrUnavailable:            "Service unavailable",
+}
+
+// ProblemDetails is the RFC 7807 body every error is rendered as, with our extension members
+type ProblemDetails struct {
+	Type       string    `json:"type"`
+	Title      string    `json:"title"`
+	Status     int       `json:"status"`
+	Detail     string    `json:"detail,omitempty"`
+	Instance   string    `json:"instance,omitempty"`
+	Reason     ErrorCode `json:"reason,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Upstream   string    `json:"upstream,omitempty"`
+	RetryAfter int       `json:"retry_after,omitempty"`
+}
+
+// NewProblemDetails describes err for the request at instance, typed errors get a /problems/<reason> type
+func NewProblemDetails(err WikiErrorInterface, instance string, requestID string) *ProblemDetails {
+	problem := &ProblemDetails{
+		Type:       "about:blank",
+		Title:      http.StatusText(err.Status()),
+		Status:     err.Status(),
+		Detail:     err.Message(),
+		Instance:   instance,
+		Reason:     err.Reason(),
+		RequestID:  requestID,
+		Upstream:   err.Upstream(),
+		RetryAfter: err.RetryAfterSeconds(),
+	}
+	if title, ok := errorCodeTitle[err.Reason()]; ok {
+		problem.Type = "/problems/" + string(err.Reason())
+		problem.Title = title
+	}
+	return problem
+}
@@ -0,0 +1,30 @@
+package wiki_domain
+
+// SuggestQuery asks for the pages whose title starts with Prefix
+type SuggestQuery struct {
+	Prefix string
+	Locale string
+	// Limit is how many suggestions to return, 10 by default
+	Limit int
+}
+
+type Suggestion struct {
+	Title            string `json:"title"`
+	ShortDescription string `json:"short_description,omitempty"`
+}
+
+type Suggestions struct {
+	Language    string       `json:"language"`
+	Suggestions []Suggestion `json:"suggestions"`
+}
+
+// PrefixSearch is the generator=prefixsearch response, Index is the page's rank in the search
+type PrefixSearch struct {
+	Query struct {
+		Pages []struct {
+			Index     int       `json:"index"`
+			Title     string    `json:"title"`
+			Pageprops PageProps `json:"pageprops"`
+		} `json:"pages"`
+	} `json:"query"`
+}
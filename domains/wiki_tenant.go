@@ -0,0 +1,66 @@
+package wiki_domain
+
+import "context"
+
+// Tenant is a customer's settings, resolved from the tenant of its API key. The zero value of
+// every setting keeps the server's default, and so does a nil *Tenant.
+type Tenant struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+	// CacheTTL is how many seconds the tenant's responses are cached for, -1 turns caching off
+	CacheTTL int `json:"cache_ttl,omitempty"`
+	// AllowedLocales are the editions the tenant may look names up in
+	AllowedLocales []string `json:"allowed_locales,omitempty"`
+	// DefaultLocale is the edition looked up when a request names none
+	DefaultLocale string `json:"default_locale,omitempty"`
+	// Facets are the ones the tenant may ask for with ?include=
+	Facets []string `json:"facets,omitempty"`
+	// MaxBatchSize caps the names of one batch, it can't raise the server's own cap
+	MaxBatchSize int `json:"max_batch_size,omitempty"`
+}
+
+type tenantKey struct{}
+
+// WithTenant is ctx carrying the tenant, for the handlers and providers serving its request
+func WithTenant(ctx context.Context, tenant *Tenant) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom is the tenant of the request, nil when it has none
+func TenantFrom(ctx context.Context) *Tenant {
+	tenant, _ := ctx.Value(tenantKey{}).(*Tenant)
+	return tenant
+}
+
+// LocaleOr is the tenant's default locale, or fallback when it has none
+func (t *Tenant) LocaleOr(fallback string) string {
+	if t == nil || t.DefaultLocale == "" {
+		return fallback
+	}
+	return t.DefaultLocale
+}
+
+func (t *Tenant) AllowsLocale(code string) bool {
+	return t == nil || len(t.AllowedLocales) == 0 || contains(t.AllowedLocales, code)
+}
+
+func (t *Tenant) AllowsFacet(name string) bool {
+	return t == nil || len(t.Facets) == 0 || contains(t.Facets, name)
+}
+
+// BatchSize is the most names the tenant can send at once, max being the server's cap
+func (t *Tenant) BatchSize(max int) int {
+	if t == nil || t.MaxBatchSize <= 0 || t.MaxBatchSize > max {
+		return max
+	}
+	return t.MaxBatchSize
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
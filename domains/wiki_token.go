@@ -0,0 +1,23 @@
+package wiki_domain
+
+import "time"
+
+// The scopes a bearer token can grant, each route group needs one of them
+const (
+	ScopeLookupRead   = "lookup:read"
+	ScopeBatchWrite   = "batch:write"
+	ScopeWebhookWrite = "webhook:write"
+	ScopeAdmin        = "admin"
+)
+
+// Claims are what we use of a validated bearer token: who it is for, their tenant and scopes
+type Claims struct {
+	Subject   string    `json:"sub"`
+	Tenant    string    `json:"tenant,omitempty"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"exp"`
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	return c != nil && contains(c.Scopes, scope)
+}
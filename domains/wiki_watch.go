@@ -0,0 +1,20 @@
+package wiki_domain
+
+import "time"
+
+// WatchQuery is the query string of /watch, Names is a comma separated list of titles
+type WatchQuery struct {
+	Names  string `form:"names" binding:"required"`
+	Locale string `form:"locale"`
+}
+
+// WatchEvent is pushed to watchers when a name's short description changes. The description
+// already known when subscribing, or seen for the first time, is sent too with Initial set.
+type WatchEvent struct {
+	Name             string    `json:"name"`
+	Language         string    `json:"language"`
+	ShortDescription string    `json:"short_description"`
+	Previous         string    `json:"previous,omitempty"`
+	Initial          bool      `json:"initial,omitempty"`
+	ChangedAt        time.Time `json:"changed_at"`
+}
@@ -0,0 +1,67 @@
+package wiki_domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Delivery statuses, a dead delivery ran out of attempts and waits in the dead-letter list
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// WebhookRequest registers a callback URL for the names given and the members of the categories given
+type WebhookRequest struct {
+	URL        string   `json:"url" binding:"required,url"`
+	Locale     string   `json:"locale"`
+	Names      []string `json:"names" binding:"max=500"`
+	Categories []string `json:"categories" binding:"max=10"`
+	// Secret signs the payloads, one is generated when it is left out
+	Secret string `json:"secret" binding:"omitempty,min=16"`
+}
+
+// Webhook is a registered callback. Secret is only shown when it is created, Seen is the last
+// description delivered or seen for each name, so changes made while we were down are still sent.
+type Webhook struct {
+	ID         string            `json:"id"`
+	Tenant     string            `json:"tenant,omitempty"`
+	URL        string            `json:"url"`
+	Locale     string            `json:"locale"`
+	Names      []string          `json:"names"`
+	Categories []string          `json:"categories"`
+	Secret     string            `json:"secret,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Seen       map[string]string `json:"seen,omitempty"`
+}
+
+// WebhookPayload is the body POSTed to a callback URL
+type WebhookPayload struct {
+	ID      string     `json:"id"`
+	Event   string     `json:"event"`
+	Webhook string     `json:"webhook"`
+	Data    WatchEvent `json:"data"`
+}
+
+// Delivery is one payload sent to a webhook, and how sending it went so far
+type Delivery struct {
+	ID          string          `json:"id"`
+	Webhook     string          `json:"webhook"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt,omitempty"`
+	// ResponseStatus is the status code of the last attempt, LastError why it failed
+	ResponseStatus int       `json:"response_status,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// DeliveryQuery filters the delivery log
+type DeliveryQuery struct {
+	Webhook string `form:"webhook"`
+	Status  string `form:"status" binding:"omitempty,oneof=pending delivered dead"`
+	Limit   int    `form:"limit" binding:"min=0,max=1000"`
+}
@@ -0,0 +1,53 @@
+package wiki_domain
+
+// WikidataClaims is the wbgetclaims response, only the entity ids of item valued claims are decoded
+type WikidataClaims struct {
+	Claims map[string][]WikidataClaim `json:"claims"`
+	Error  *MediaWikiError            `json:"error,omitempty"`
+}
+
+type WikidataClaim struct {
+	Mainsnak struct {
+		Snaktype  string `json:"snaktype"`
+		Datavalue struct {
+			Value struct {
+				ID string `json:"id"`
+			} `json:"value"`
+		} `json:"datavalue"`
+	} `json:"mainsnak"`
+}
+
+// ItemIDs returns the ids of the items a property points to, e.g. Q5 for P31 (instance of) human
+func (w *WikidataClaims) ItemIDs(property string) []string {
+	var ids []string
+	for _, claim := range w.Claims[property] {
+		if claim.Mainsnak.Snaktype == "value" && claim.Mainsnak.Datavalue.Value.ID != "" {
+			ids = append(ids, claim.Mainsnak.Datavalue.Value.ID)
+		}
+	}
+	return ids
+}
+
+// WikidataEntities is the wbgetentities response when asking for labels only
+type WikidataEntities struct {
+	Entities map[string]struct {
+		Labels map[string]struct {
+			Value string `json:"value"`
+		} `json:"labels"`
+	} `json:"entities"`
+	Error *MediaWikiError `json:"error,omitempty"`
+}
+
+// Label returns the item's label in the first of the languages it has one for
+func (w *WikidataEntities) Label(id string, languages ...string) string {
+	entity, ok := w.Entities[id]
+	if !ok {
+		return ""
+	}
+	for _, language := range languages {
+		if label, ok := entity.Labels[language]; ok {
+			return label.Value
+		}
+	}
+	return ""
+}
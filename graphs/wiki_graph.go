@@ -0,0 +1,162 @@
+package wiki_graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// MaxComplexity and MaxDepth bound what one query may ask for, see complexity for how it is counted
+var (
+	MaxComplexity = 5000
+	MaxDepth      = 8
+)
+
+// fieldCosts are the Person fields that cost more than the default of 1, because they need more upstream calls
+var fieldCosts = map[string]int{
+	"image":   2,
+	"extract": 2,
+}
+
+// Execute parses, validates and runs the query. Every name the query looks up goes through one
+// PageLoader, so they are fetched together. It returns false when the query was not run at all
+// (it doesn't parse, is invalid or too complex), which is a bad request rather than a partial result.
+func Execute(ctx context.Context, request wiki_domain.GraphQLRequest) (*graphql.Result, bool) {
+	document, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(request.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, false
+	}
+	if validation := graphql.ValidateDocument(&Schema, document, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}, false
+	}
+	cost, depth, err := complexity(document, request.OperationName, request.Variables)
+	if err == nil && depth > MaxDepth {
+		err = fmt.Errorf("query depth %d exceeds the maximum of %d", depth, MaxDepth)
+	}
+	if err == nil && cost > MaxComplexity {
+		err = fmt.Errorf("query complexity %d exceeds the maximum of %d", cost, MaxComplexity)
+	}
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}, false
+	}
+
+	loader := NewPageLoader(ctx)
+	loader.Tenant = wiki_domain.TenantFrom(ctx)
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        Schema,
+		AST:           document,
+		OperationName: request.OperationName,
+		Args:          request.Variables,
+		Context:       context.WithValue(ctx, loaderKey{}, loader),
+	})
+	for i := range result.Errors {
+		if result.Errors[i].Extensions == nil {
+			result.Errors[i].Extensions = extensionsOf(result.Errors[i].OriginalError())
+		}
+	}
+	return result, true
+}
+
+// extensionsOf digs the extensions out of an error. graphql-go only keeps them for errors returned
+// by a resolver itself, the ones returned by its thunks come back wrapped a couple of times.
+func extensionsOf(err error) map[string]interface{} {
+	for err != nil {
+		switch e := err.(type) {
+		case gqlerrors.ExtendedError:
+			return e.Extensions()
+		case gqlerrors.FormattedError:
+			err = e.OriginalError()
+		case *gqlerrors.Error:
+			err = e.OriginalError
+		default:
+			return nil
+		}
+	}
+	return nil
+}
+
+// complexity counts 1 per field (more for the fields in fieldCosts), multiplied by the number of
+// names under people, and the deepest nesting of the operation. Introspection is free.
+func complexity(document *ast.Document, operationName string, variables map[string]interface{}) (int, int, error) {
+	fragments := map[string]*ast.FragmentDefinition{}
+	var operation *ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		switch definition := definition.(type) {
+		case *ast.FragmentDefinition:
+			fragments[definition.Name.Value] = definition
+		case *ast.OperationDefinition:
+			if operationName == "" || (definition.Name != nil && definition.Name.Value == operationName) {
+				operation = definition
+			}
+		}
+	}
+	if operation == nil {
+		return 0, 0, fmt.Errorf("unknown operation %q", operationName)
+	}
+
+	var walk func(set *ast.SelectionSet, depth int) (int, int)
+	walk = func(set *ast.SelectionSet, depth int) (int, int) {
+		if set == nil {
+			return 0, depth
+		}
+		cost, deepest := 0, depth
+		for _, selection := range set.Selections {
+			var childCost, childDepth int
+			switch selection := selection.(type) {
+			case *ast.Field:
+				name := selection.Name.Value
+				if strings.HasPrefix(name, "__") {
+					continue
+				}
+				childCost, childDepth = walk(selection.SelectionSet, depth+1)
+				if name == "people" {
+					childCost *= listLength(selection.Arguments, "names", variables)
+				}
+				fieldCost, ok := fieldCosts[name]
+				if !ok {
+					fieldCost = 1
+				}
+				childCost += fieldCost
+			case *ast.InlineFragment:
+				childCost, childDepth = walk(selection.SelectionSet, depth)
+			case *ast.FragmentSpread:
+				if fragment, ok := fragments[selection.Name.Value]; ok {
+					childCost, childDepth = walk(fragment.SelectionSet, depth)
+				}
+			}
+			cost += childCost
+			if childDepth > deepest {
+				deepest = childDepth
+			}
+		}
+		return cost, deepest
+	}
+	cost, depth := walk(operation.SelectionSet, 0)
+	return cost, depth, nil
+}
+
+// listLength is the length of a list argument, written inline or passed as a variable
+func listLength(arguments []*ast.Argument, name string, variables map[string]interface{}) int {
+	for _, argument := range arguments {
+		if argument.Name.Value != name {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.ListValue:
+			return len(value.Values)
+		case *ast.Variable:
+			if list, ok := variables[value.Name.Value].([]interface{}); ok {
+				return len(list)
+			}
+		}
+	}
+	return 1
+}
@@ -0,0 +1,202 @@
+package wiki_graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_provider "wiki-names/providers"
+)
+
+type pagesCall struct {
+	locale  string
+	titles  []string
+	include string
+}
+
+type providerMock struct {
+	wiki_provider.WikiProviderStruct
+	mu    sync.Mutex
+	calls []pagesCall
+}
+
+// We are mocking the provider method "GetPages", en has Yoshua Bengio and Geoffrey Hinton, fr only Yoshua Bengio
+func (pm *providerMock) GetPages(ctx context.Context, locale string, titles []string, include string) (map[string]*wiki_domain.Page, *wiki_domain.WikiError) {
+	pm.mu.Lock()
+	pm.calls = append(pm.calls, pagesCall{locale, titles, include})
+	pm.mu.Unlock()
+	pages := map[string]*wiki_domain.Page{}
+	for _, title := range titles {
+		switch locale + ":" + strings.ReplaceAll(title, "_", " ") {
+		case "en:Yoshua Bengio":
+			pages[title] = &wiki_domain.Page{Title: "Yoshua Bengio", Response: &wiki_domain.Response{
+				ShortDescription: "Canadian computer scientist",
+				Language:         "en",
+				Extract:          "Yoshua Bengio is a Canadian computer scientist. He won the Turing Award.",
+				Image:            &wiki_domain.Image{File: "Yoshua_Bengio_2017.jpg", Thumbnail: "https://upload.wikimedia.org/320px-Yoshua_Bengio_2017.jpg", License: "CC BY-SA 4.0"},
+				Infobox:          &wiki_domain.Infobox{Type: "scientist", Fields: map[string]string{"birth_date": "March 5, 1964"}},
+				Langlinks:        map[string]string{"fr": "Yoshua Bengio"},
+			}}
+		case "en:Geoffrey Hinton":
+			pages[title] = &wiki_domain.Page{Title: "Geoffrey Hinton", Response: &wiki_domain.Response{ShortDescription: "British-Canadian computer scientist", Language: "en", Langlinks: map[string]string{}}}
+		case "fr:Yoshua Bengio":
+			pages[title] = &wiki_domain.Page{Title: "Yoshua Bengio", Response: &wiki_domain.Response{Language: "fr", Extract: "Yoshua Bengio est un informaticien canadien. Il est professeur."}}
+		}
+	}
+	return pages, nil
+}
+
+func setupProvider(t *testing.T) *providerMock {
+	mock := &providerMock{}
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = mock
+	t.Cleanup(func() { wiki_provider.WikiProvider = original })
+	return mock
+}
+
+func execute(t *testing.T, query string, variables map[string]interface{}) (map[string]interface{}, bool) {
+	result, executed := Execute(context.Background(), wiki_domain.GraphQLRequest{Query: query, Variables: variables})
+	bytes, err := json.Marshal(result)
+	assert.Nil(t, err)
+	var decoded map[string]interface{}
+	assert.Nil(t, json.Unmarshal(bytes, &decoded))
+	return decoded, executed
+}
+
+func TestPerson(t *testing.T) {
+	mock := setupProvider(t)
+
+	result, executed := execute(t, `{
+		person(name: "Yoshua_Bengio") {
+			title
+			shortDescription
+			image { thumbnail license }
+			infobox { birthDate: field(name: "birth_date") }
+			extract(sentences: 1)
+			frenchExtract: extract(locale: "fr", sentences: 1)
+		}
+	}`, nil)
+	assert.True(t, executed)
+	assert.Nil(t, result["errors"])
+	person := result["data"].(map[string]interface{})["person"].(map[string]interface{})
+	assert.EqualValues(t, "Canadian computer scientist", person["shortDescription"])
+	assert.EqualValues(t, "CC BY-SA 4.0", person["image"].(map[string]interface{})["license"])
+	assert.EqualValues(t, "March 5, 1964", person["infobox"].(map[string]interface{})["birthDate"])
+	assert.EqualValues(t, "Yoshua Bengio is a Canadian computer scientist.", person["extract"])
+	assert.EqualValues(t, "Yoshua Bengio est un informaticien canadien.", person["frenchExtract"])
+	// One call for the page with every facet, one for the French extract
+	assert.EqualValues(t, []pagesCall{
+		{"en", []string{"Yoshua_Bengio"}, "extract,image,infobox,langlinks"},
+		{"fr", []string{"Yoshua Bengio"}, "extract"},
+	}, mock.calls)
+}
+
+func TestPeopleBatchesNames(t *testing.T) {
+	mock := setupProvider(t)
+
+	result, executed := execute(t, `query People($names: [String!]!) {
+		people(names: $names) { ...card }
+	}
+	fragment card on Person { title shortDescription extract(locale: "fr") }`,
+		map[string]interface{}{"names": []interface{}{"Yoshua Bengio", "Geoffrey Hinton", "Nobody At All"}})
+	assert.True(t, executed)
+	people := result["data"].(map[string]interface{})["people"].([]interface{})
+	assert.Len(t, people, 3)
+	assert.EqualValues(t, "Yoshua Bengio est un informaticien canadien. Il est professeur.", people[0].(map[string]interface{})["extract"])
+	assert.Nil(t, people[1].(map[string]interface{})["extract"])
+	assert.Nil(t, people[2])
+
+	errors := result["errors"].([]interface{})
+	assert.Len(t, errors, 1)
+	extensions := errors[0].(map[string]interface{})["extensions"].(map[string]interface{})
+	assert.EqualValues(t, "page_not_found", extensions["reason"])
+	assert.EqualValues(t, []pagesCall{
+		{"en", []string{"Yoshua Bengio", "Geoffrey Hinton", "Nobody At All"}, "langlinks"},
+		{"fr", []string{"Yoshua Bengio"}, "extract"},
+	}, mock.calls)
+}
+
+func TestLoaderReusesPages(t *testing.T) {
+	mock := setupProvider(t)
+
+	_, executed := execute(t, `{
+		a: person(name: "Yoshua_Bengio") { image { file } }
+		b: person(name: "Yoshua_Bengio") { title }
+	}`, nil)
+	assert.True(t, executed)
+	assert.Len(t, mock.calls, 1)
+}
+
+func TestComplexityLimit(t *testing.T) {
+	mock := setupProvider(t)
+	names := make([]interface{}, 600)
+	for i := range names {
+		names[i] = fmt.Sprintf("Person %d", i)
+	}
+
+	result, executed := execute(t, `query People($names: [String!]!) {
+		people(names: $names) { title shortDescription image { thumbnail license } infobox { type fields { name value } } }
+	}`, map[string]interface{}{"names": names})
+	assert.False(t, executed)
+	assert.Contains(t, result["errors"].([]interface{})[0].(map[string]interface{})["message"], "query complexity")
+	assert.Empty(t, mock.calls)
+}
+
+func complexityOf(query string, variables map[string]interface{}) (int, int, error) {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return 0, 0, err
+	}
+	return complexity(document, "", variables)
+}
+
+func TestComplexity(t *testing.T) {
+	cost, depth, err := complexityOf(`{ person(name: "x") { title image { thumbnail } } }`, nil)
+	assert.Nil(t, err)
+	// person 1, title 1, image 2, thumbnail 1
+	assert.EqualValues(t, 5, cost)
+	assert.EqualValues(t, 3, depth)
+
+	cost, _, err = complexityOf(`{ people(names: ["a", "b", "c"]) { title extract } __schema { types { name } } }`, nil)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 3*3+1, cost)
+}
+
+func TestInvalidQuery(t *testing.T) {
+	setupProvider(t)
+
+	result, executed := execute(t, `{ person(name: "Yoshua_Bengio") { horoscope } }`, nil)
+	assert.False(t, executed)
+	assert.Contains(t, result["errors"].([]interface{})[0].(map[string]interface{})["message"], `Cannot query field "horoscope"`)
+
+	_, executed = execute(t, `{ person(`, nil)
+	assert.False(t, executed)
+}
+
+func TestTenantSettings(t *testing.T) {
+	mock := setupProvider(t)
+	tenant := &wiki_domain.Tenant{ID: "acme", AllowedLocales: []string{"fr"}, DefaultLocale: "fr", Facets: []string{"extract"}, MaxBatchSize: 1}
+	ctx := wiki_domain.WithTenant(context.Background(), tenant)
+
+	// The tenant's default locale is looked up when the query names none
+	result, _ := Execute(ctx, wiki_domain.GraphQLRequest{Query: `{ person(name: "Yoshua Bengio") { extract } }`})
+	assert.Nil(t, result.Errors)
+	assert.EqualValues(t, []pagesCall{{"fr", []string{"Yoshua Bengio"}, "extract"}}, mock.calls)
+
+	for _, query := range []string{
+		`{ person(name: "Yoshua Bengio", locale: "en") { title } }`,
+		`{ person(name: "Yoshua Bengio") { image { file } } }`,
+		`{ people(names: ["a", "b"]) { title } }`,
+	} {
+		result, _ := Execute(ctx, wiki_domain.GraphQLRequest{Query: query})
+		assert.Len(t, result.Errors, 1, query)
+	}
+	assert.Len(t, mock.calls, 1)
+}
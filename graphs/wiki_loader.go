@@ -0,0 +1,139 @@
+package wiki_graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	wiki_domain "wiki-names/domains"
+	wiki_locale "wiki-names/locales"
+	wiki_provider "wiki-names/providers"
+)
+
+// PageLoader batches the page lookups of one GraphQL request. Resolvers call Load, which only queues
+// the title and returns a thunk; the first thunk called fetches everything queued for its locale in
+// one GetPages call (50 titles per upstream call), and the pages are kept for the rest of the request.
+type PageLoader struct {
+	ctx     context.Context
+	mu      sync.Mutex
+	pending map[string]*pageBatch
+	loaded  map[string][]*pageBatch
+	// Tenant is whose locales and facets the pages can be loaded in, nil allows them all
+	Tenant *wiki_domain.Tenant
+	// Calls counts the GetPages calls made, for tests and logs
+	Calls int
+}
+
+// pageBatch is the titles of one locale queued together, and the facets any of them asked for
+type pageBatch struct {
+	locale string
+	titles []string
+	facets map[string]bool
+
+	once  sync.Once
+	pages map[string]*wiki_domain.Page
+	err   *wiki_domain.WikiError
+}
+
+// NewPageLoader makes the loader of one request, its GetPages calls end with ctx
+func NewPageLoader(ctx context.Context) *PageLoader {
+	return &PageLoader{ctx: ctx, pending: map[string]*pageBatch{}, loaded: map[string][]*pageBatch{}}
+}
+
+// Load queues the title with the facets it needs and returns the thunk that gets its page,
+// a page already fetched with those facets is not fetched again
+func (l *PageLoader) Load(locale string, title string, facets ...string) func() (*wiki_domain.Page, *wiki_domain.WikiError) {
+	if err := l.allows(locale, facets); err != nil {
+		return func() (*wiki_domain.Page, *wiki_domain.WikiError) { return nil, err }
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, batch := range l.loaded[locale] {
+		if batch.has(title, facets) {
+			return l.thunk(batch, title)
+		}
+	}
+	batch, ok := l.pending[locale]
+	if !ok {
+		batch = &pageBatch{locale: locale, facets: map[string]bool{}}
+		l.pending[locale] = batch
+	}
+	if !contains(batch.titles, title) {
+		batch.titles = append(batch.titles, title)
+	}
+	for _, facet := range facets {
+		batch.facets[facet] = true
+	}
+	return l.thunk(batch, title)
+}
+
+// allows checks the tenant may use the locale and facets, an unknown locale is left to GetPages to report
+func (l *PageLoader) allows(locale string, facets []string) *wiki_domain.WikiError {
+	if l.Tenant == nil {
+		return nil
+	}
+	if normalized, err := wiki_locale.Locales.Normalize(locale); err == nil && !l.Tenant.AllowsLocale(normalized.Code) {
+		return wiki_domain.NewTypedError(wiki_domain.ErrNotEnabled, fmt.Sprintf("locale %s is not enabled for tenant %s", normalized.Code, l.Tenant.ID))
+	}
+	for _, facet := range facets {
+		if !l.Tenant.AllowsFacet(facet) {
+			return wiki_domain.NewTypedError(wiki_domain.ErrNotEnabled, fmt.Sprintf("%s is not enabled for tenant %s", facet, l.Tenant.ID))
+		}
+	}
+	return nil
+}
+
+func (l *PageLoader) thunk(batch *pageBatch, title string) func() (*wiki_domain.Page, *wiki_domain.WikiError) {
+	return func() (*wiki_domain.Page, *wiki_domain.WikiError) {
+		batch.once.Do(func() { l.dispatch(batch) })
+		if batch.err != nil {
+			return nil, batch.err
+		}
+		page, ok := batch.pages[title]
+		if !ok {
+			return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, fmt.Sprintf("Page %s does not exist", title)).WithUpstream(batch.locale + ".wikipedia.org")
+		}
+		return page, nil
+	}
+}
+
+// dispatch fetches the batch, titles queued after this go to a new batch
+func (l *PageLoader) dispatch(batch *pageBatch) {
+	l.mu.Lock()
+	if l.pending[batch.locale] == batch {
+		delete(l.pending, batch.locale)
+	}
+	l.loaded[batch.locale] = append(l.loaded[batch.locale], batch)
+	l.Calls++
+	l.mu.Unlock()
+
+	facets := make([]string, 0, len(batch.facets))
+	for facet := range batch.facets {
+		facets = append(facets, facet)
+	}
+	sort.Strings(facets)
+	batch.pages, batch.err = wiki_provider.WikiProvider.GetPages(l.ctx, batch.locale, batch.titles, strings.Join(facets, ","))
+}
+
+func (b *pageBatch) has(title string, facets []string) bool {
+	if !contains(b.titles, title) {
+		return false
+	}
+	for _, facet := range facets {
+		if !b.facets[facet] {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
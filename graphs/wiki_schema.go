@@ -0,0 +1,401 @@
+package wiki_graph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+
+	wiki_domain "wiki-names/domains"
+	wiki_locale "wiki-names/locales"
+	wiki_name "wiki-names/names"
+	wiki_sentence "wiki-names/sentences"
+)
+
+// defaultExtractSentences is how much of the intro extract returns when no limit is asked for, like /extract
+const defaultExtractSentences = 2
+
+// person is what the Person fields resolve from: the page, the locale it was looked up in, and the
+// facets it was loaded with. Fields needing another facet load the page again, batched the same way.
+type person struct {
+	locale string
+	facets map[string]bool
+	*wiki_domain.Page
+}
+
+type loaderKey struct{}
+
+func loaderFrom(ctx context.Context) *PageLoader {
+	return ctx.Value(loaderKey{}).(*PageLoader)
+}
+
+var imageType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Image",
+	Description: "The page's lead image and its license on Commons",
+	Fields: graphql.Fields{
+		"file":           &graphql.Field{Type: graphql.String},
+		"thumbnail":      &graphql.Field{Type: graphql.String, Description: "A 320px wide thumbnail"},
+		"width":          &graphql.Field{Type: graphql.Int},
+		"height":         &graphql.Field{Type: graphql.Int},
+		"original":       &graphql.Field{Type: graphql.String},
+		"originalWidth":  &graphql.Field{Type: graphql.Int},
+		"originalHeight": &graphql.Field{Type: graphql.Int},
+		"license":        &graphql.Field{Type: graphql.String},
+		"licenseUrl":     &graphql.Field{Type: graphql.String},
+		"artist":         &graphql.Field{Type: graphql.String},
+		"attribution":    &graphql.Field{Type: graphql.String},
+	},
+})
+
+var infoboxFieldType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "InfoboxField",
+	Fields: graphql.Fields{
+		"name":  &graphql.Field{Type: graphql.String},
+		"value": &graphql.Field{Type: graphql.String},
+	},
+})
+
+var infoboxType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Infobox",
+	Description: "The page's first infobox, as plain text",
+	Fields: graphql.Fields{
+		"type": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*wiki_domain.Infobox).Type, nil
+		}},
+		"fields": &graphql.Field{Type: graphql.NewList(infoboxFieldType), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			infobox := p.Source.(*wiki_domain.Infobox)
+			names := make([]string, 0, len(infobox.Fields))
+			for name := range infobox.Fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			fields := make([]interface{}, 0, len(names))
+			for _, name := range names {
+				fields = append(fields, map[string]interface{}{"name": name, "value": infobox.Fields[name]})
+			}
+			return fields, nil
+		}},
+		"field": &graphql.Field{
+			Type:        graphql.String,
+			Description: "One field by its parameter name, e.g. birth_date",
+			Args:        graphql.FieldConfigArgument{"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				if value, ok := p.Source.(*wiki_domain.Infobox).Fields[p.Args["name"].(string)]; ok {
+					return value, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var nameType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Name",
+	Description: "The title split into name parts, by the same rules as /search",
+	Fields: graphql.Fields{
+		"given":         &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"particles":     &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"family":        &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"suffixes":      &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"disambiguator": &graphql.Field{Type: graphql.String},
+		"source":        &graphql.Field{Type: graphql.String},
+	},
+})
+
+var langlinkType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Langlink",
+	Fields: graphql.Fields{
+		"lang":  &graphql.Field{Type: graphql.String},
+		"title": &graphql.Field{Type: graphql.String},
+	},
+})
+
+var personType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Person",
+	Description: "A page, usually about a person, with the lookup facets as fields",
+	Fields: graphql.Fields{
+		"title": &graphql.Field{Type: graphql.String, Description: "The page's title, after redirects", Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*person).Title, nil
+		}},
+		"language": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*person).Language, nil
+		}},
+		"shortDescription": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			if description := p.Source.(*person).ShortDescription; description != "" {
+				return description, nil
+			}
+			return nil, nil
+		}},
+		"name": &graphql.Field{Type: nameType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			name := wiki_name.Parse(p.Source.(*person).Title)
+			return map[string]interface{}{
+				"given": name.Given, "particles": name.Particles, "family": name.Family, "suffixes": name.Suffixes,
+				"disambiguator": name.Disambiguator, "source": name.Source,
+			}, nil
+		}},
+		"image": &graphql.Field{Type: imageType, Resolve: facetField("image", func(page *wiki_domain.Page) interface{} {
+			if page.Image == nil {
+				return nil
+			}
+			image := page.Image
+			return map[string]interface{}{
+				"file": image.File, "thumbnail": image.Thumbnail, "width": image.Width, "height": image.Height,
+				"original": image.Original, "originalWidth": image.OriginalWidth, "originalHeight": image.OriginalHeight,
+				"license": image.License, "licenseUrl": image.LicenseUrl, "artist": image.Artist, "attribution": image.Attribution,
+			}
+		})},
+		"infobox": &graphql.Field{Type: infoboxType, Resolve: facetField("infobox", func(page *wiki_domain.Page) interface{} {
+			if page.Infobox == nil {
+				return nil
+			}
+			return page.Infobox
+		})},
+		"categories": &graphql.Field{Type: graphql.NewList(graphql.String), Resolve: facetField("categories", func(page *wiki_domain.Page) interface{} {
+			return page.Categories
+		})},
+		"langlinks": &graphql.Field{Type: graphql.NewList(langlinkType), Resolve: facetField("langlinks", func(page *wiki_domain.Page) interface{} {
+			langs := make([]string, 0, len(page.Langlinks))
+			for lang := range page.Langlinks {
+				langs = append(langs, lang)
+			}
+			sort.Strings(langs)
+			links := make([]interface{}, 0, len(langs))
+			for _, lang := range langs {
+				links = append(links, map[string]interface{}{"lang": lang, "title": page.Langlinks[lang]})
+			}
+			return links
+		})},
+		"redirects": &graphql.Field{Type: graphql.NewList(graphql.String), Resolve: facetField("redirects", func(page *wiki_domain.Page) interface{} {
+			return page.Redirects
+		})},
+		"extract": &graphql.Field{
+			Type:        graphql.String,
+			Description: "The start of the intro, in another edition when locale is given (through the page's interlanguage links)",
+			Args: graphql.FieldConfigArgument{
+				"locale":     &graphql.ArgumentConfig{Type: graphql.String},
+				"sentences":  &graphql.ArgumentConfig{Type: graphql.Int},
+				"chars":      &graphql.ArgumentConfig{Type: graphql.Int},
+				"paragraphs": &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: resolveExtract,
+		},
+	},
+})
+
+// facetField resolves a Person field from the page, loading the page again with the facet when it was loaded without it
+func facetField(facet string, value func(page *wiki_domain.Page) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		source := p.Source.(*person)
+		if source.facets[facet] {
+			return value(source.Page), nil
+		}
+		load := loaderFrom(p.Context).Load(source.locale, source.Title, facet)
+		return func() (interface{}, error) {
+			page, err := load()
+			if err != nil {
+				return nil, graphError{err}
+			}
+			return value(page), nil
+		}, nil
+	}
+}
+
+func resolveExtract(p graphql.ResolveParams) (interface{}, error) {
+	source := p.Source.(*person)
+	limits := wiki_sentence.Limits{Sentences: intArg(p.Args, "sentences"), Chars: intArg(p.Args, "chars"), Paragraphs: intArg(p.Args, "paragraphs")}
+	if limits.IsZero() {
+		limits.Sentences = defaultExtractSentences
+	}
+	truncate := func(page *wiki_domain.Page, locale string) interface{} {
+		if extract := wiki_sentence.Truncate(page.Extract, locale, limits); extract != "" {
+			return extract
+		}
+		return nil
+	}
+	locale, _ := p.Args["locale"].(string)
+	if locale == "" || locale == source.locale {
+		return facetField("extract", func(page *wiki_domain.Page) interface{} { return truncate(page, source.locale) })(p)
+	}
+
+	// The title in the other edition comes from the langlinks, which selectedFacets has the person
+	// loaded with whenever the query asks for a translated extract, load them now if it somehow wasn't
+	page := source.Page
+	if !source.facets["langlinks"] {
+		loaded, err := loaderFrom(p.Context).Load(source.locale, source.Title, "langlinks")()
+		if err != nil {
+			return nil, graphError{err}
+		}
+		page = loaded
+	}
+	title, ok := page.Langlinks[locale]
+	if !ok {
+		return nil, nil
+	}
+	load := loaderFrom(p.Context).Load(locale, title, "extract")
+	return func() (interface{}, error) {
+		translated, err := load()
+		if err != nil {
+			return nil, graphError{err}
+		}
+		return truncate(translated, locale), nil
+	}, nil
+}
+
+// maxNames caps the names of one people query, whatever its complexity
+const maxNames = 500
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"person": &graphql.Field{
+			Type: personType,
+			Args: graphql.FieldConfigArgument{
+				"name":   &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String), Description: "The page title, spaces or underscores"},
+				"locale": &graphql.ArgumentConfig{Type: graphql.String, Description: "The Wikipedia edition, the tenant's default or en by default"},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return loadPerson(p, p.Args["name"].(string), selectedFacets(p.Info)), nil
+			},
+		},
+		"people": &graphql.Field{
+			Type:        graphql.NewList(personType),
+			Description: "Several pages at once, looked up 50 titles per upstream call",
+			Args: graphql.FieldConfigArgument{
+				"names":  &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+				"locale": &graphql.ArgumentConfig{Type: graphql.String},
+			},
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				names := p.Args["names"].([]interface{})
+				if max := wiki_domain.TenantFrom(p.Context).BatchSize(maxNames); len(names) > max {
+					return nil, graphError{&wiki_domain.WikiError{Code: http.StatusBadRequest, ErrorMessage: fmt.Sprintf("at most %d names can be looked up at once", max)}}
+				}
+				facets := selectedFacets(p.Info)
+				people := make([]interface{}, 0, len(names))
+				for _, name := range names {
+					people = append(people, loadPerson(p, name.(string), facets))
+				}
+				return people, nil
+			},
+		},
+	},
+})
+
+// Schema is the GraphQL schema over the lookup facets
+var Schema, _ = graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+
+// loadPerson queues the name and returns the thunk resolving it, so every name of the query is fetched together
+func loadPerson(p graphql.ResolveParams, name string, facets []string) func() (interface{}, error) {
+	locale, _ := p.Args["locale"].(string)
+	if locale == "" {
+		locale = wiki_domain.TenantFrom(p.Context).LocaleOr(wiki_locale.DefaultLocale)
+	}
+	load := loaderFrom(p.Context).Load(locale, name, facets...)
+	return func() (interface{}, error) {
+		page, err := load()
+		if err != nil {
+			return nil, graphError{err}
+		}
+		loaded := map[string]bool{}
+		for _, facet := range facets {
+			loaded[facet] = true
+		}
+		return &person{locale: locale, facets: loaded, Page: page}, nil
+	}
+}
+
+// personFacets are the Person fields that need a facet fetched with the page
+var personFacets = map[string]string{
+	"image":      "image",
+	"infobox":    "infobox",
+	"categories": "categories",
+	"langlinks":  "langlinks",
+	"redirects":  "redirects",
+}
+
+// selectedFacets lists the facets the fields selected under this field need, fragments included,
+// so the page is fetched with all of them in one go
+func selectedFacets(info graphql.ResolveInfo) []string {
+	found := map[string]bool{}
+	var walk func(set *ast.SelectionSet)
+	walk = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch selection := selection.(type) {
+			case *ast.Field:
+				name := selection.Name.Value
+				if facet, ok := personFacets[name]; ok {
+					found[facet] = true
+				}
+				if name == "extract" {
+					if locale := stringArg(selection.Arguments, "locale", info.VariableValues); locale != "" {
+						found["langlinks"] = true
+					} else {
+						found["extract"] = true
+					}
+				}
+			case *ast.InlineFragment:
+				walk(selection.SelectionSet)
+			case *ast.FragmentSpread:
+				if fragment, ok := info.Fragments[selection.Name.Value].(*ast.FragmentDefinition); ok {
+					walk(fragment.SelectionSet)
+				}
+			}
+		}
+	}
+	for _, field := range info.FieldASTs {
+		walk(field.SelectionSet)
+	}
+	facets := make([]string, 0, len(found))
+	for facet := range found {
+		facets = append(facets, facet)
+	}
+	sort.Strings(facets)
+	return facets
+}
+
+// stringArg is the value of a string argument, written inline or passed as a variable
+func stringArg(arguments []*ast.Argument, name string, variables map[string]interface{}) string {
+	for _, argument := range arguments {
+		if argument.Name.Value != name {
+			continue
+		}
+		switch value := argument.Value.(type) {
+		case *ast.StringValue:
+			return value.Value
+		case *ast.Variable:
+			text, _ := variables[value.Name.Value].(string)
+			return text
+		}
+	}
+	return ""
+}
+
+func intArg(args map[string]interface{}, name string) int {
+	value, _ := args[name].(int)
+	return value
+}
+
+// graphError carries the reason and status of a lookup error into the GraphQL error's extensions
+type graphError struct {
+	*wiki_domain.WikiError
+}
+
+func (e graphError) Error() string {
+	return e.ErrorMessage
+}
+
+func (e graphError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{"status": e.Code}
+	if e.ErrorCode != "" {
+		extensions["reason"] = e.ErrorCode
+	}
+	if e.UpstreamWiki != "" {
+		extensions["upstream"] = e.UpstreamWiki
+	}
+	return extensions
+}
@@ -0,0 +1,98 @@
+package wiki_infobox
+
+import (
+	"regexp"
+	"strings"
+
+	wiki_domain "wiki-names/domains"
+)
+
+var (
+	infoboxStartRe = regexp.MustCompile(`(?i){{\s*Infobox[ _]*([^|}\n<]*)`)
+	commentRe      = regexp.MustCompile(`(?s)<!--.*?-->`)
+	refRe          = regexp.MustCompile(`(?is)<ref[^>/]*/>|<ref[^>]*>.*?</ref>`)
+	breakRe        = regexp.MustCompile(`(?i)<br\s*/?>`)
+	tagRe          = regexp.MustCompile(`<[^>]*>`)
+	pipedLinkRe    = regexp.MustCompile(`\[\[[^\]|]*\|([^\]]*)\]\]`)
+	linkRe         = regexp.MustCompile(`\[\[([^\]]*)\]\]`)
+	externalLinkRe = regexp.MustCompile(`\[https?://[^\s\]]+\s+([^\]]*)\]`)
+	// Wrapper templates whose only job is layout, {{nowrap|Yoshua Bengio}} reads as Yoshua Bengio
+	wrapperRe = regexp.MustCompile(`(?i){{\s*(?:nowrap|nobr|small|lang\|[a-z-]+)\s*\|([^{}|]*)}}`)
+)
+
+// Parse reads the first infobox of the wikitext into its type and non empty fields, nil when the page has none
+func Parse(wikitext string) *wiki_domain.Infobox {
+	wikitext = commentRe.ReplaceAllString(wikitext, "")
+	location := infoboxStartRe.FindStringSubmatchIndex(wikitext)
+	if location == nil {
+		return nil
+	}
+	infobox := &wiki_domain.Infobox{
+		Type:   strings.TrimSpace(strings.ReplaceAll(wikitext[location[2]:location[3]], "_", " ")),
+		Fields: map[string]string{},
+	}
+	for _, parameter := range parameters(wikitext[location[0]:]) {
+		equals := strings.Index(parameter, "=")
+		if equals < 0 {
+			continue
+		}
+		key := strings.TrimSpace(parameter[:equals])
+		value := clean(parameter[equals+1:])
+		if key != "" && value != "" {
+			infobox.Fields[key] = value
+		}
+	}
+	return infobox
+}
+
+// parameters splits the template starting at text on the pipes that are not inside a nested template or link
+func parameters(text string) []string {
+	var parameters []string
+	braces, brackets, start := 0, 0, -1
+	for i := 0; i < len(text)-1; i++ {
+		switch {
+		case text[i] == '{' && text[i+1] == '{':
+			braces++
+			i++
+		case text[i] == '}' && text[i+1] == '}':
+			braces--
+			i++
+			if braces == 0 {
+				if start >= 0 {
+					parameters = append(parameters, text[start:i-1])
+				}
+				return parameters
+			}
+		case text[i] == '[' && text[i+1] == '[':
+			brackets++
+			i++
+		case text[i] == ']' && text[i+1] == ']':
+			brackets--
+			i++
+		case text[i] == '|' && braces == 1 && brackets == 0:
+			if start >= 0 {
+				parameters = append(parameters, text[start:i])
+			}
+			start = i + 1
+		}
+	}
+	// An unclosed infobox still has its parameters up to the end of the page
+	if start >= 0 {
+		parameters = append(parameters, text[start:])
+	}
+	return parameters
+}
+
+// clean turns a parameter value into plain text: links become their label, refs and markup go away
+func clean(value string) string {
+	value = refRe.ReplaceAllString(value, "")
+	value = breakRe.ReplaceAllString(value, ", ")
+	value = tagRe.ReplaceAllString(value, "")
+	value = wrapperRe.ReplaceAllString(value, "$1")
+	value = pipedLinkRe.ReplaceAllString(value, "$1")
+	value = linkRe.ReplaceAllString(value, "$1")
+	value = externalLinkRe.ReplaceAllString(value, "$1")
+	value = strings.NewReplacer("'''", "", "''", "", "&nbsp;", " ").Replace(value)
+	value = strings.Join(strings.Fields(value), " ")
+	return strings.Trim(value, ", ")
+}
@@ -0,0 +1,44 @@
+package wiki_infobox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const bengio = `{{Short description|Canadian computer scientist}}
+{{Infobox scientist
+| name              = Yoshua Bengio
+| honorific_suffix  = {{post-nominals|country=CAN|OC|FRS|FRSC}}
+| image             = Yoshua Bengio 2019 cropped.jpg
+| birth_date        = {{birth date and age|1964|3|5}}
+| birth_place       = [[Paris]], France
+| fields            = [[Artificial intelligence]]<br />[[Deep learning|deep learning]]
+| alma_mater        = [[McGill University]]<ref name="thesis">{{cite thesis|title=Artificial Neural Networks}}</ref>
+| website           = <!-- none yet -->
+| awards            = {{nowrap|[[Turing Award]] (2018)}}
+}}
+'''Yoshua Bengio''' is a Canadian computer scientist.`
+
+func TestParseInfobox(t *testing.T) {
+	infobox := Parse(bengio)
+	assert.NotNil(t, infobox)
+	assert.EqualValues(t, "scientist", infobox.Type)
+	assert.EqualValues(t, "Yoshua Bengio", infobox.Fields["name"])
+	assert.EqualValues(t, "Paris, France", infobox.Fields["birth_place"])
+	assert.EqualValues(t, "Artificial intelligence, deep learning", infobox.Fields["fields"])
+	assert.EqualValues(t, "McGill University", infobox.Fields["alma_mater"])
+	assert.EqualValues(t, "{{birth date and age|1964|3|5}}", infobox.Fields["birth_date"])
+	assert.EqualValues(t, "Turing Award (2018)", infobox.Fields["awards"])
+	assert.NotContains(t, infobox.Fields, "website")
+}
+
+func TestParseNoInfobox(t *testing.T) {
+	assert.Nil(t, Parse("{{Short description|Topics referred to by the same term}}\n'''Heat''' may refer to:"))
+}
+
+func TestParseUnderscoreType(t *testing.T) {
+	infobox := Parse("{{Infobox_football_biography|name=Lionel Messi|position=[[Forward (association football)|Forward]]}}")
+	assert.EqualValues(t, "football biography", infobox.Type)
+	assert.EqualValues(t, "Forward", infobox.Fields["position"])
+}
@@ -0,0 +1,87 @@
+package wiki_key
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Counters are named counters that expire ttl after they were first incremented
+type Counters interface {
+	Incr(name string, ttl time.Duration) (int64, error)
+	Get(name string) (int64, error)
+}
+
+// MemoryCounters keep the counts in this process, they start over on a restart
+type MemoryCounters struct {
+	mu        sync.Mutex
+	counts    map[string]*memoryCount
+	lastSweep time.Time
+}
+
+type memoryCount struct {
+	value   int64
+	expires time.Time
+}
+
+func NewMemoryCounters() *MemoryCounters {
+	return &MemoryCounters{counts: map[string]*memoryCount{}, lastSweep: time.Now()}
+}
+
+func (m *MemoryCounters) Incr(name string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	// Drop the expired counters once a minute rather than on every call
+	if now.Sub(m.lastSweep) > time.Minute {
+		for key, count := range m.counts {
+			if now.After(count.expires) {
+				delete(m.counts, key)
+			}
+		}
+		m.lastSweep = now
+	}
+	count, ok := m.counts[name]
+	if !ok || now.After(count.expires) {
+		count = &memoryCount{expires: now.Add(ttl)}
+		m.counts[name] = count
+	}
+	count.value++
+	return count.value, nil
+}
+
+func (m *MemoryCounters) Get(name string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if count, ok := m.counts[name]; ok && time.Now().Before(count.expires) {
+		return count.value, nil
+	}
+	return 0, nil
+}
+
+// RedisCounters share the counts between every instance behind the same Redis
+type RedisCounters struct {
+	Client *redis.Client
+}
+
+// incrScript sets the expiry with the first increment only, in one step so a counter can't be left without one
+var incrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count`)
+
+func (r *RedisCounters) Incr(name string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(context.Background(), r.Client, []string{"wiki:counter:" + name}, ttl.Milliseconds()).Int64()
+}
+
+func (r *RedisCounters) Get(name string) (int64, error) {
+	value, err := r.Client.Get(context.Background(), "wiki:counter:"+name).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return value, err
+}
@@ -0,0 +1,282 @@
+package wiki_key
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// KeyPrefix starts every issued key, so a Bearer token without it can be left to other authentication
+const KeyPrefix = "wk_"
+
+// usageRetention is how long the daily usage counters are kept, and so how far back Usage can look
+const usageRetention = 35 * 24 * time.Hour
+
+// Manager issues keys and checks the requests made with them against their limits
+type Manager struct {
+	Store    Store
+	Counters Counters
+	// DefaultRateLimit and DefaultDailyQuota are given to keys issued without limits of their own
+	DefaultRateLimit  int
+	DefaultDailyQuota int
+
+	now func() time.Time
+}
+
+// Limits is what a key has left of its most constrained limit, for the RateLimit-* headers
+type Limits struct {
+	Limit     int
+	Remaining int
+	Reset     time.Duration
+	// Policy lists every limit of the key, as in "60;w=60, 10000;w=86400"
+	Policy string
+}
+
+// Keys is the manager behind the API key middleware and admin routes, nil when API keys aren't configured
+var Keys *Manager
+
+func NewManager(store Store, counters Counters) *Manager {
+	return &Manager{Store: store, Counters: counters, DefaultRateLimit: 60, DefaultDailyQuota: 10000, now: time.Now}
+}
+
+// Authenticate finds the key, which must not be revoked, or be a rotated key past its grace period
+func (m *Manager) Authenticate(token string) (*wiki_domain.APIKey, *wiki_domain.WikiError) {
+	hash := hashKey(token)
+	key, err := m.Store.Find(hash)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if key == nil {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidAPIKey, "unknown API key")
+	}
+	if key.RevokedAt != nil {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidAPIKey, "this API key has been revoked")
+	}
+	if key.Hash != hash && (key.PreviousExpiresAt == nil || m.now().After(*key.PreviousExpiresAt)) {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidAPIKey, "this API key has been rotated")
+	}
+	return key, nil
+}
+
+// Allow counts a request against the key's rate limit and daily quota. The counters failing lets
+// the request through, we'd rather serve it than turn everyone away while Redis is down.
+func (m *Manager) Allow(key *wiki_domain.APIKey) (*Limits, *wiki_domain.WikiError) {
+	now := m.now().UTC()
+	var limits *Limits
+	var policies []string
+	tighter := func(limit int, count int64, reset time.Duration) {
+		remaining := limit - int(count)
+		if remaining < 0 {
+			remaining = 0
+		}
+		if limits == nil || remaining < limits.Remaining {
+			limits = &Limits{Limit: limit, Remaining: remaining, Reset: reset}
+		}
+	}
+
+	if key.RateLimit > 0 {
+		policies = append(policies, fmt.Sprintf("%d;w=60", key.RateLimit))
+		window := now.Truncate(time.Minute)
+		count, err := m.Counters.Incr(fmt.Sprintf("rate:%s:%d", key.ID, window.Unix()), 2*time.Minute)
+		if err != nil {
+			log.Printf("API keys: failed to count a request of %s: %s", key.ID, err.Error())
+		} else {
+			tighter(key.RateLimit, count, window.Add(time.Minute).Sub(now))
+			if count > int64(key.RateLimit) {
+				return m.reject(key, limits, policies, now, wiki_domain.ErrRateLimited, fmt.Sprintf("API key %s is limited to %d requests a minute", key.ID, key.RateLimit))
+			}
+		}
+	}
+
+	day := now.Truncate(24 * time.Hour)
+	count, err := m.Counters.Incr(usageCounter("usage", key.ID, day), usageRetention)
+	if err != nil {
+		log.Printf("API keys: failed to count a request of %s: %s", key.ID, err.Error())
+	} else if key.DailyQuota > 0 {
+		policies = append(policies, fmt.Sprintf("%d;w=86400", key.DailyQuota))
+		reset := day.Add(24 * time.Hour).Sub(now)
+		tighter(key.DailyQuota, count, reset)
+		if count > int64(key.DailyQuota) {
+			// Report the quota even when the rate limit has less left, it's what the client waits for
+			limits = &Limits{Limit: key.DailyQuota, Remaining: 0, Reset: reset}
+			return m.reject(key, limits, policies, now, wiki_domain.ErrQuotaExceeded, fmt.Sprintf("API key %s has used its %d requests for today", key.ID, key.DailyQuota))
+		}
+	}
+	if limits != nil {
+		limits.Policy = strings.Join(policies, ", ")
+	}
+	return limits, nil
+}
+
+func (m *Manager) reject(key *wiki_domain.APIKey, limits *Limits, policies []string, now time.Time, code wiki_domain.ErrorCode, message string) (*Limits, *wiki_domain.WikiError) {
+	if _, err := m.Counters.Incr(usageCounter("rejected", key.ID, now.Truncate(24*time.Hour)), usageRetention); err != nil {
+		log.Printf("API keys: failed to count a rejected request of %s: %s", key.ID, err.Error())
+	}
+	limits.Policy = strings.Join(policies, ", ")
+	apiError := wiki_domain.NewTypedError(code, message)
+	apiError.RetryAfter = int(math.Ceil(limits.Reset.Seconds()))
+	return limits, apiError
+}
+
+// Issue creates a key, the key itself is in the result and nowhere else
+func (m *Manager) Issue(request wiki_domain.APIKeyRequest) (*wiki_domain.IssuedKey, *wiki_domain.WikiError) {
+	key := &wiki_domain.APIKey{
+		ID:         randomHex(8),
+		Name:       request.Name,
+		Tenant:     request.Tenant,
+		RateLimit:  m.DefaultRateLimit,
+		DailyQuota: m.DefaultDailyQuota,
+		CreatedAt:  m.now().UTC(),
+	}
+	if request.RateLimit != nil {
+		key.RateLimit = *request.RateLimit
+	}
+	if request.DailyQuota != nil {
+		key.DailyQuota = *request.DailyQuota
+	}
+	secret := newKey(key)
+	if err := m.Store.Save(key); err != nil {
+		return nil, storeError(err)
+	}
+	return &wiki_domain.IssuedKey{APIKey: public(key), Key: secret}, nil
+}
+
+// Rotate replaces the key, the one it replaces keeps working for the grace period
+func (m *Manager) Rotate(id string, grace time.Duration) (*wiki_domain.IssuedKey, *wiki_domain.WikiError) {
+	key, apiError := m.get(id)
+	if apiError != nil {
+		return nil, apiError
+	}
+	if key.RevokedAt != nil {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrConflict, fmt.Sprintf("API key %s has been revoked", id))
+	}
+	now := m.now().UTC()
+	key.PreviousHash, key.PreviousExpiresAt = "", nil
+	if grace > 0 {
+		expires := now.Add(grace)
+		key.PreviousHash, key.PreviousExpiresAt = key.Hash, &expires
+	}
+	key.RotatedAt = &now
+	secret := newKey(key)
+	if err := m.Store.Save(key); err != nil {
+		return nil, storeError(err)
+	}
+	return &wiki_domain.IssuedKey{APIKey: public(key), Key: secret}, nil
+}
+
+// Revoke stops the key and any previous key still in its grace period from working, the key stays listed
+func (m *Manager) Revoke(id string) *wiki_domain.WikiError {
+	key, apiError := m.get(id)
+	if apiError != nil {
+		return apiError
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	now := m.now().UTC()
+	key.RevokedAt = &now
+	if err := m.Store.Save(key); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
+
+// Get returns the key without its hashes
+func (m *Manager) Get(id string) (*wiki_domain.APIKey, *wiki_domain.WikiError) {
+	key, apiError := m.get(id)
+	if apiError != nil {
+		return nil, apiError
+	}
+	return public(key), nil
+}
+
+// List returns every key without its hashes, oldest first, only the tenant's when tenant isn't empty
+func (m *Manager) List(tenant string) ([]*wiki_domain.APIKey, *wiki_domain.WikiError) {
+	keys, err := m.Store.List()
+	if err != nil {
+		return nil, storeError(err)
+	}
+	listed := []*wiki_domain.APIKey{}
+	for _, key := range keys {
+		if tenant == "" || key.Tenant == tenant {
+			listed = append(listed, public(key))
+		}
+	}
+	return listed, nil
+}
+
+// Usage is the key's requests for each of the last days UTC days, today first
+func (m *Manager) Usage(id string, days int) (*wiki_domain.KeyUsage, *wiki_domain.WikiError) {
+	if _, apiError := m.get(id); apiError != nil {
+		return nil, apiError
+	}
+	usage := &wiki_domain.KeyUsage{ID: id, Days: []wiki_domain.DayUsage{}}
+	today := m.now().UTC().Truncate(24 * time.Hour)
+	for i := 0; i < days; i++ {
+		day := today.AddDate(0, 0, -i)
+		requests, err := m.Counters.Get(usageCounter("usage", id, day))
+		if err != nil {
+			return nil, storeError(err)
+		}
+		rejected, err := m.Counters.Get(usageCounter("rejected", id, day))
+		if err != nil {
+			return nil, storeError(err)
+		}
+		usage.Days = append(usage.Days, wiki_domain.DayUsage{Date: day.Format("2006-01-02"), Requests: requests, Rejected: rejected})
+	}
+	return usage, nil
+}
+
+func (m *Manager) get(id string) (*wiki_domain.APIKey, *wiki_domain.WikiError) {
+	key, err := m.Store.Get(id)
+	if err != nil {
+		return nil, storeError(err)
+	}
+	if key == nil {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrNotFound, fmt.Sprintf("API key %s does not exist", id))
+	}
+	return key, nil
+}
+
+// newKey gives the key a new secret and returns it
+func newKey(key *wiki_domain.APIKey) string {
+	secret := KeyPrefix + randomHex(24)
+	key.Hash = hashKey(secret)
+	key.Prefix = secret[:len(KeyPrefix)+6]
+	return secret
+}
+
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func usageCounter(kind string, id string, day time.Time) string {
+	return kind + ":" + id + ":" + day.Format("2006-01-02")
+}
+
+func public(key *wiki_domain.APIKey) *wiki_domain.APIKey {
+	copied := *key
+	copied.Hash, copied.PreviousHash = "", ""
+	return &copied
+}
+
+func storeError(err error) *wiki_domain.WikiError {
+	log.Printf("API keys: key store error: %s", err.Error())
+	return wiki_domain.NewTypedError(wiki_domain.ErrUnavailable, "the key store is unavailable")
+}
+
+func randomHex(size int) string {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(bytes)
+}
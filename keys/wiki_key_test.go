@@ -0,0 +1,193 @@
+package wiki_key
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// clock is a time the tests move by hand
+type clock struct {
+	at time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.at
+}
+
+func setupManager(t *testing.T) (*Manager, *clock) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "keys", "keys.json"))
+	assert.Nil(t, err)
+	manager := NewManager(store, NewMemoryCounters())
+	clock := &clock{at: time.Date(2024, 3, 1, 12, 0, 30, 0, time.UTC)}
+	manager.now = clock.now
+	return manager, clock
+}
+
+func limit(value int) *int {
+	return &value
+}
+
+func TestIssueAndAuthenticate(t *testing.T) {
+	manager, _ := setupManager(t)
+	issued, err := manager.Issue(wiki_domain.APIKeyRequest{Name: "ci", Tenant: "acme"})
+	assert.Nil(t, err)
+	assert.Regexp(t, "^wk_[0-9a-f]{48}$", issued.Key)
+	assert.EqualValues(t, issued.Key[:9], issued.Prefix)
+	assert.Empty(t, issued.Hash)
+	assert.EqualValues(t, 60, issued.RateLimit)
+	assert.EqualValues(t, 10000, issued.DailyQuota)
+
+	key, err := manager.Authenticate(issued.Key)
+	assert.Nil(t, err)
+	assert.EqualValues(t, issued.ID, key.ID)
+	assert.EqualValues(t, "acme", key.Tenant)
+
+	_, err = manager.Authenticate("wk_unknown")
+	assert.EqualValues(t, wiki_domain.ErrInvalidAPIKey, err.ErrorCode)
+
+	listed, _ := manager.List("acme")
+	assert.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Hash)
+	listed, _ = manager.List("someone-else")
+	assert.Empty(t, listed)
+}
+
+func TestRateLimit(t *testing.T) {
+	manager, clock := setupManager(t)
+	issued, _ := manager.Issue(wiki_domain.APIKeyRequest{RateLimit: limit(2), DailyQuota: limit(0)})
+	key, _ := manager.Authenticate(issued.Key)
+
+	limits, err := manager.Allow(key)
+	assert.Nil(t, err)
+	assert.EqualValues(t, &Limits{Limit: 2, Remaining: 1, Reset: 30 * time.Second, Policy: "2;w=60"}, limits)
+	manager.Allow(key)
+	limits, err = manager.Allow(key)
+	assert.EqualValues(t, wiki_domain.ErrRateLimited, err.ErrorCode)
+	assert.EqualValues(t, http.StatusTooManyRequests, err.Code)
+	assert.EqualValues(t, 30, err.RetryAfter)
+	assert.EqualValues(t, 0, limits.Remaining)
+
+	// The next minute starts over
+	clock.at = clock.at.Add(time.Minute)
+	_, err = manager.Allow(key)
+	assert.Nil(t, err)
+
+	usage, _ := manager.Usage(key.ID, 2)
+	assert.EqualValues(t, []wiki_domain.DayUsage{{Date: "2024-03-01", Requests: 3, Rejected: 1}, {Date: "2024-02-29"}}, usage.Days)
+}
+
+func TestDailyQuota(t *testing.T) {
+	manager, clock := setupManager(t)
+	issued, _ := manager.Issue(wiki_domain.APIKeyRequest{RateLimit: limit(100), DailyQuota: limit(2)})
+	key, _ := manager.Authenticate(issued.Key)
+
+	limits, _ := manager.Allow(key)
+	assert.EqualValues(t, &Limits{Limit: 2, Remaining: 1, Reset: 12*time.Hour - 30*time.Second, Policy: "100;w=60, 2;w=86400"}, limits)
+	manager.Allow(key)
+	clock.at = clock.at.Add(time.Hour)
+	limits, err := manager.Allow(key)
+	assert.EqualValues(t, wiki_domain.ErrQuotaExceeded, err.ErrorCode)
+	assert.EqualValues(t, 11*3600-30, err.RetryAfter)
+	assert.EqualValues(t, 2, limits.Limit)
+
+	// Tomorrow's quota is a new one
+	clock.at = clock.at.Add(12 * time.Hour)
+	_, err = manager.Allow(key)
+	assert.Nil(t, err)
+}
+
+func TestRotateAndRevoke(t *testing.T) {
+	manager, clock := setupManager(t)
+	issued, _ := manager.Issue(wiki_domain.APIKeyRequest{})
+
+	rotated, err := manager.Rotate(issued.ID, time.Hour)
+	assert.Nil(t, err)
+	assert.NotEqual(t, issued.Key, rotated.Key)
+	assert.EqualValues(t, issued.ID, rotated.ID)
+
+	// The previous key works until its grace period is over
+	_, err = manager.Authenticate(issued.Key)
+	assert.Nil(t, err)
+	clock.at = clock.at.Add(2 * time.Hour)
+	_, err = manager.Authenticate(issued.Key)
+	assert.EqualValues(t, wiki_domain.ErrInvalidAPIKey, err.ErrorCode)
+	_, err = manager.Authenticate(rotated.Key)
+	assert.Nil(t, err)
+
+	// Without a grace period it stops straight away
+	again, _ := manager.Rotate(issued.ID, 0)
+	_, err = manager.Authenticate(rotated.Key)
+	assert.EqualValues(t, wiki_domain.ErrInvalidAPIKey, err.ErrorCode)
+
+	assert.Nil(t, manager.Revoke(issued.ID))
+	_, err = manager.Authenticate(again.Key)
+	assert.EqualValues(t, wiki_domain.ErrInvalidAPIKey, err.ErrorCode)
+	_, err = manager.Rotate(issued.ID, 0)
+	assert.EqualValues(t, http.StatusConflict, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrConflict, err.ErrorCode)
+	assert.EqualValues(t, http.StatusNotFound, manager.Revoke("missing").Code)
+	assert.EqualValues(t, wiki_domain.ErrNotFound, manager.Revoke("missing").ErrorCode)
+}
+
+func TestFileStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	store, _ := NewFileStore(path)
+	manager := NewManager(store, NewMemoryCounters())
+	issued, _ := manager.Issue(wiki_domain.APIKeyRequest{Name: "ci"})
+
+	reopened, err := NewFileStore(path)
+	assert.Nil(t, err)
+	key, apiError := NewManager(reopened, NewMemoryCounters()).Authenticate(issued.Key)
+	assert.Nil(t, apiError)
+	assert.EqualValues(t, "ci", key.Name)
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	manager := NewManager(&RedisStore{Client: client}, &RedisCounters{Client: client})
+	issued, _ := manager.Issue(wiki_domain.APIKeyRequest{Tenant: "acme", RateLimit: limit(1)})
+
+	key, err := manager.Authenticate(issued.Key)
+	assert.Nil(t, err)
+	_, err = manager.Allow(key)
+	assert.Nil(t, err)
+	_, err = manager.Allow(key)
+	assert.EqualValues(t, wiki_domain.ErrRateLimited, err.ErrorCode)
+	for _, name := range server.Keys() {
+		if strings.HasPrefix(name, "wiki:counter:") {
+			assert.True(t, server.TTL(name) > 0, name)
+		}
+	}
+
+	// The previous key's entry expires with its grace period, a replaced one is removed
+	rotated, _ := manager.Rotate(issued.ID, time.Hour)
+	_, err = manager.Authenticate(issued.Key)
+	assert.Nil(t, err)
+	assert.EqualValues(t, time.Hour, server.TTL(redisHashes+hashKey(issued.Key)).Round(time.Minute))
+	manager.Rotate(issued.ID, 0)
+	assert.False(t, server.Exists(redisHashes+hashKey(issued.Key)))
+	assert.False(t, server.Exists(redisHashes+hashKey(rotated.Key)))
+
+	listed, err := manager.List("acme")
+	assert.Nil(t, err)
+	assert.Len(t, listed, 1)
+	usage, _ := manager.Usage(issued.ID, 1)
+	assert.EqualValues(t, 1, usage.Days[0].Requests)
+	assert.EqualValues(t, 1, usage.Days[0].Rejected)
+
+	// Redis going away is the store's problem, not the caller's
+	server.Close()
+	_, err = manager.Get(issued.ID)
+	assert.EqualValues(t, http.StatusServiceUnavailable, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrUnavailable, err.ErrorCode)
+}
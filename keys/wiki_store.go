@@ -0,0 +1,216 @@
+package wiki_key
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// Store keeps the issued keys. Find looks a key up by its hash or, after a rotation, by its
+// previous hash, and returns nil when no key has it.
+type Store interface {
+	Find(hash string) (*wiki_domain.APIKey, error)
+	Get(id string) (*wiki_domain.APIKey, error)
+	List() ([]*wiki_domain.APIKey, error)
+	Save(key *wiki_domain.APIKey) error
+}
+
+// FileStore keeps the keys in memory and in one JSON file, rewritten on every save
+type FileStore struct {
+	Path string
+
+	mu   sync.RWMutex
+	keys map[string]*wiki_domain.APIKey
+}
+
+// NewFileStore reads the keys from path, a file that doesn't exist yet has no keys
+func NewFileStore(path string) (*FileStore, error) {
+	store := &FileStore{Path: path, keys: map[string]*wiki_domain.APIKey{}}
+	bytes, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []*wiki_domain.APIKey
+	if err := json.Unmarshal(bytes, &keys); err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		store.keys[key.ID] = key
+	}
+	return store, nil
+}
+
+func (s *FileStore) Find(hash string) (*wiki_domain.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.keys {
+		if key.Hash == hash || key.PreviousHash == hash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *FileStore) Get(id string) (*wiki_domain.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *key
+	return &copied, nil
+}
+
+func (s *FileStore) List() ([]*wiki_domain.APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.sorted(), nil
+}
+
+// Save writes to a temporary file first, so a crash mid-write leaves the previous keys in place
+func (s *FileStore) Save(key *wiki_domain.APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *key
+	previous, existed := s.keys[key.ID]
+	s.keys[key.ID] = &copied
+	bytes, err := json.MarshalIndent(s.sorted(), "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.Path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(s.Path+".tmp", bytes, 0o600)
+	}
+	if err == nil {
+		err = os.Rename(s.Path+".tmp", s.Path)
+	}
+	if err != nil {
+		if existed {
+			s.keys[key.ID] = previous
+		} else {
+			delete(s.keys, key.ID)
+		}
+	}
+	return err
+}
+
+func (s *FileStore) sorted() []*wiki_domain.APIKey {
+	keys := make([]*wiki_domain.APIKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		copied := *key
+		keys = append(keys, &copied)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys
+}
+
+const (
+	redisKeys   = "wiki:apikeys"
+	redisHashes = "wiki:apikey-hash:"
+)
+
+// RedisStore keeps the keys as JSON in the wiki:apikeys hash, with a wiki:apikey-hash:<hash>
+// entry pointing at the key's id for each hash it can be found by
+type RedisStore struct {
+	Client *redis.Client
+}
+
+func (s *RedisStore) Find(hash string) (*wiki_domain.APIKey, error) {
+	id, err := s.Client.Get(context.Background(), redisHashes+hash).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(id)
+}
+
+func (s *RedisStore) Get(id string) (*wiki_domain.APIKey, error) {
+	value, err := s.Client.HGet(context.Background(), redisKeys, id).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var key wiki_domain.APIKey
+	if err := json.Unmarshal([]byte(value), &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *RedisStore) List() ([]*wiki_domain.APIKey, error) {
+	values, err := s.Client.HGetAll(context.Background(), redisKeys).Result()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*wiki_domain.APIKey, 0, len(values))
+	for _, value := range values {
+		var key wiki_domain.APIKey
+		if err := json.Unmarshal([]byte(value), &key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, &key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+// Save replaces the key and its hash entries, the previous hash's entry expires with it
+func (s *RedisStore) Save(key *wiki_domain.APIKey) error {
+	ctx := context.Background()
+	old, err := s.Get(key.ID)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if old != nil {
+			for _, hash := range []string{old.Hash, old.PreviousHash} {
+				if hash != "" && hash != key.Hash && hash != key.PreviousHash {
+					pipe.Del(ctx, redisHashes+hash)
+				}
+			}
+		}
+		pipe.HSet(ctx, redisKeys, key.ID, value)
+		pipe.Set(ctx, redisHashes+key.Hash, key.ID, 0)
+		if key.PreviousHash != "" && key.PreviousExpiresAt != nil {
+			if ttl := time.Until(*key.PreviousExpiresAt); ttl > 0 {
+				pipe.Set(ctx, redisHashes+key.PreviousHash, key.ID, ttl)
+			} else {
+				pipe.Del(ctx, redisHashes+key.PreviousHash)
+			}
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,66 @@
+package wiki_locale
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is served last when none of the caller's languages has a description
+const DefaultLocale = "en"
+
+type weightedTag struct {
+	tag     string
+	quality float64
+	index   int
+}
+
+// ParseAcceptLanguage returns the tags of an Accept-Language header, best quality first.
+// Tags with q=0 and the "*" wildcard are dropped, ties keep the header order.
+func ParseAcceptLanguage(header string) []string {
+	var weighted []weightedTag
+	for index, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.TrimSpace(fields[0])
+		if tag == "" || tag == "*" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+		weighted = append(weighted, weightedTag{tag: tag, quality: quality, index: index})
+	}
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].quality > weighted[j].quality
+	})
+	tags := make([]string, 0, len(weighted))
+	for _, w := range weighted {
+		tags = append(tags, w.tag)
+	}
+	return tags
+}
+
+// Candidates turns the caller's preferred tags into the editions to try in order,
+// skipping unknown tags and duplicates and always ending with fallback
+func (r *Registry) Candidates(tags []string, fallback string) []Locale {
+	var candidates []Locale
+	seen := map[string]bool{}
+	for _, tag := range append(tags, fallback) {
+		locale, err := r.Normalize(tag)
+		if err != nil || seen[locale.Code] {
+			continue
+		}
+		seen[locale.Code] = true
+		candidates = append(candidates, locale)
+	}
+	return candidates
+}
@@ -0,0 +1,21 @@
+package wiki_locale
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.EqualValues(t, []string{"de-CH", "de", "fr", "en"}, ParseAcceptLanguage("fr;q=0.7, de-CH, en;q=0.5, de;q=0.9, *;q=0.1"))
+	assert.EqualValues(t, []string{"pt-BR", "es"}, ParseAcceptLanguage("pt-BR,es, it;q=0"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestCandidates(t *testing.T) {
+	registry := NewRegistry(parseSnapshot(snapshot))
+	candidates := registry.Candidates(ParseAcceptLanguage("de-CH, de;q=0.9, zz;q=0.8, zh-Hant;q=0.5"), DefaultLocale)
+	assert.EqualValues(t, []Locale{{Code: "de"}, {Code: "zh", Variant: "zh-hant"}, {Code: "en"}}, candidates)
+
+	assert.EqualValues(t, []Locale{{Code: "en"}}, registry.Candidates(nil, DefaultLocale))
+}
@@ -0,0 +1,192 @@
+package wiki_locale
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+
+	wiki_client "wiki-names/clients"
+)
+
+const sitematrixUrl = "https://meta.wikimedia.org/w/api.php?action=sitematrix&format=json&formatversion=2&smtype=language&smlangprop=code|site&smsiteprop=url|code"
+
+//go:embed sitematrix_snapshot.txt
+var snapshot string
+
+// aliases maps BCP-47 / ISO 639 codes onto the subdomain Wikipedia actually uses for them
+var aliases = map[string]string{
+	"nb":  "no",
+	"cmn": "zh",
+	"yue": "zh-yue",
+	"nan": "zh-min-nan",
+	"lzh": "zh-classical",
+	"sgs": "bat-smg",
+	"vro": "fiu-vro",
+	"rup": "roa-rup",
+	"gsw": "als",
+	"fil": "tl",
+}
+
+// variants are the script/region tags MediaWiki can convert an edition's text into
+var variants = map[string]string{
+	"zh-hans": "zh-hans",
+	"zh-hant": "zh-hant",
+	"zh-cn":   "zh-cn",
+	"zh-tw":   "zh-tw",
+	"zh-hk":   "zh-hk",
+	"zh-sg":   "zh-sg",
+	"zh-mo":   "zh-mo",
+	"zh-my":   "zh-my",
+	"sr-latn": "sr-el",
+	"sr-cyrl": "sr-ec",
+	"sr-el":   "sr-el",
+	"sr-ec":   "sr-ec",
+}
+
+// Locale is a validated Wikipedia edition with an optional language variant
+type Locale struct {
+	Code    string
+	Variant string
+}
+
+type Registry struct {
+	mu       sync.RWMutex
+	editions map[string]bool
+}
+
+// Locales starts out with the bundled snapshot and is replaced by the live sitematrix once Load succeeds
+var Locales = NewRegistry(parseSnapshot(snapshot))
+
+func NewRegistry(codes []string) *Registry {
+	registry := &Registry{}
+	registry.set(codes)
+	return registry
+}
+
+func (r *Registry) set(codes []string) {
+	editions := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		editions[code] = true
+	}
+	r.mu.Lock()
+	r.editions = editions
+	r.mu.Unlock()
+}
+
+func (r *Registry) has(code string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.editions[code]
+}
+
+// Len is the number of known editions
+func (r *Registry) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.editions)
+}
+
+// Normalize turns a BCP-47 tag such as pt-BR or zh-Hant into a known edition, pt and zh (variant zh-hant)
+func (r *Registry) Normalize(tag string) (Locale, error) {
+	tag = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(tag), "_", "-"))
+	if tag == "" {
+		return Locale{}, fmt.Errorf("empty locale")
+	}
+	subtags := strings.Split(tag, "-")
+	variant := ""
+	for i := len(subtags); i > 1 && variant == ""; i-- {
+		variant = variants[strings.Join(subtags[:i], "-")]
+	}
+	// Try the longest prefix first, so zh-yue and be-tarask win over zh and be
+	for i := len(subtags); i > 0; i-- {
+		code := strings.Join(subtags[:i], "-")
+		if alias, ok := aliases[code]; ok {
+			code = alias
+		}
+		if r.has(code) {
+			return Locale{Code: code, Variant: variant}, nil
+		}
+	}
+	return Locale{}, fmt.Errorf("unknown Wikipedia language edition %q", tag)
+}
+
+// Load replaces the registry with the open Wikipedia editions listed by the sitematrix API
+func (r *Registry) Load() error {
+	response, err := wiki_client.Client.Get(context.Background(), sitematrixUrl)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode > 299 {
+		return fmt.Errorf("sitematrix returned status %d", response.StatusCode)
+	}
+	bytes, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	codes, err := parseSitematrix(bytes)
+	if err != nil {
+		return err
+	}
+	if len(codes) == 0 {
+		return fmt.Errorf("sitematrix listed no Wikipedia editions")
+	}
+	r.set(codes)
+	log.Printf("Loaded %d Wikipedia language editions from sitematrix", len(codes))
+	return nil
+}
+
+type sitematrixLanguage struct {
+	Code string `json:"code"`
+	Site []struct {
+		Url    string `json:"url"`
+		Code   string `json:"code"`
+		Closed bool   `json:"closed"`
+	} `json:"site"`
+}
+
+// parseSitematrix reads the subdomains of the open wikipedias, the matrix is keyed by index plus "count" and "specials"
+func parseSitematrix(body []byte) ([]string, error) {
+	var result struct {
+		Sitematrix map[string]json.RawMessage `json:"sitematrix"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, err
+	}
+	var codes []string
+	for key, raw := range result.Sitematrix {
+		if key == "count" || key == "specials" {
+			continue
+		}
+		var language sitematrixLanguage
+		if err := json.Unmarshal(raw, &language); err != nil {
+			continue
+		}
+		for _, site := range language.Site {
+			if site.Code != "wiki" || site.Closed {
+				continue
+			}
+			if parsed, err := url.Parse(site.Url); err == nil && strings.HasSuffix(parsed.Host, ".wikipedia.org") {
+				codes = append(codes, strings.TrimSuffix(parsed.Host, ".wikipedia.org"))
+			}
+		}
+	}
+	return codes, nil
+}
+
+func parseSnapshot(text string) []string {
+	var codes []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			codes = append(codes, line)
+		}
+	}
+	return codes
+}
@@ -0,0 +1,100 @@
+package wiki_locale
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	wiki_client "wiki-names/clients"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var getMockFunc func(url string) (*http.Response, error)
+
+type getClientMock struct{}
+
+// We are mocking the client method "Get"
+func (cm *getClientMock) Get(ctx context.Context, request string) (*http.Response, error) {
+	return getMockFunc(request)
+}
+
+func TestNormalize(t *testing.T) {
+	registry := NewRegistry(parseSnapshot(snapshot))
+	tests := []struct {
+		tag     string
+		code    string
+		variant string
+	}{
+		{"en", "en", ""},
+		{"pt-BR", "pt", ""},
+		{"pt_br", "pt", ""},
+		{"zh-Hant", "zh", "zh-hant"},
+		{"zh-Hant-TW", "zh", "zh-hant"},
+		{"zh-TW", "zh", "zh-tw"},
+		{"zh-yue", "zh-yue", ""},
+		{"yue-HK", "zh-yue", ""},
+		{"sr-Latn", "sr", "sr-el"},
+		{"nb-NO", "no", ""},
+		{"be-tarask", "be-tarask", ""},
+		{"simple", "simple", ""},
+		{"DE-ch", "de", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			locale, err := registry.Normalize(tt.tag)
+			assert.Nil(t, err)
+			assert.EqualValues(t, tt.code, locale.Code)
+			assert.EqualValues(t, tt.variant, locale.Variant)
+		})
+	}
+}
+
+func TestNormalizeUnknown(t *testing.T) {
+	registry := NewRegistry(parseSnapshot(snapshot))
+	for _, tag := range []string{"", "enn", "xx-YY", "evil.example.com", "en.evil"} {
+		_, err := registry.Normalize(tag)
+		assert.NotNil(t, err, tag)
+	}
+}
+
+func TestLoadSitematrix(t *testing.T) {
+	getMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: io.NopCloser(strings.NewReader(`{"sitematrix":{"count":3,
+				"0":{"code":"aa","site":[{"url":"https://aa.wikipedia.org","code":"wiki","closed":true}]},
+				"1":{"code":"en","site":[{"url":"https://en.wikipedia.org","code":"wiki"},{"url":"https://en.wiktionary.org","code":"wiktionary"}]},
+				"2":{"code":"zh-min-nan","site":[{"url":"https://zh-min-nan.wikipedia.org","code":"wiki"}]},
+				"specials":[{"url":"https://meta.wikimedia.org","code":"meta"}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	registry := NewRegistry(nil)
+	assert.Nil(t, registry.Load())
+	assert.EqualValues(t, 2, registry.Len())
+
+	locale, err := registry.Normalize("nan")
+	assert.Nil(t, err)
+	assert.EqualValues(t, "zh-min-nan", locale.Code)
+	_, err = registry.Normalize("aa")
+	assert.NotNil(t, err)
+}
+
+func TestLoadSitematrixKeepsSnapshotOnError(t *testing.T) {
+	getMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusServiceUnavailable,
+			Body:       io.NopCloser(strings.NewReader(``)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	registry := NewRegistry(parseSnapshot(snapshot))
+	before := registry.Len()
+	assert.NotNil(t, registry.Load())
+	assert.EqualValues(t, before, registry.Len())
+}
@@ -0,0 +1,45 @@
+package wiki_middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// AdminToken lets through the requests carrying "Authorization: Bearer <token>", or a JWT granting
+// the admin scope when JWT runs before it. With no token configured the admin routes are closed to
+// everyone but those JWTs.
+func AdminToken(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claimsFrom(c) != nil {
+			RequireScope(wiki_domain.ScopeAdmin)(c)
+			return
+		}
+		given := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if token == "" {
+			abortWithProblem(c, wiki_domain.NewForbiddenError("the admin API is disabled, set ADMIN_TOKEN to enable it"))
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="admin"`)
+			abortWithProblem(c, wiki_domain.NewWikiError(http.StatusUnauthorized, "a valid admin token is required"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// abortWithProblem renders the error as problem+json like the controllers do, and stops the chain
+func abortWithProblem(c *gin.Context, apiError wiki_domain.WikiErrorInterface) {
+	problem := wiki_domain.NewProblemDetails(apiError, c.Request.URL.RequestURI(), c.GetString(RequestIDKey))
+	if problem.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(problem.RetryAfter))
+	}
+	c.Header("Content-Type", wiki_domain.ProblemContentType)
+	c.AbortWithStatusJSON(problem.Status, problem)
+}
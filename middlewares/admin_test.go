@@ -0,0 +1,38 @@
+package wiki_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveAdmin(token string, authorization string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin", AdminToken(token), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	request := httptest.NewRequest(http.MethodGet, "/admin", nil)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAdminToken(t *testing.T) {
+	assert.EqualValues(t, http.StatusOK, serveAdmin("s3cret", "Bearer s3cret").Code)
+
+	recorder := serveAdmin("s3cret", "Bearer wrong")
+	assert.EqualValues(t, http.StatusUnauthorized, recorder.Code)
+	assert.EqualValues(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.NotEmpty(t, recorder.Header().Get("WWW-Authenticate"))
+	assert.EqualValues(t, http.StatusUnauthorized, serveAdmin("s3cret", "").Code)
+
+	// No token configured closes the admin routes, even to an empty bearer token
+	assert.EqualValues(t, http.StatusForbidden, serveAdmin("", "Bearer ").Code)
+}
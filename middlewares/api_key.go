@@ -0,0 +1,61 @@
+package wiki_middleware
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+)
+
+const (
+	APIKeyHeader = "X-API-Key"
+	// APIKeyContextKey holds the *wiki_domain.APIKey of the request, when it came with one
+	APIKeyContextKey = "api_key"
+)
+
+// APIKey authenticates the key sent in X-API-Key, or as a Bearer token starting with wk_, and
+// counts the request against the key's limits, reporting what is left in the RateLimit-* headers.
+// Requests without a key are let through unless required is set. Internal requests are never
+// required a key, and the key they send only picks their tenant, it isn't counted.
+func APIKey(keys *wiki_key.Manager, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.GetHeader(APIKeyHeader)
+		if bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token == "" && strings.HasPrefix(bearer, wiki_key.KeyPrefix) {
+			token = bearer
+		}
+		if token == "" {
+			if required && !isInternal(c) {
+				abortWithProblem(c, wiki_domain.NewTypedError(wiki_domain.ErrInvalidAPIKey, "an API key is required, send it in "+APIKeyHeader))
+				return
+			}
+			c.Next()
+			return
+		}
+		key, apiError := keys.Authenticate(token)
+		if apiError != nil {
+			abortWithProblem(c, apiError)
+			return
+		}
+		c.Set(APIKeyContextKey, key)
+		if isInternal(c) {
+			c.Next()
+			return
+		}
+		limits, apiError := keys.Allow(key)
+		if limits != nil {
+			c.Header("RateLimit-Limit", strconv.Itoa(limits.Limit))
+			c.Header("RateLimit-Remaining", strconv.Itoa(limits.Remaining))
+			c.Header("RateLimit-Reset", strconv.Itoa(int(math.Ceil(limits.Reset.Seconds()))))
+			c.Header("RateLimit-Policy", limits.Policy)
+		}
+		if apiError != nil {
+			abortWithProblem(c, apiError)
+			return
+		}
+		c.Next()
+	}
+}
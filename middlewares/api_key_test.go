@@ -0,0 +1,79 @@
+package wiki_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+)
+
+func setupKeys(t *testing.T, required bool) (func(header string, value string) *httptest.ResponseRecorder, *wiki_key.Manager) {
+	store, err := wiki_key.NewFileStore(filepath.Join(t.TempDir(), "keys.json"))
+	assert.Nil(t, err)
+	keys := wiki_key.NewManager(store, wiki_key.NewMemoryCounters())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/search", APIKey(keys, required), func(c *gin.Context) {
+		tenant := ""
+		if key, ok := c.Get(APIKeyContextKey); ok {
+			tenant = key.(*wiki_domain.APIKey).Tenant
+		}
+		c.String(http.StatusOK, tenant)
+	})
+	return func(header string, value string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/search", nil)
+		if header != "" {
+			request.Header.Set(header, value)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}, keys
+}
+
+func TestAPIKey(t *testing.T) {
+	serve, keys := setupKeys(t, false)
+	limit := 2
+	issued, _ := keys.Issue(wiki_domain.APIKeyRequest{Tenant: "acme", RateLimit: &limit})
+
+	recorder := serve(APIKeyHeader, issued.Key)
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.EqualValues(t, "acme", recorder.Body.String())
+	assert.EqualValues(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	assert.EqualValues(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	assert.NotEmpty(t, recorder.Header().Get("RateLimit-Reset"))
+	assert.EqualValues(t, "2;w=60, 10000;w=86400", recorder.Header().Get("RateLimit-Policy"))
+
+	assert.EqualValues(t, http.StatusOK, serve("Authorization", "Bearer "+issued.Key).Code)
+	recorder = serve(APIKeyHeader, issued.Key)
+	assert.EqualValues(t, http.StatusTooManyRequests, recorder.Code)
+	assert.EqualValues(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"))
+	assert.EqualValues(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+	assert.EqualValues(t, http.StatusUnauthorized, serve(APIKeyHeader, "wk_nope").Code)
+	// Without a key, or with a Bearer token that isn't one, the request is anonymous
+	assert.EqualValues(t, http.StatusOK, serve("", "").Code)
+	recorder = serve("Authorization", "Bearer some.jwt.token")
+	assert.EqualValues(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+}
+
+func TestAPIKeyRequired(t *testing.T) {
+	serve, keys := setupKeys(t, true)
+	issued, _ := keys.Issue(wiki_domain.APIKeyRequest{})
+
+	assert.EqualValues(t, http.StatusUnauthorized, serve("", "").Code)
+	assert.EqualValues(t, http.StatusUnauthorized, serve("Authorization", "Bearer some.jwt.token").Code)
+	assert.EqualValues(t, http.StatusOK, serve(APIKeyHeader, issued.Key).Code)
+
+	assert.Nil(t, keys.Revoke(issued.ID))
+	assert.EqualValues(t, http.StatusUnauthorized, serve(APIKeyHeader, issued.Key).Code)
+}
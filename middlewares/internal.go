@@ -0,0 +1,21 @@
+package wiki_middleware
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+type internalKey struct{}
+
+// Internal marks a request the service sends to its own router, such as a hot names warm-up. It
+// needs no credentials and isn't counted against any rate limit or quota. The mark lives in the
+// request context, which a client can't set.
+func Internal(ctx context.Context) context.Context {
+	return context.WithValue(ctx, internalKey{}, true)
+}
+
+func isInternal(c *gin.Context) bool {
+	internal, _ := c.Request.Context().Value(internalKey{}).(bool)
+	return internal
+}
@@ -0,0 +1,60 @@
+package wiki_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+)
+
+func TestInternal(t *testing.T) {
+	store, err := wiki_key.NewFileStore(filepath.Join(t.TempDir(), "keys.json"))
+	assert.Nil(t, err)
+	keys := wiki_key.NewManager(store, wiki_key.NewMemoryCounters())
+	limit := 1
+	issued, _ := keys.Issue(wiki_domain.APIKeyRequest{Tenant: "acme", RateLimit: &limit})
+	limiter := NewRateLimiter(wiki_key.NewMemoryCounters(), LimitByIP)
+	limiter.Default = RateLimit{Requests: 1, Window: time.Minute}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/search", APIKey(keys, true), limiter.Handler(), RequireScope(wiki_domain.ScopeLookupRead), func(c *gin.Context) {
+		tenant := ""
+		if key, ok := c.Get(APIKeyContextKey); ok {
+			tenant = key.(*wiki_domain.APIKey).Tenant
+		}
+		c.String(http.StatusOK, tenant)
+	})
+	serve := func(internal bool, key string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/search", nil)
+		if internal {
+			request = request.WithContext(Internal(request.Context()))
+		}
+		if key != "" {
+			request.Header.Set(APIKeyHeader, key)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Internal requests need no key, and the key they send picks the tenant without being counted
+	for i := 0; i < 3; i++ {
+		assert.EqualValues(t, http.StatusOK, serve(true, "").Code)
+		recorder := serve(true, issued.Key)
+		assert.EqualValues(t, http.StatusOK, recorder.Code)
+		assert.EqualValues(t, "acme", recorder.Body.String())
+	}
+	assert.EqualValues(t, http.StatusOK, serve(false, issued.Key).Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, serve(false, issued.Key).Code)
+	assert.EqualValues(t, http.StatusUnauthorized, serve(false, "").Code)
+	// A bad key is still refused
+	assert.EqualValues(t, http.StatusUnauthorized, serve(true, "wk_nope").Code)
+}
@@ -0,0 +1,90 @@
+package wiki_middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+	wiki_token "wiki-names/tokens"
+)
+
+// ClaimsContextKey holds the *wiki_domain.Claims of the request, when it came with a valid bearer token
+const ClaimsContextKey = "claims"
+
+// JWT validates the bearer token of the request when it is a JWT. API keys and the admin token,
+// which aren't, are left to their own middleware, as are requests without a token.
+func JWT(verifier *wiki_token.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := bearerToken(c)
+		if token == "" || strings.HasPrefix(token, wiki_key.KeyPrefix) || strings.Count(token, ".") != 2 {
+			c.Next()
+			return
+		}
+		claims, apiError := verifier.Verify(token)
+		if apiError != nil {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			abortWithProblem(c, apiError)
+			return
+		}
+		c.Set(ClaimsContextKey, claims)
+		c.Next()
+	}
+}
+
+// RequireScope lets through the requests whose bearer token grants the scope. A request made with
+// an API key instead is let through too, keys are limited by the API key middleware, but never to
+// the admin scope. Internal requests are let through to any scope but admin. Run it after JWT and APIKey.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isInternal(c) && scope != wiki_domain.ScopeAdmin {
+			c.Next()
+			return
+		}
+		var key *wiki_domain.APIKey
+		if value, ok := c.Get(APIKeyContextKey); ok {
+			key = value.(*wiki_domain.APIKey)
+		}
+		apiError := CheckScope(scope, key, claimsFrom(c))
+		if apiError == nil {
+			c.Next()
+			return
+		}
+		if apiError.ErrorCode == wiki_domain.ErrInsufficientScope {
+			c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		} else {
+			c.Header("WWW-Authenticate", `Bearer scope="`+scope+`"`)
+		}
+		abortWithProblem(c, apiError)
+	}
+}
+
+// CheckScope is RequireScope's check of the claims, or of the key without them
+func CheckScope(scope string, key *wiki_domain.APIKey, claims *wiki_domain.Claims) *wiki_domain.WikiError {
+	if claims != nil {
+		if !claims.HasScope(scope) {
+			return wiki_domain.NewTypedError(wiki_domain.ErrInsufficientScope, "the token doesn't grant "+scope)
+		}
+		return nil
+	}
+	if key != nil && scope != wiki_domain.ScopeAdmin {
+		return nil
+	}
+	return wiki_domain.NewTypedError(wiki_domain.ErrInvalidToken, "a bearer token or API key is required")
+}
+
+func claimsFrom(c *gin.Context) *wiki_domain.Claims {
+	if value, ok := c.Get(ClaimsContextKey); ok {
+		return value.(*wiki_domain.Claims)
+	}
+	return nil
+}
+
+func bearerToken(c *gin.Context) string {
+	authorization := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authorization, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(authorization, "Bearer ")
+}
@@ -0,0 +1,118 @@
+package wiki_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_tenant "wiki-names/tenants"
+	wiki_token "wiki-names/tokens"
+)
+
+// serveScoped runs a request through RequireScope, as if the earlier middleware had found the claims or key
+func serveScoped(scope string, claims *wiki_domain.Claims, key *wiki_domain.APIKey, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	chain := []gin.HandlerFunc{func(c *gin.Context) {
+		if claims != nil {
+			c.Set(ClaimsContextKey, claims)
+		}
+		if key != nil {
+			c.Set(APIKeyContextKey, key)
+		}
+	}}
+	if len(handlers) == 0 {
+		handlers = []gin.HandlerFunc{RequireScope(scope)}
+	}
+	chain = append(chain, handlers...)
+	chain = append(chain, func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	router.GET("/search", chain...)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/search", nil))
+	return recorder
+}
+
+func TestRequireScope(t *testing.T) {
+	reader := &wiki_domain.Claims{Subject: "reports", Scopes: []string{wiki_domain.ScopeLookupRead}}
+	assert.EqualValues(t, http.StatusOK, serveScoped(wiki_domain.ScopeLookupRead, reader, nil).Code)
+
+	recorder := serveScoped(wiki_domain.ScopeBatchWrite, reader, nil)
+	assert.EqualValues(t, http.StatusForbidden, recorder.Code)
+	assert.EqualValues(t, `Bearer error="insufficient_scope", scope="batch:write"`, recorder.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, recorder.Body.String(), "insufficient_scope")
+
+	// An API key is enough, except for the admin scope
+	key := &wiki_domain.APIKey{ID: "1"}
+	assert.EqualValues(t, http.StatusOK, serveScoped(wiki_domain.ScopeBatchWrite, nil, key).Code)
+	assert.EqualValues(t, http.StatusUnauthorized, serveScoped(wiki_domain.ScopeAdmin, nil, key).Code)
+
+	recorder = serveScoped(wiki_domain.ScopeLookupRead, nil, nil)
+	assert.EqualValues(t, http.StatusUnauthorized, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "invalid_token")
+}
+
+func TestAdminTokenScope(t *testing.T) {
+	admin := &wiki_domain.Claims{Subject: "ops", Scopes: []string{wiki_domain.ScopeAdmin}}
+	assert.EqualValues(t, http.StatusOK, serveScoped("", admin, nil, AdminToken("")).Code)
+	reader := &wiki_domain.Claims{Subject: "reports", Scopes: []string{wiki_domain.ScopeLookupRead}}
+	assert.EqualValues(t, http.StatusForbidden, serveScoped("", reader, nil, AdminToken("s3cret")).Code)
+}
+
+func TestTenantFromClaims(t *testing.T) {
+	tenants := wiki_tenant.NewRegistry()
+	assert.Nil(t, tenants.Set(&wiki_domain.Tenant{ID: "acme", DefaultLocale: "fr"}))
+	var tenant *wiki_domain.Tenant
+	serveScoped("", &wiki_domain.Claims{Tenant: "acme"}, nil, Tenant(tenants), func(c *gin.Context) {
+		tenant = wiki_domain.TenantFrom(c.Request.Context())
+	})
+	assert.EqualValues(t, "fr", tenant.DefaultLocale)
+
+	// The API key's tenant wins over the token's
+	serveScoped("", &wiki_domain.Claims{Tenant: "acme"}, &wiki_domain.APIKey{ID: "1", Tenant: "globex"}, Tenant(tenants), func(c *gin.Context) {
+		tenant = wiki_domain.TenantFrom(c.Request.Context())
+	})
+	assert.EqualValues(t, "globex", tenant.ID)
+}
+
+func TestJWT(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{"keys":[]}`), 0o600))
+	verifier := wiki_token.NewVerifier(wiki_token.NewKeySet("", path), "https://id.example.com", "wiki-names")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/search", JWT(verifier), func(c *gin.Context) {
+		_, ok := c.Get(ClaimsContextKey)
+		assert.False(t, ok)
+		c.String(http.StatusOK, "ok")
+	})
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/search", nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Requests without a JWT are left to the other middleware
+	assert.EqualValues(t, http.StatusOK, serve("").Code)
+	assert.EqualValues(t, http.StatusOK, serve("Bearer wk_0123.abc.def").Code)
+	assert.EqualValues(t, http.StatusOK, serve("Bearer s3cret").Code)
+	assert.EqualValues(t, http.StatusOK, serve("Basic a.b.c").Code)
+
+	recorder := serve("Bearer eyJhbGciOiJub25lIn0.e30.")
+	assert.EqualValues(t, http.StatusUnauthorized, recorder.Code)
+	assert.EqualValues(t, `Bearer error="invalid_token"`, recorder.Header().Get("WWW-Authenticate"))
+	assert.EqualValues(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "invalid_token")
+}
@@ -0,0 +1,208 @@
+package wiki_middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+)
+
+// What the rate limiter tells clients apart by
+const (
+	LimitByIP        = "ip"
+	LimitByAPIKey    = "api_key"
+	LimitByForwarded = "forwarded"
+)
+
+// RateLimit allows Requests in any Window, 0 requests is unlimited
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimiter counts each client's requests to each route in a sliding window. The window is
+// estimated from two fixed ones: the current window's count plus the previous one's, weighted
+// by how much of it still overlaps, which is what a real sliding log gives within a few percent.
+// Rejected requests are counted too, a client that keeps hammering stays locked out.
+type RateLimiter struct {
+	Counters wiki_key.Counters
+	// By is LimitByIP, LimitByAPIKey (the token's subject for bearer tokens, the IP for requests
+	// with neither) or LimitByForwarded
+	By string
+	// TrustedHops is how many proxies of ours add to X-Forwarded-For, the client is the address
+	// the furthest of them saw. Anything further left is whatever the client sent.
+	TrustedHops int
+	Default     RateLimit
+	// Routes overrides Default by route, as registered: /search/:name, /search/batch
+	Routes map[string]RateLimit
+
+	now func() time.Time
+}
+
+func NewRateLimiter(counters wiki_key.Counters, by string) *RateLimiter {
+	return &RateLimiter{
+		Counters:    counters,
+		By:          by,
+		TrustedHops: 1,
+		Default:     RateLimit{Requests: 120, Window: time.Minute},
+		Routes:      map[string]RateLimit{"/search/batch": {Requests: 10, Window: time.Minute}},
+		now:         time.Now,
+	}
+}
+
+// Handler rejects the requests over their route's limit with a 429 and a Retry-After. Run it after
+// APIKey to limit by key. Like the key limits, the counters failing lets the request through.
+// Internal requests aren't limited.
+func (l *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if isInternal(c) {
+			c.Next()
+			return
+		}
+		if apiError := l.Check(c.FullPath(), l.client(c)); apiError != nil {
+			abortWithProblem(c, apiError)
+			return
+		}
+		c.Next()
+	}
+}
+
+// Check counts a request of the client to the route, and returns a rate_limited error with a retry
+// hint when it is over the route's limit. The gRPC interceptors count their calls with it too.
+func (l *RateLimiter) Check(route string, client string) *wiki_domain.WikiError {
+	limit, ok := l.Routes[route]
+	if !ok {
+		limit = l.Default
+	}
+	if limit.Requests <= 0 || limit.Window <= 0 {
+		return nil
+	}
+	retryAfter, err := l.allow(route, client, limit)
+	if err != nil {
+		log.Printf("Rate limit: failed to count a request to %s: %s", route, err.Error())
+		return nil
+	}
+	if retryAfter > 0 {
+		apiError := wiki_domain.NewTypedError(wiki_domain.ErrRateLimited, fmt.Sprintf("%s is limited to %d requests every %s", route, limit.Requests, limit.Window))
+		apiError.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+		return apiError
+	}
+	return nil
+}
+
+// allow counts the request and returns how long the client should wait when it is over the limit
+func (l *RateLimiter) allow(route string, client string, limit RateLimit) (time.Duration, error) {
+	now := l.now()
+	start := now.Truncate(limit.Window)
+	name := "ratelimit:" + route + ":" + client + ":"
+	current, err := l.Counters.Incr(name+strconv.FormatInt(start.UnixNano(), 36), 2*limit.Window)
+	if err != nil {
+		return 0, err
+	}
+	previous, err := l.Counters.Get(name + strconv.FormatInt(start.Add(-limit.Window).UnixNano(), 36))
+	if err != nil {
+		return 0, err
+	}
+	elapsed := float64(now.Sub(start)) / float64(limit.Window)
+	allowed := float64(limit.Requests)
+	if float64(previous)*(1-elapsed)+float64(current) <= allowed {
+		return 0, nil
+	}
+	// Wait until the next request fits: once enough of the previous window has slid out when this
+	// window has room, otherwise into the next window, for enough of this one to slide out
+	next := float64(current) + 1
+	var until float64
+	if next <= allowed && previous > 0 {
+		until = 1 - (allowed-next)/float64(previous)
+	} else {
+		until = 1 + math.Max(0, 1-(allowed-1)/float64(current))
+	}
+	wait := time.Duration((until - elapsed) * float64(limit.Window))
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return wait, nil
+}
+
+// client is who the request is counted against
+func (l *RateLimiter) client(c *gin.Context) string {
+	var key *wiki_domain.APIKey
+	if value, ok := c.Get(APIKeyContextKey); ok {
+		key = value.(*wiki_domain.APIKey)
+	}
+	ip := c.RemoteIP()
+	if l.By == LimitByForwarded {
+		if hops := forwardedHops(c.GetHeader("X-Forwarded-For")); l.TrustedHops > 0 && len(hops) >= l.TrustedHops {
+			ip = hops[len(hops)-l.TrustedHops]
+		}
+	}
+	return l.Client(key, claimsFrom(c), ip)
+}
+
+// Client is who a request from ip, made with the key or the claims when it has them, is counted against
+func (l *RateLimiter) Client(key *wiki_domain.APIKey, claims *wiki_domain.Claims, ip string) string {
+	if l.By == LimitByAPIKey {
+		if key != nil {
+			return "key:" + key.ID
+		}
+		if claims != nil && claims.Subject != "" {
+			return "sub:" + claims.Subject
+		}
+	}
+	return "ip:" + ip
+}
+
+func forwardedHops(header string) []string {
+	var hops []string
+	for _, hop := range strings.Split(header, ",") {
+		if hop = strings.TrimSpace(hop); net.ParseIP(hop) != nil {
+			hops = append(hops, hop)
+		}
+	}
+	return hops
+}
+
+// ParseRateLimits reads route limits written as /search/batch=10/1m,/search/:name=100/1m
+func ParseRateLimits(value string) (map[string]RateLimit, error) {
+	limits := map[string]RateLimit{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%q should be route=requests/window", entry)
+		}
+		limit, err := ParseRateLimit(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(parts[0])] = limit
+	}
+	return limits, nil
+}
+
+// ParseRateLimit reads a limit written as requests/window, e.g. 100/1m
+func ParseRateLimit(value string) (RateLimit, error) {
+	parts := strings.SplitN(strings.TrimSpace(value), "/", 2)
+	if len(parts) != 2 {
+		return RateLimit{}, fmt.Errorf("%q should be requests/window, e.g. 100/1m", value)
+	}
+	limit := RateLimit{}
+	var err error
+	if limit.Requests, err = strconv.Atoi(parts[0]); err != nil || limit.Requests < 0 {
+		return RateLimit{}, fmt.Errorf("%q should start with a number of requests", value)
+	}
+	if limit.Window, err = time.ParseDuration(parts[1]); err != nil || limit.Window <= 0 {
+		return RateLimit{}, fmt.Errorf("%q should end with a window such as 1m", value)
+	}
+	return limit, nil
+}
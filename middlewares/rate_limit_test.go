@@ -0,0 +1,134 @@
+package wiki_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+)
+
+func setupLimiter(t *testing.T, limiter *RateLimiter) (func(target string, forwarded string) *httptest.ResponseRecorder, *time.Time) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" {
+			c.Set(APIKeyContextKey, &wiki_domain.APIKey{ID: key})
+		}
+	}, limiter.Handler())
+	router.GET("/search/:name", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.POST("/search/batch", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	return func(target string, header string) *httptest.ResponseRecorder {
+		method := http.MethodGet
+		if target == "/search/batch" {
+			method = http.MethodPost
+		}
+		request := httptest.NewRequest(method, target, nil)
+		request.RemoteAddr = "10.0.0.1:1234"
+		if header != "" {
+			request.Header.Set("X-Forwarded-For", header)
+			request.Header.Set(APIKeyHeader, header)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}, &now
+}
+
+func TestRateLimitPerRoute(t *testing.T) {
+	limiter := NewRateLimiter(wiki_key.NewMemoryCounters(), LimitByIP)
+	limiter.Default = RateLimit{Requests: 3, Window: time.Minute}
+	limiter.Routes["/search/batch"] = RateLimit{Requests: 1, Window: time.Minute}
+	serve, _ := setupLimiter(t, limiter)
+
+	assert.EqualValues(t, http.StatusOK, serve("/search/batch", "").Code)
+	recorder := serve("/search/batch", "")
+	assert.EqualValues(t, http.StatusTooManyRequests, recorder.Code)
+	assert.EqualValues(t, wiki_domain.ProblemContentType, recorder.Header().Get("Content-Type"))
+	// The request it let through only slides out at the end of the next window
+	assert.EqualValues(t, "120", recorder.Header().Get("Retry-After"))
+
+	// Each route has its own count, and every name shares /search/:name's
+	for _, name := range []string{"a", "b", "c"} {
+		assert.EqualValues(t, http.StatusOK, serve("/search/"+name, "").Code)
+	}
+	assert.EqualValues(t, http.StatusTooManyRequests, serve("/search/d", "").Code)
+}
+
+func TestRateLimitSlidingWindow(t *testing.T) {
+	limiter := NewRateLimiter(wiki_key.NewMemoryCounters(), LimitByIP)
+	limiter.Default = RateLimit{Requests: 4, Window: time.Minute}
+	serve, now := setupLimiter(t, limiter)
+
+	for i := 0; i < 4; i++ {
+		assert.EqualValues(t, http.StatusOK, serve("/search/a", "").Code)
+	}
+	// A quarter into the next window, three quarters of the previous four still count
+	*now = now.Add(75 * time.Second)
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "").Code)
+	recorder := serve("/search/a", "")
+	assert.EqualValues(t, http.StatusTooManyRequests, recorder.Code)
+	// Rejected requests count too, so there is room again once the previous window's share is down to one
+	assert.EqualValues(t, "30", recorder.Header().Get("Retry-After"))
+
+	*now = now.Add(30 * time.Second)
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "").Code)
+}
+
+func TestRateLimitClients(t *testing.T) {
+	limiter := NewRateLimiter(wiki_key.NewMemoryCounters(), LimitByForwarded)
+	limiter.Default = RateLimit{Requests: 1, Window: time.Minute}
+	serve, _ := setupLimiter(t, limiter)
+
+	// The hop our proxy added is the client, whatever else the client put in front of it
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "1.1.1.1, 203.0.113.7").Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, serve("/search/a", "2.2.2.2, 203.0.113.7").Code)
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "203.0.113.8").Code)
+	// Without the header it is the connection's address
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "").Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, serve("/search/a", "").Code)
+
+	limiter.By = LimitByAPIKey
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "key-one").Code)
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "key-two").Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, serve("/search/a", "key-one").Code)
+}
+
+func TestRateLimitRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	limiter := NewRateLimiter(&wiki_key.RedisCounters{Client: client}, LimitByIP)
+	limiter.Default = RateLimit{Requests: 1, Window: time.Minute}
+	serve, _ := setupLimiter(t, limiter)
+
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "").Code)
+	assert.EqualValues(t, http.StatusTooManyRequests, serve("/search/a", "").Code)
+
+	// Redis being down lets requests through
+	server.Close()
+	assert.EqualValues(t, http.StatusOK, serve("/search/a", "").Code)
+}
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits("/search/batch=10/1m, /search/:name=100/30s,")
+	assert.Nil(t, err)
+	assert.EqualValues(t, map[string]RateLimit{
+		"/search/batch": {Requests: 10, Window: time.Minute},
+		"/search/:name": {Requests: 100, Window: 30 * time.Second},
+	}, limits)
+
+	for _, bad := range []string{"/search", "/search=10", "/search=x/1m", "/search=10/never", "/search=10/0s"} {
+		_, err := ParseRateLimits(bad)
+		assert.NotNil(t, err, bad)
+	}
+}
@@ -0,0 +1,30 @@
+package wiki_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, c.GetString(RequestIDKey))
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Len(t, recorder.Header().Get(RequestIDHeader), 32)
+	assert.EqualValues(t, recorder.Header().Get(RequestIDHeader), recorder.Body.String())
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set(RequestIDHeader, "from-the-caller")
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.EqualValues(t, "from-the-caller", recorder.Header().Get(RequestIDHeader))
+}
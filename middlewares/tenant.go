@@ -0,0 +1,42 @@
+package wiki_middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	wiki_domain "wiki-names/domains"
+	wiki_tenant "wiki-names/tenants"
+)
+
+// Tenant puts the tenant of the request's API key, or of its bearer token, in the request context,
+// where the handlers and providers serving it read their settings from. Run it after APIKey and
+// JWT. A tenant missing from the
+// registry gets the defaults, so issuing a key for a new tenant doesn't have to wait on a deploy.
+func Tenant(tenants *wiki_tenant.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var key *wiki_domain.APIKey
+		if value, ok := c.Get(APIKeyContextKey); ok {
+			key = value.(*wiki_domain.APIKey)
+		}
+		if tenant := ResolveTenant(tenants, key, claimsFrom(c)); tenant != nil {
+			c.Request = c.Request.WithContext(wiki_domain.WithTenant(c.Request.Context(), tenant))
+		}
+		c.Next()
+	}
+}
+
+// ResolveTenant is the tenant of the key, or of the claims without one, nil when neither has a tenant
+func ResolveTenant(tenants *wiki_tenant.Registry, key *wiki_domain.APIKey, claims *wiki_domain.Claims) *wiki_domain.Tenant {
+	var id string
+	if key != nil {
+		id = key.Tenant
+	} else if claims != nil {
+		id = claims.Tenant
+	}
+	if id == "" {
+		return nil
+	}
+	if tenant := tenants.Get(id); tenant != nil {
+		return tenant
+	}
+	return &wiki_domain.Tenant{ID: id}
+}
@@ -0,0 +1,39 @@
+package wiki_middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_tenant "wiki-names/tenants"
+)
+
+func serveTenant(tenants *wiki_tenant.Registry, key *wiki_domain.APIKey) *wiki_domain.Tenant {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var tenant *wiki_domain.Tenant
+	router.GET("/search", func(c *gin.Context) {
+		if key != nil {
+			c.Set(APIKeyContextKey, key)
+		}
+	}, Tenant(tenants), func(c *gin.Context) {
+		tenant = wiki_domain.TenantFrom(c.Request.Context())
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/search", nil))
+	return tenant
+}
+
+func TestTenant(t *testing.T) {
+	tenants := wiki_tenant.NewRegistry()
+	assert.Nil(t, tenants.Set(&wiki_domain.Tenant{ID: "acme", DefaultLocale: "fr"}))
+
+	assert.EqualValues(t, "fr", serveTenant(tenants, &wiki_domain.APIKey{ID: "1", Tenant: "acme"}).DefaultLocale)
+	// A tenant that isn't configured gets the defaults
+	assert.EqualValues(t, &wiki_domain.Tenant{ID: "globex"}, serveTenant(tenants, &wiki_domain.APIKey{ID: "2", Tenant: "globex"}))
+	assert.Nil(t, serveTenant(tenants, &wiki_domain.APIKey{ID: "3"}))
+	assert.Nil(t, serveTenant(tenants, nil))
+}
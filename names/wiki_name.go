@@ -0,0 +1,113 @@
+package wiki_name
+
+import (
+	"regexp"
+	"strings"
+
+	wiki_domain "wiki-names/domains"
+)
+
+const (
+	SourceRules    = "rules"
+	SourceWikidata = "wikidata"
+)
+
+var disambiguatorRe = regexp.MustCompile(`^(.*?)\s*\(([^()]+)\)$`)
+
+// particles are the lower case words that start a family name ("van Beethoven", "de la Cruz")
+var particles = map[string]bool{
+	"van": true, "von": true, "der": true, "den": true, "de": true, "del": true, "della": true, "dei": true,
+	"di": true, "da": true, "do": true, "dos": true, "das": true, "du": true, "la": true, "le": true,
+	"les": true, "ter": true, "ten": true, "zu": true, "bin": true, "ibn": true, "bint": true, "al": true,
+	"el": true, "y": true, "af": true, "av": true, "op": true, "het": true, "'t": true, "st.": true,
+}
+
+// hyphenParticles are written joined to the family name, as in al-Khwarizmi
+var hyphenParticles = []string{"al-", "el-", "ad-", "an-", "ar-", "as-", "at-", "az-", "ash-"}
+
+var suffixes = map[string]bool{
+	"jr": true, "jr.": true, "sr": true, "sr.": true, "ii": true, "iii": true, "iv": true,
+	"junior": true, "senior": true,
+}
+
+// Parse splits a page title such as Ludwig_van_Beethoven or "Martin Luther King Jr." using rules only
+func Parse(title string) *wiki_domain.PersonName {
+	name := &wiki_domain.PersonName{
+		Given:     []string{},
+		Particles: []string{},
+		Family:    []string{},
+		Suffixes:  []string{},
+		Source:    SourceRules,
+	}
+	title = strings.TrimSpace(strings.ReplaceAll(title, "_", " "))
+	if matches := disambiguatorRe.FindStringSubmatch(title); matches != nil {
+		title, name.Disambiguator = matches[1], matches[2]
+	}
+
+	tokens := strings.Fields(strings.ReplaceAll(title, ",", " "))
+	for len(tokens) > 1 && suffixes[strings.ToLower(tokens[len(tokens)-1])] {
+		name.Suffixes = append([]string{tokens[len(tokens)-1]}, name.Suffixes...)
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return name
+	}
+	if len(tokens) == 1 {
+		// Mononyms such as "Pelé" or "Aristotle" only have a given name
+		name.Given = append(name.Given, tokens[0])
+		return name
+	}
+
+	// The family name starts at the first particle after the given name, or is the last word
+	familyStart := len(tokens) - 1
+	for i := 1; i < len(tokens)-1; i++ {
+		if strings.ToLower(tokens[i]) == "y" {
+			// Spanish "y" joins two family names, as in Ortega y Gasset
+			familyStart = i - 1
+			break
+		}
+		if particles[strings.ToLower(tokens[i])] {
+			familyStart = i
+			break
+		}
+	}
+	name.Given = append(name.Given, tokens[:familyStart]...)
+	splitFamily(name, tokens[familyStart:])
+	return name
+}
+
+// splitFamily separates the particles from the family name words
+func splitFamily(name *wiki_domain.PersonName, tokens []string) {
+	for _, token := range tokens {
+		if particles[strings.ToLower(token)] && len(name.Family) == 0 {
+			name.Particles = append(name.Particles, token)
+			continue
+		}
+		lower := strings.ToLower(token)
+		for _, particle := range hyphenParticles {
+			if strings.HasPrefix(lower, particle) && len(token) > len(particle) {
+				name.Particles = append(name.Particles, token[:len(particle)])
+				token = token[len(particle):]
+				break
+			}
+		}
+		name.Family = append(name.Family, token)
+	}
+}
+
+// ApplyWikidata replaces the rule based given and family names with the labels of the page's P735/P734 items
+func ApplyWikidata(name *wiki_domain.PersonName, given []string, family []string) {
+	if len(given) == 0 && len(family) == 0 {
+		return
+	}
+	if len(given) > 0 {
+		name.Given = given
+	}
+	if len(family) > 0 {
+		name.Particles, name.Family = []string{}, []string{}
+		for _, label := range family {
+			splitFamily(name, strings.Fields(label))
+		}
+	}
+	name.Source = SourceWikidata
+}
@@ -0,0 +1,72 @@
+package wiki_name
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseParticles(t *testing.T) {
+	name := Parse("Ludwig_van_Beethoven")
+	assert.EqualValues(t, []string{"Ludwig"}, name.Given)
+	assert.EqualValues(t, []string{"van"}, name.Particles)
+	assert.EqualValues(t, []string{"Beethoven"}, name.Family)
+	assert.EqualValues(t, SourceRules, name.Source)
+
+	name = Parse("Charles de Gaulle")
+	assert.EqualValues(t, []string{"Charles"}, name.Given)
+	assert.EqualValues(t, []string{"de"}, name.Particles)
+	assert.EqualValues(t, []string{"Gaulle"}, name.Family)
+}
+
+func TestParseSuffixes(t *testing.T) {
+	name := Parse("Martin Luther King Jr.")
+	assert.EqualValues(t, []string{"Martin", "Luther"}, name.Given)
+	assert.EqualValues(t, []string{"King"}, name.Family)
+	assert.EqualValues(t, []string{"Jr."}, name.Suffixes)
+
+	name = Parse("Henry Ford II")
+	assert.EqualValues(t, []string{"Henry"}, name.Given)
+	assert.EqualValues(t, []string{"Ford"}, name.Family)
+	assert.EqualValues(t, []string{"II"}, name.Suffixes)
+}
+
+func TestParseDisambiguator(t *testing.T) {
+	name := Parse("John_Smith_(footballer,_born_1898)")
+	assert.EqualValues(t, "footballer, born 1898", name.Disambiguator)
+	assert.EqualValues(t, []string{"John"}, name.Given)
+	assert.EqualValues(t, []string{"Smith"}, name.Family)
+}
+
+func TestParseHyphenParticleAndMononym(t *testing.T) {
+	name := Parse("Muhammad ibn Musa al-Khwarizmi")
+	assert.EqualValues(t, []string{"Muhammad"}, name.Given)
+	assert.EqualValues(t, []string{"ibn", "al-"}, name.Particles)
+	assert.EqualValues(t, []string{"Musa", "Khwarizmi"}, name.Family)
+
+	name = Parse("Pelé")
+	assert.EqualValues(t, []string{"Pelé"}, name.Given)
+	assert.Empty(t, name.Family)
+}
+
+func TestParseSpanishDoubleFamilyName(t *testing.T) {
+	name := Parse("José Ortega y Gasset")
+	assert.EqualValues(t, []string{"José"}, name.Given)
+	assert.Empty(t, name.Particles)
+	assert.EqualValues(t, []string{"Ortega", "y", "Gasset"}, name.Family)
+}
+
+func TestApplyWikidata(t *testing.T) {
+	// The rules alone can't tell García is a family name here
+	name := Parse("Gabriel García Márquez")
+	assert.EqualValues(t, []string{"Gabriel", "García"}, name.Given)
+
+	ApplyWikidata(name, []string{"Gabriel"}, []string{"García", "Márquez"})
+	assert.EqualValues(t, []string{"Gabriel"}, name.Given)
+	assert.EqualValues(t, []string{"García", "Márquez"}, name.Family)
+	assert.EqualValues(t, SourceWikidata, name.Source)
+
+	name = Parse("Ludwig van Beethoven")
+	ApplyWikidata(name, nil, nil)
+	assert.EqualValues(t, SourceRules, name.Source)
+}
@@ -0,0 +1,57 @@
+package wiki_person
+
+import "regexp"
+
+// HumanItem is the Wikidata item for "human", people are P31 (instance of) Q5
+const HumanItem = "Q5"
+
+var (
+	birthsDeathsRe  = regexp.MustCompile(`^Category:(?:\d{1,4}(?:s)?(?: BC)? (?:births|deaths)|Living people|People from .+|Possibly living people)$`)
+	personInfoboxRe = regexp.MustCompile(`(?i){{\s*Infobox[ _]+(?:person|scientist|officeholder|musical[ _]artist|sportsperson|football[ _]biography|[a-z ]+[ _]biography|writer|artist|actor|military[ _]person|politician|royalty|philosopher|cricketer|ice[ _]hockey[ _]player|chess[ _]player|criminal|academic|engineer|economist|astronaut|model|comedian|youtube[ _]personality|noble|monarch|saint|christian[ _]leader|judge|journalist|architect|chef|dancer|boxer|martial[ _]artist|swimmer|racing[ _]driver|clergy|president|prime[ _]minister|officeholder)\s*[|}\n]`)
+	anyInfoboxRe    = regexp.MustCompile(`(?i){{\s*Infobox[ _]`)
+)
+
+// Signals are the hints we have about a page, any of them may be empty
+type Signals struct {
+	// InstanceOf holds the page's Wikidata P31 values, HasWikidata tells an empty list from an unknown one
+	InstanceOf  []string
+	HasWikidata bool
+	Categories  []string
+	Wikitext    string
+}
+
+// Classify decides whether the page is about a human. Wikidata wins when it has P31 claims,
+// otherwise categories and the infobox are weighed. It returns nil when there is no evidence either way.
+func Classify(signals Signals) *bool {
+	if signals.HasWikidata && len(signals.InstanceOf) > 0 {
+		isPerson := false
+		for _, id := range signals.InstanceOf {
+			if id == HumanItem {
+				isPerson = true
+			}
+		}
+		return &isPerson
+	}
+
+	score, evidence := 0, false
+	for _, category := range signals.Categories {
+		if birthsDeathsRe.MatchString(category) {
+			score += 2
+			evidence = true
+		}
+	}
+	switch {
+	case personInfoboxRe.MatchString(signals.Wikitext):
+		score++
+		evidence = true
+	case anyInfoboxRe.MatchString(signals.Wikitext):
+		// An infobox for a film, band, city... is evidence against
+		score -= 2
+		evidence = true
+	}
+	if !evidence {
+		return nil
+	}
+	isPerson := score > 0
+	return &isPerson
+}
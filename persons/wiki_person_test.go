@@ -0,0 +1,37 @@
+package wiki_person
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyWikidataWins(t *testing.T) {
+	isPerson := Classify(Signals{InstanceOf: []string{"Q5"}, HasWikidata: true, Wikitext: "{{Infobox film}}"})
+	assert.True(t, *isPerson)
+
+	isPerson = Classify(Signals{InstanceOf: []string{"Q11424"}, HasWikidata: true, Categories: []string{"Category:Living people"}})
+	assert.False(t, *isPerson)
+}
+
+func TestClassifyCategoriesAndInfobox(t *testing.T) {
+	isPerson := Classify(Signals{Categories: []string{"Category:1964 births", "Category:Living people", "Category:Canadian computer scientists"}})
+	assert.True(t, *isPerson)
+
+	isPerson = Classify(Signals{Categories: []string{"Category:470s BC births"}})
+	assert.True(t, *isPerson)
+
+	isPerson = Classify(Signals{Wikitext: "{{Short description|Canadian computer scientist}}\n{{Infobox scientist\n| name = Yoshua Bengio"})
+	assert.True(t, *isPerson)
+
+	isPerson = Classify(Signals{Wikitext: "{{Infobox football biography\n| name = Lionel Messi"})
+	assert.True(t, *isPerson)
+
+	isPerson = Classify(Signals{Wikitext: "{{Infobox film\n| name = John Smith", Categories: []string{"Category:2010 films"}})
+	assert.False(t, *isPerson)
+}
+
+func TestClassifyNoEvidence(t *testing.T) {
+	assert.Nil(t, Classify(Signals{Categories: []string{"Category:Computer science"}, Wikitext: "No templates here"}))
+	assert.Nil(t, Classify(Signals{HasWikidata: true}))
+}
@@ -0,0 +1,4 @@
+package wiki_proto
+
+// Regenerate the messages and the service stubs after changing wiki_names.proto
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative wiki_names.proto
@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.2.0
+// - protoc             v3.21.12
+// source: wiki_names.proto
+
+package wiki_proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// WikiNamesClient is the client API for WikiNames service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type WikiNamesClient interface {
+	// GetSummary is /search/:name: the short description plus the facets asked for in include
+	GetSummary(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*Summary, error)
+	// GetExtract is /extract/:name: the start of the plaintext extract
+	GetExtract(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*Summary, error)
+	// BatchLookup describes several names, streaming each result as soon as it is ready
+	BatchLookup(ctx context.Context, in *BatchLookupRequest, opts ...grpc.CallOption) (WikiNames_BatchLookupClient, error)
+	// Suggest lists the pages whose title starts with a prefix, for autocompletion
+	Suggest(ctx context.Context, in *SuggestRequest, opts ...grpc.CallOption) (*SuggestResponse, error)
+}
+
+type wikiNamesClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWikiNamesClient(cc grpc.ClientConnInterface) WikiNamesClient {
+	return &wikiNamesClient{cc}
+}
+
+func (c *wikiNamesClient) GetSummary(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*Summary, error) {
+	out := new(Summary)
+	err := c.cc.Invoke(ctx, "/wikinames.v1.WikiNames/GetSummary", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wikiNamesClient) GetExtract(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*Summary, error) {
+	out := new(Summary)
+	err := c.cc.Invoke(ctx, "/wikinames.v1.WikiNames/GetExtract", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *wikiNamesClient) BatchLookup(ctx context.Context, in *BatchLookupRequest, opts ...grpc.CallOption) (WikiNames_BatchLookupClient, error) {
+	stream, err := c.cc.NewStream(ctx, &WikiNames_ServiceDesc.Streams[0], "/wikinames.v1.WikiNames/BatchLookup", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &wikiNamesBatchLookupClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type WikiNames_BatchLookupClient interface {
+	Recv() (*BatchLookupResult, error)
+	grpc.ClientStream
+}
+
+type wikiNamesBatchLookupClient struct {
+	grpc.ClientStream
+}
+
+func (x *wikiNamesBatchLookupClient) Recv() (*BatchLookupResult, error) {
+	m := new(BatchLookupResult)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *wikiNamesClient) Suggest(ctx context.Context, in *SuggestRequest, opts ...grpc.CallOption) (*SuggestResponse, error) {
+	out := new(SuggestResponse)
+	err := c.cc.Invoke(ctx, "/wikinames.v1.WikiNames/Suggest", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// WikiNamesServer is the server API for WikiNames service.
+// All implementations must embed UnimplementedWikiNamesServer
+// for forward compatibility
+type WikiNamesServer interface {
+	// GetSummary is /search/:name: the short description plus the facets asked for in include
+	GetSummary(context.Context, *LookupRequest) (*Summary, error)
+	// GetExtract is /extract/:name: the start of the plaintext extract
+	GetExtract(context.Context, *LookupRequest) (*Summary, error)
+	// BatchLookup describes several names, streaming each result as soon as it is ready
+	BatchLookup(*BatchLookupRequest, WikiNames_BatchLookupServer) error
+	// Suggest lists the pages whose title starts with a prefix, for autocompletion
+	Suggest(context.Context, *SuggestRequest) (*SuggestResponse, error)
+	mustEmbedUnimplementedWikiNamesServer()
+}
+
+// UnimplementedWikiNamesServer must be embedded to have forward compatible implementations.
+type UnimplementedWikiNamesServer struct {
+}
+
+func (UnimplementedWikiNamesServer) GetSummary(context.Context, *LookupRequest) (*Summary, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSummary not implemented")
+}
+func (UnimplementedWikiNamesServer) GetExtract(context.Context, *LookupRequest) (*Summary, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetExtract not implemented")
+}
+func (UnimplementedWikiNamesServer) BatchLookup(*BatchLookupRequest, WikiNames_BatchLookupServer) error {
+	return status.Errorf(codes.Unimplemented, "method BatchLookup not implemented")
+}
+func (UnimplementedWikiNamesServer) Suggest(context.Context, *SuggestRequest) (*SuggestResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Suggest not implemented")
+}
+func (UnimplementedWikiNamesServer) mustEmbedUnimplementedWikiNamesServer() {}
+
+// UnsafeWikiNamesServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WikiNamesServer will
+// result in compilation errors.
+type UnsafeWikiNamesServer interface {
+	mustEmbedUnimplementedWikiNamesServer()
+}
+
+func RegisterWikiNamesServer(s grpc.ServiceRegistrar, srv WikiNamesServer) {
+	s.RegisterService(&WikiNames_ServiceDesc, srv)
+}
+
+func _WikiNames_GetSummary_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WikiNamesServer).GetSummary(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wikinames.v1.WikiNames/GetSummary",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WikiNamesServer).GetSummary(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WikiNames_GetExtract_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WikiNamesServer).GetExtract(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wikinames.v1.WikiNames/GetExtract",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WikiNamesServer).GetExtract(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WikiNames_BatchLookup_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BatchLookupRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WikiNamesServer).BatchLookup(m, &wikiNamesBatchLookupServer{stream})
+}
+
+type WikiNames_BatchLookupServer interface {
+	Send(*BatchLookupResult) error
+	grpc.ServerStream
+}
+
+type wikiNamesBatchLookupServer struct {
+	grpc.ServerStream
+}
+
+func (x *wikiNamesBatchLookupServer) Send(m *BatchLookupResult) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _WikiNames_Suggest_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuggestRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WikiNamesServer).Suggest(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/wikinames.v1.WikiNames/Suggest",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WikiNamesServer).Suggest(ctx, req.(*SuggestRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// WikiNames_ServiceDesc is the grpc.ServiceDesc for WikiNames service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WikiNames_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "wikinames.v1.WikiNames",
+	HandlerType: (*WikiNamesServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetSummary",
+			Handler:    _WikiNames_GetSummary_Handler,
+		},
+		{
+			MethodName: "GetExtract",
+			Handler:    _WikiNames_GetExtract_Handler,
+		},
+		{
+			MethodName: "Suggest",
+			Handler:    _WikiNames_Suggest_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "BatchLookup",
+			Handler:       _WikiNames_BatchLookup_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "wiki_names.proto",
+}
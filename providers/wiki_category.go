@@ -0,0 +1,165 @@
+package wiki_provider
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	wiki_domain "wiki-names/domains"
+)
+
+const (
+	categoriesUrl        = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&prop=categories&cllimit=max&clprop=hidden&titles=%s"
+	categoryMembersUrl   = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&list=categorymembers&cmtitle=%s&cmtype=%s&cmlimit=%d&cmprop=ids%%7Ctitle"
+	shortDescriptionsUrl = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&prop=pageprops&ppprop=wikibase-shortdesc&titles=%s"
+)
+
+// defaultCategoryLimit is how many members one /category page lists when no limit is asked for,
+// titlesBatch is the most titles MediaWiki takes in one titles= parameter
+const (
+	defaultCategoryLimit = 50
+	titlesBatch          = 50
+)
+
+// GetCategories lists the page's categories, leaving out the hidden maintenance ones
+func (p *WikiProviderStruct) GetCategories(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Categories, *wiki_domain.WikiError) {
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	if err := validateTitle(request.Name); err != nil {
+		return nil, err
+	}
+	upstream := wikiHost(request.Locale)
+	var result wiki_domain.Content
+	if _, err := QueryAll(ctx, fmt.Sprintf(categoriesUrl, request.Locale, url.QueryEscape(request.Name)), QueryLimits{}, &result); err != nil {
+		return nil, err.WithUpstream(upstream)
+	}
+	if len(result.Query.Pages) == 0 || result.Query.Pages[0].Missing {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, fmt.Sprintf("Page %s does not exist", request.Name)).WithUpstream(upstream)
+	}
+	page := result.Query.Pages[0]
+	if page.Invalid {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, page.InvalidReason).WithUpstream(upstream)
+	}
+	categories := &wiki_domain.Categories{Title: page.Title, Language: request.Locale, Categories: []string{}}
+	for _, category := range page.Categories {
+		if category.Hidden {
+			continue
+		}
+		categories.Categories = append(categories.Categories, categoryName(category.Title))
+	}
+	return categories, nil
+}
+
+// GetCategoryMembers lists one page of the category's members, starting from query.Cursor
+func (p *WikiProviderStruct) GetCategoryMembers(ctx context.Context, query wiki_domain.CategoryQuery) (*wiki_domain.CategoryMembers, *wiki_domain.WikiError) {
+	request := wiki_domain.RequestQuery{Tenant: query.Tenant, Name: trimCategoryPrefix(query.Category), Locale: query.Locale}
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	if err := validateTitle(request.Name); err != nil {
+		return nil, err
+	}
+	if query.Type == "" {
+		query.Type = "page"
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultCategoryLimit
+	}
+	upstream := wikiHost(request.Locale)
+	categoryUrl := fmt.Sprintf(categoryMembersUrl, request.Locale, url.QueryEscape("Category:"+request.Name), query.Type, query.Limit)
+	if query.Cursor != "" {
+		categoryUrl += "&cmcontinue=" + url.QueryEscape(query.Cursor)
+	}
+	iterator := NewQueryIterator(ctx, categoryUrl, QueryLimits{MaxPages: 1})
+	var result wiki_domain.CategoryMembersResponse
+	if iterator.Next() {
+		if err := iterator.Decode(&result); err != nil {
+			return nil, err.WithUpstream(upstream)
+		}
+	}
+	if err := iterator.Err(); err != nil {
+		return nil, err.WithUpstream(upstream)
+	}
+
+	members := &wiki_domain.CategoryMembers{
+		Category: strings.ReplaceAll(request.Name, "_", " "),
+		Language: request.Locale,
+		Members:  result.Query.Categorymembers,
+		Cursor:   iterator.Continuation()["cmcontinue"],
+	}
+	if members.Members == nil {
+		members.Members = []wiki_domain.CategoryMember{}
+	}
+	if query.Descriptions && len(members.Members) > 0 {
+		titles := make([]string, 0, len(members.Members))
+		for _, member := range members.Members {
+			titles = append(titles, member.Title)
+		}
+		descriptions, err := p.GetShortDescriptions(ctx, request.Locale, titles)
+		if err != nil {
+			return nil, err
+		}
+		for i := range members.Members {
+			members.Members[i].ShortDescription = descriptions[members.Members[i].Title]
+		}
+	}
+	return members, nil
+}
+
+// GetShortDescriptions looks the short descriptions of many titles up, 50 titles per call,
+// titles without a description are left out of the map
+func (p *WikiProviderStruct) GetShortDescriptions(ctx context.Context, locale string, titles []string) (map[string]string, *wiki_domain.WikiError) {
+	request := wiki_domain.RequestQuery{Locale: locale}
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	upstream := wikiHost(request.Locale)
+	descriptions := map[string]string{}
+	for start := 0; start < len(titles); start += titlesBatch {
+		end := start + titlesBatch
+		if end > len(titles) {
+			end = len(titles)
+		}
+		escaped := make([]string, 0, end-start)
+		for _, title := range titles[start:end] {
+			escaped = append(escaped, url.QueryEscape(title))
+		}
+		var result wiki_domain.ShortDescriptions
+		if _, err := QueryAll(ctx, fmt.Sprintf(shortDescriptionsUrl, request.Locale, strings.Join(escaped, "%7C")), QueryLimits{}, &result); err != nil {
+			return nil, err.WithUpstream(upstream)
+		}
+		// Report the descriptions under the titles we were given, not the ones MediaWiki normalized them to
+		normalized := map[string]string{}
+		for _, n := range result.Query.Normalized {
+			normalized[n.To] = n.From
+		}
+		for _, page := range result.Query.Pages {
+			if page.Pageprops.ShortDescription == "" {
+				continue
+			}
+			descriptions[page.Title] = page.Pageprops.ShortDescription
+			if from, ok := normalized[page.Title]; ok {
+				descriptions[from] = page.Pageprops.ShortDescription
+			}
+		}
+	}
+	return descriptions, nil
+}
+
+// categoryName drops the namespace prefix of "Category:Turing Award laureates", in any language
+func categoryName(title string) string {
+	if i := strings.Index(title, ":"); i >= 0 {
+		return title[i+1:]
+	}
+	return title
+}
+
+// trimCategoryPrefix drops a "Category:" the caller may have included, the rest of the name can have colons of its own
+func trimCategoryPrefix(name string) string {
+	if len(name) > len("Category:") && strings.EqualFold(name[:len("Category:")], "Category:") {
+		return name[len("Category:"):]
+	}
+	return name
+}
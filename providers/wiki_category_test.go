@@ -0,0 +1,107 @@
+package wiki_provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	wiki_domain "wiki-names/domains"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCategories(t *testing.T) {
+	urls := mockResponses(t,
+		`{"batchcomplete":true,"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","categories":[`+
+			`{"ns":14,"title":"Category:1964 births"},{"ns":14,"title":"Category:Articles with short description","hidden":true},{"ns":14,"title":"Category:Turing Award laureates"}]}]}}`,
+	)
+
+	response, err := WikiProvider.GetCategories(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio"})
+	assert.Nil(t, err)
+	assert.Contains(t, (*urls)[0], "clprop=hidden")
+	assert.EqualValues(t, "Yoshua Bengio", response.Title)
+	assert.EqualValues(t, []string{"1964 births", "Turing Award laureates"}, response.Categories)
+}
+
+func TestGetCategoriesMissingPage(t *testing.T) {
+	mockResponses(t, `{"batchcomplete":true,"query":{"pages":[{"ns":0,"title":"Nobody At All","missing":true}]}}`)
+
+	response, err := WikiProvider.GetCategories(context.Background(), wiki_domain.RequestQuery{Name: "Nobody_At_All"})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrPageNotFound, err.ErrorCode)
+}
+
+func TestGetCategoriesCancelled(t *testing.T) {
+	urls := mockResponses(t)
+
+	// The client hung up, so no upstream call is made for it
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	response, err := WikiProvider.GetCategories(ctx, wiki_domain.RequestQuery{Name: "Yoshua_Bengio"})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrUpstreamUnavailable, err.ErrorCode)
+	assert.Empty(t, *urls)
+}
+
+func TestGetCategoryMembers(t *testing.T) {
+	urls := mockResponses(t,
+		`{"continue":{"cmcontinue":"page|4245524e474945|47749536","continue":"-||"},"query":{"categorymembers":[{"pageid":1,"ns":0,"title":"Alan Kay"},{"pageid":47749536,"ns":0,"title":"Yoshua Bengio"}]}}`,
+		`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Alan Kay","pageprops":{"wikibase-shortdesc":"American computer scientist"}},{"pageid":47749536,"ns":0,"title":"Yoshua Bengio"}]}}`,
+	)
+
+	response, err := WikiProvider.GetCategoryMembers(context.Background(), wiki_domain.CategoryQuery{Category: "Category:Turing_Award_laureates", Limit: 2, Cursor: "page|41|1", Descriptions: true})
+	assert.Nil(t, err)
+	assert.Contains(t, (*urls)[0], "cmtitle=Category%3ATuring_Award_laureates&cmtype=page&cmlimit=2")
+	assert.Contains(t, (*urls)[0], "&cmcontinue=page%7C41%7C1")
+	assert.Contains(t, (*urls)[1], "titles=Alan+Kay%7CYoshua+Bengio")
+	assert.EqualValues(t, "Turing Award laureates", response.Category)
+	assert.EqualValues(t, "page|4245524e474945|47749536", response.Cursor)
+	assert.Len(t, response.Members, 2)
+	assert.EqualValues(t, "American computer scientist", response.Members[0].ShortDescription)
+	assert.EqualValues(t, "", response.Members[1].ShortDescription)
+}
+
+func TestGetCategoryMembersLastPage(t *testing.T) {
+	mockResponses(t, `{"batchcomplete":true,"query":{"categorymembers":[{"pageid":1,"ns":0,"title":"Alan Kay"}]}}`)
+
+	response, err := WikiProvider.GetCategoryMembers(context.Background(), wiki_domain.CategoryQuery{Category: "Turing_Award_laureates"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "", response.Cursor)
+	assert.Len(t, response.Members, 1)
+}
+
+func TestGetCategoryMembersTenantLocales(t *testing.T) {
+	urls := mockResponses(t, `{"batchcomplete":true,"query":{"categorymembers":[{"pageid":1,"ns":0,"title":"Alan Kay"}]}}`)
+	tenant := &wiki_domain.Tenant{ID: "acme", DefaultLocale: "fr", AllowedLocales: []string{"fr"}}
+
+	// The tenant's default locale replaces en, and the locales it doesn't allow are refused
+	response, err := WikiProvider.GetCategoryMembers(context.Background(), wiki_domain.CategoryQuery{Category: "Lauréat_du_prix_Turing", Tenant: tenant})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "fr", response.Language)
+	assert.Contains(t, (*urls)[0], "https://fr.wikipedia.org/")
+
+	response, err = WikiProvider.GetCategoryMembers(context.Background(), wiki_domain.CategoryQuery{Category: "Turing_Award_laureates", Locale: "en", Tenant: tenant})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrNotEnabled, err.ErrorCode)
+	assert.Len(t, *urls, 1)
+}
+
+func TestGetShortDescriptionsBatches(t *testing.T) {
+	var titles []string
+	for i := 0; i < 60; i++ {
+		titles = append(titles, fmt.Sprintf("Person %d", i))
+	}
+	urls := mockResponses(t,
+		`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Person 0","pageprops":{"wikibase-shortdesc":"First person"}}]}}`,
+		`{"batchcomplete":true,"query":{"normalized":[{"fromencoded":false,"from":"person 59","to":"Person 59"}],"pages":[{"pageid":2,"ns":0,"title":"Person 59","pageprops":{"wikibase-shortdesc":"Last person"}}]}}`,
+	)
+	titles[59] = "person 59"
+
+	descriptions, err := WikiProvider.GetShortDescriptions(context.Background(), "en", titles)
+	assert.Nil(t, err)
+	assert.Len(t, *urls, 2)
+	assert.EqualValues(t, 49, strings.Count((*urls)[0], "%7C"))
+	assert.EqualValues(t, "First person", descriptions["Person 0"])
+	assert.EqualValues(t, "Last person", descriptions["person 59"])
+}
@@ -0,0 +1,286 @@
+package wiki_provider
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	neturl "net/url"
+	"sort"
+	"strings"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// QueryLimits caps how far a QueryIterator follows the continue tokens, zero means no cap
+type QueryLimits struct {
+	// MaxPages is the most upstream responses fetched
+	MaxPages int
+	// MaxItems stops once this many items (list entries, or the entries of the pages' props) were read
+	MaxItems int
+}
+
+// metaKeys are the query fields that describe the request rather than list results
+var metaKeys = map[string]bool{"normalized": true, "redirects": true, "converted": true, "interwiki": true, "badrevids": true}
+
+// QueryIterator follows the continue tokens of any formatversion=2 action=query url. Each batch it
+// yields has every prop of its pages: the responses before batchcomplete are merged into one batch.
+type QueryIterator struct {
+	ctx    context.Context
+	url    string
+	limits QueryLimits
+
+	continuation map[string]string
+	pages        int
+	items        int
+	done         bool
+	complete     bool
+	current      *queryBatch
+	err          *wiki_domain.WikiError
+}
+
+func NewQueryIterator(ctx context.Context, url string, limits QueryLimits) *QueryIterator {
+	return &QueryIterator{ctx: ctx, url: url, limits: limits}
+}
+
+type queryResponse struct {
+	Batchcomplete bool                        `json:"batchcomplete"`
+	Continue      map[string]json.RawMessage  `json:"continue"`
+	Warnings      wiki_domain.WarningsType    `json:"warnings"`
+	Error         *wiki_domain.MediaWikiError `json:"error,omitempty"`
+	Query         map[string]json.RawMessage  `json:"query"`
+}
+
+// Next fetches the next complete batch, it returns false when there is none left, a cap was
+// reached, the context was cancelled or an error happened (see Err)
+func (it *QueryIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	batch := newQueryBatch()
+	fetched := false
+	for {
+		if it.limits.MaxPages > 0 && it.pages >= it.limits.MaxPages {
+			it.done = true
+			break
+		}
+		if err := it.ctx.Err(); err != nil {
+			it.err = wiki_domain.NewTypedError(wiki_domain.ErrUpstreamUnavailable, "query cancelled: "+err.Error())
+			it.done = true
+			return false
+		}
+		var response queryResponse
+		if err := getJSON(it.ctx, it.pageUrl(), &response); err != nil {
+			it.err, it.done = err, true
+			return false
+		}
+		if response.Error != nil {
+			log.Printf("wiki query error response: %s %s", response.Error.Code, response.Error.Info)
+			it.err, it.done = response.Error.ToWikiError(), true
+			return false
+		}
+		logWarnings(response.Warnings)
+		it.pages++
+		fetched = true
+		if err := batch.merge(response.Query); err != nil {
+			log.Printf("error when trying to merge wiki query response: %s", err.Error())
+			it.err = &wiki_domain.WikiError{Code: http.StatusInternalServerError, ErrorMessage: "error unmarshaling wiki fetch response"}
+			it.done = true
+			return false
+		}
+		it.continuation = continueParams(response.Continue)
+		if len(it.continuation) == 0 {
+			it.done, it.complete = true, true
+			break
+		}
+		if it.limits.MaxItems > 0 && it.items+batch.items() >= it.limits.MaxItems {
+			it.done = true
+			break
+		}
+		// Without batchcomplete the next response carries more of the same pages' props
+		if response.Batchcomplete {
+			break
+		}
+	}
+	if !fetched {
+		return false
+	}
+	it.items += batch.items()
+	it.current = batch
+	return true
+}
+
+// Decode unmarshals the current batch into result, as if it was the body of a single response
+func (it *QueryIterator) Decode(result interface{}) *wiki_domain.WikiError {
+	bytes, err := json.Marshal(map[string]interface{}{"batchcomplete": true, "query": it.current.query()})
+	if err == nil {
+		err = json.Unmarshal(bytes, result)
+	}
+	if err != nil {
+		log.Printf("error when trying to decode wiki query batch: %s", err.Error())
+		return &wiki_domain.WikiError{Code: http.StatusInternalServerError, ErrorMessage: "error unmarshaling wiki fetch response"}
+	}
+	return nil
+}
+
+// Err is the error that stopped the iterator, nil when it ran out of results or hit a cap
+func (it *QueryIterator) Err() *wiki_domain.WikiError {
+	return it.err
+}
+
+// Complete reports whether every continuation was followed, rather than a cap stopping the iterator
+func (it *QueryIterator) Complete() bool {
+	return it.complete
+}
+
+// Continuation is the continue parameters the next call would send, empty once the iterator is complete
+func (it *QueryIterator) Continuation() map[string]string {
+	if it.complete {
+		return map[string]string{}
+	}
+	return it.continuation
+}
+
+func (it *QueryIterator) pageUrl() string {
+	if len(it.continuation) == 0 {
+		return it.url
+	}
+	keys := make([]string, 0, len(it.continuation))
+	for key := range it.continuation {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var url strings.Builder
+	url.WriteString(it.url)
+	for _, key := range keys {
+		url.WriteString("&" + neturl.QueryEscape(key) + "=" + neturl.QueryEscape(it.continuation[key]))
+	}
+	return url.String()
+}
+
+// QueryAll follows every continuation of url (up to the limits) and decodes all the batches merged together into result
+func QueryAll(ctx context.Context, url string, limits QueryLimits, result interface{}) (bool, *wiki_domain.WikiError) {
+	iterator := NewQueryIterator(ctx, url, limits)
+	all := newQueryBatch()
+	for iterator.Next() {
+		if err := all.merge(iterator.current.query()); err != nil {
+			return false, &wiki_domain.WikiError{Code: http.StatusInternalServerError, ErrorMessage: "error unmarshaling wiki fetch response"}
+		}
+	}
+	if err := iterator.Err(); err != nil {
+		return false, err
+	}
+	iterator.current = all
+	return iterator.Complete(), iterator.Decode(result)
+}
+
+// continueParams turns the continue object into url parameters, most tokens are strings but offsets are numbers
+func continueParams(raw map[string]json.RawMessage) map[string]string {
+	params := make(map[string]string, len(raw))
+	for key, value := range raw {
+		var text string
+		if err := json.Unmarshal(value, &text); err != nil {
+			text = string(value)
+		}
+		params[key] = text
+	}
+	return params
+}
+
+// queryBatch merges query objects: pages are matched by title and their props appended, lists are appended
+type queryBatch struct {
+	fields map[string]json.RawMessage
+	pages  []map[string]json.RawMessage
+	index  map[string]int
+}
+
+func newQueryBatch() *queryBatch {
+	return &queryBatch{fields: map[string]json.RawMessage{}, index: map[string]int{}}
+}
+
+func (b *queryBatch) merge(query map[string]json.RawMessage) error {
+	for key, raw := range query {
+		if key != "pages" {
+			b.fields[key] = mergeValue(b.fields[key], raw)
+			continue
+		}
+		var pages []map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &pages); err != nil {
+			return err
+		}
+		for _, page := range pages {
+			pageKey := string(page["title"]) + "|" + string(page["pageid"])
+			i, ok := b.index[pageKey]
+			if !ok {
+				b.index[pageKey] = len(b.pages)
+				b.pages = append(b.pages, page)
+				continue
+			}
+			for field, value := range page {
+				b.pages[i][field] = mergeValue(b.pages[i][field], value)
+			}
+		}
+	}
+	return nil
+}
+
+func (b *queryBatch) query() map[string]json.RawMessage {
+	query := make(map[string]json.RawMessage, len(b.fields)+1)
+	for key, value := range b.fields {
+		query[key] = value
+	}
+	if b.pages != nil {
+		if pages, err := json.Marshal(b.pages); err == nil {
+			query["pages"] = pages
+		}
+	}
+	return query
+}
+
+// items counts the list entries and, for each page, the entries of its props (or the page itself when it has none)
+func (b *queryBatch) items() int {
+	count := 0
+	for key, raw := range b.fields {
+		var list []json.RawMessage
+		if !metaKeys[key] && json.Unmarshal(raw, &list) == nil {
+			count += len(list)
+		}
+	}
+	for _, page := range b.pages {
+		entries := 0
+		for _, raw := range page {
+			var list []json.RawMessage
+			if json.Unmarshal(raw, &list) == nil {
+				entries += len(list)
+			}
+		}
+		if entries == 0 {
+			entries = 1
+		}
+		count += entries
+	}
+	return count
+}
+
+// mergeValue appends arrays, merges objects key by key and otherwise keeps the newest value
+func mergeValue(existing json.RawMessage, value json.RawMessage) json.RawMessage {
+	if existing == nil {
+		return value
+	}
+	var existingList, list []json.RawMessage
+	if json.Unmarshal(existing, &existingList) == nil && json.Unmarshal(value, &list) == nil {
+		if merged, err := json.Marshal(append(existingList, list...)); err == nil {
+			return merged
+		}
+	}
+	var existingObject, object map[string]json.RawMessage
+	if json.Unmarshal(existing, &existingObject) == nil && json.Unmarshal(value, &object) == nil {
+		for key, v := range object {
+			existingObject[key] = v
+		}
+		if merged, err := json.Marshal(existingObject); err == nil {
+			return merged
+		}
+	}
+	return value
+}
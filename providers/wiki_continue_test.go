@@ -0,0 +1,94 @@
+package wiki_provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	wiki_client "wiki-names/clients"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockResponses(t *testing.T, bodies ...string) *[]string {
+	var urls []string
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		urls = append(urls, url)
+		if len(urls) > len(bodies) {
+			t.Fatalf("unexpected call %s", url)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(bodies[len(urls)-1]))}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+	return &urls
+}
+
+func TestQueryIteratorMergesPartialBatches(t *testing.T) {
+	urls := mockResponses(t,
+		`{"continue":{"clcontinue":"1|Living_people","continue":"||"},"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","categories":[{"ns":14,"title":"Category:1964 births"}]},{"pageid":2,"ns":0,"title":"Geoffrey Hinton"}]}}`,
+		`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","categories":[{"ns":14,"title":"Category:Living people"}]},{"pageid":2,"ns":0,"title":"Geoffrey Hinton","categories":[{"ns":14,"title":"Category:1947 births"}]}]}}`,
+	)
+
+	iterator := NewQueryIterator(context.Background(), "https://en.wikipedia.org/w/api.php?action=query&prop=categories&titles=Yoshua_Bengio%7CGeoffrey_Hinton&formatversion=2&format=json", QueryLimits{})
+	assert.True(t, iterator.Next())
+	var result struct {
+		Query struct {
+			Pages []struct {
+				Title      string `json:"title"`
+				Categories []struct {
+					Title string `json:"title"`
+				} `json:"categories"`
+			} `json:"pages"`
+		} `json:"query"`
+	}
+	assert.Nil(t, iterator.Decode(&result))
+	assert.Len(t, result.Query.Pages, 2)
+	assert.Len(t, result.Query.Pages[0].Categories, 2)
+	assert.EqualValues(t, "Category:Living people", result.Query.Pages[0].Categories[1].Title)
+	assert.Len(t, result.Query.Pages[1].Categories, 1)
+	assert.False(t, iterator.Next())
+	assert.Nil(t, iterator.Err())
+	assert.True(t, iterator.Complete())
+	assert.Contains(t, (*urls)[1], "&clcontinue=1%7CLiving_people&continue=%7C%7C")
+}
+
+func TestQueryIteratorCaps(t *testing.T) {
+	page := `{"batchcomplete":true,"continue":{"sroffset":10,"continue":"-||"},"query":{"search":[{"title":"A"},{"title":"B"}]}}`
+	urls := mockResponses(t, page, page, page)
+
+	iterator := NewQueryIterator(context.Background(), "https://en.wikipedia.org/w/api.php?action=query&list=search&srsearch=Bengio&formatversion=2&format=json", QueryLimits{MaxPages: 2})
+	batches := 0
+	for iterator.Next() {
+		batches++
+	}
+	assert.EqualValues(t, 2, batches)
+	assert.False(t, iterator.Complete())
+	assert.Contains(t, (*urls)[1], "&sroffset=10")
+
+	urls = mockResponses(t, page, page, page)
+	var result struct {
+		Query struct {
+			Search []struct {
+				Title string `json:"title"`
+			} `json:"search"`
+		} `json:"query"`
+	}
+	complete, err := QueryAll(context.Background(), "https://en.wikipedia.org/w/api.php?action=query&list=search&srsearch=Bengio&formatversion=2&format=json", QueryLimits{MaxItems: 3}, &result)
+	assert.Nil(t, err)
+	assert.False(t, complete)
+	assert.Len(t, *urls, 2)
+	assert.Len(t, result.Query.Search, 4)
+}
+
+func TestQueryIteratorCancelled(t *testing.T) {
+	urls := mockResponses(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	iterator := NewQueryIterator(ctx, "https://en.wikipedia.org/w/api.php?action=query&list=search&srsearch=Bengio&formatversion=2&format=json", QueryLimits{})
+	assert.False(t, iterator.Next())
+	assert.NotNil(t, iterator.Err())
+	assert.Empty(t, *urls)
+}
@@ -0,0 +1,182 @@
+package wiki_provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+
+	wiki_domain "wiki-names/domains"
+	wiki_infobox "wiki-names/infoboxes"
+)
+
+const redirectsUrl = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&prop=redirects&rdlimit=max&rdprop=pageid%%7Ctitle&titles=%s"
+
+// facetLimits caps the langlinks and redirects facets, a page with thousands of redirects
+// shouldn't cost a request more than a few upstream calls
+var facetLimits = QueryLimits{MaxPages: 5, MaxItems: 2500}
+
+// Facets are the values ?include= takes. The description is what /search always returns, the others
+// are optional lookups that run alongside it.
+var Facets = []string{"description", "extract", "image", "infobox", "categories", "langlinks", "redirects"}
+
+// facetFunc fills its own fields of response, which only it writes to
+type facetFunc func(ctx context.Context, p *WikiProviderStruct, request wiki_domain.RequestQuery, response *wiki_domain.Response) *wiki_domain.WikiError
+
+var facets = map[string]facetFunc{
+	"extract":    extractFacet,
+	"image":      imageFacet,
+	"infobox":    infoboxFacet,
+	"categories": categoriesFacet,
+	"langlinks":  langlinksFacet,
+	"redirects":  redirectsFacet,
+}
+
+// withFacets runs the lookup and the facets in request.Include concurrently. A failing facet is
+// left out of the response, only the lookup itself can fail the request.
+func (p *WikiProviderStruct) withFacets(ctx context.Context, request wiki_domain.RequestQuery, lookup func(context.Context, wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError)) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	names, err := parseFacets(request.Include)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if !request.Tenant.AllowsFacet(name) {
+			return nil, wiki_domain.NewTypedError(wiki_domain.ErrNotEnabled, fmt.Sprintf("include %s is not enabled for tenant %s", name, request.Tenant.ID))
+		}
+	}
+	if len(names) == 0 {
+		return lookup(ctx, request)
+	}
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	parts := make([]*wiki_domain.Response, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			part := &wiki_domain.Response{}
+			if err := facets[name](ctx, p, request, part); err != nil {
+				log.Printf("error when trying to get the %s of %s: %s", name, request.Name, err.ErrorMessage)
+				return
+			}
+			parts[i] = part
+		}(i, name)
+	}
+	response, err := lookup(ctx, request)
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range parts {
+		if part != nil {
+			mergeFacet(response, part)
+		}
+	}
+	return response, nil
+}
+
+// parseFacets validates the ?include= list, the description needs no extra lookup so it is dropped
+func parseFacets(include string) ([]string, *wiki_domain.WikiError) {
+	var names []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(include, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" || name == "description" || seen[name] {
+			continue
+		}
+		if _, ok := facets[name]; !ok {
+			return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidRequest, fmt.Sprintf("unknown include %q, expected one of %s", name, strings.Join(Facets, ", ")))
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func mergeFacet(response *wiki_domain.Response, part *wiki_domain.Response) {
+	if part.Image != nil {
+		response.Image = part.Image
+	}
+	if part.Extract != "" {
+		response.Extract = part.Extract
+	}
+	if part.Infobox != nil {
+		response.Infobox = part.Infobox
+	}
+	if part.Categories != nil {
+		response.Categories = part.Categories
+	}
+	if part.Langlinks != nil {
+		response.Langlinks = part.Langlinks
+	}
+	if part.Redirects != nil {
+		response.Redirects = part.Redirects
+	}
+}
+
+func extractFacet(ctx context.Context, p *WikiProviderStruct, request wiki_domain.RequestQuery, response *wiki_domain.Response) *wiki_domain.WikiError {
+	request.Include = ""
+	extract, err := p.extract(ctx, request)
+	if err != nil {
+		return err
+	}
+	response.Extract = extract.ShortDescription
+	return nil
+}
+
+func imageFacet(ctx context.Context, p *WikiProviderStruct, request wiki_domain.RequestQuery, response *wiki_domain.Response) *wiki_domain.WikiError {
+	response.Image = getImage(ctx, request.Locale, request.Name)
+	return nil
+}
+
+func infoboxFacet(ctx context.Context, p *WikiProviderStruct, request wiki_domain.RequestQuery, response *wiki_domain.Response) *wiki_domain.WikiError {
+	content, err := p.GetContent(ctx, request)
+	if err != nil {
+		return err
+	}
+	if len(content.Query.Pages) > 0 && len(content.Query.Pages[0].Revisions) > 0 {
+		response.Infobox = wiki_infobox.Parse(content.Query.Pages[0].Revisions[0].Content)
+	}
+	return nil
+}
+
+func categoriesFacet(ctx context.Context, p *WikiProviderStruct, request wiki_domain.RequestQuery, response *wiki_domain.Response) *wiki_domain.WikiError {
+	categories, err := p.GetCategories(ctx, request)
+	if err != nil {
+		return err
+	}
+	response.Categories = categories.Categories
+	return nil
+}
+
+func langlinksFacet(ctx context.Context, p *WikiProviderStruct, request wiki_domain.RequestQuery, response *wiki_domain.Response) *wiki_domain.WikiError {
+	var result wiki_domain.LangLinks
+	if _, err := QueryAll(ctx, fmt.Sprintf(langlinksUrl, request.Locale, url.QueryEscape(request.Name)), facetLimits, &result); err != nil {
+		return err
+	}
+	response.Langlinks = map[string]string{}
+	for _, page := range result.Query.Pages {
+		for _, link := range page.Langlinks {
+			response.Langlinks[link.Lang] = link.Title
+		}
+	}
+	return nil
+}
+
+func redirectsFacet(ctx context.Context, p *WikiProviderStruct, request wiki_domain.RequestQuery, response *wiki_domain.Response) *wiki_domain.WikiError {
+	var result wiki_domain.PageRedirects
+	if _, err := QueryAll(ctx, fmt.Sprintf(redirectsUrl, request.Locale, url.QueryEscape(request.Name)), facetLimits, &result); err != nil {
+		return err
+	}
+	response.Redirects = []string{}
+	for _, page := range result.Query.Pages {
+		for _, redirect := range page.Redirects {
+			response.Redirects = append(response.Redirects, redirect.Title)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package wiki_provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	wiki_client "wiki-names/clients"
+	wiki_domain "wiki-names/domains"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetContentSummaryFacets(t *testing.T) {
+	var mu sync.Mutex
+	var urls []string
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		mu.Lock()
+		urls = append(urls, url)
+		mu.Unlock()
+		var body string
+		switch {
+		case strings.Contains(url, "prop=redirects"):
+			body = `{"batchcomplete":true,"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","redirects":[{"pageid":1,"ns":0,"title":"Bengio"},{"pageid":2,"ns":0,"title":"Yoshua bengio"}]}]}}`
+		case strings.Contains(url, "prop=langlinks"):
+			body = `{"batchcomplete":true,"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","langlinks":[{"lang":"ja","title":"ヨシュア・ベンジオ"}]}]}}`
+		case strings.Contains(url, "prop=categories&cllimit"):
+			body = `{"batchcomplete":true,"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","categories":[{"ns":14,"title":"Category:1964 births"},{"ns":14,"title":"Category:Hidden one","hidden":true}]}]}}`
+		case strings.Contains(url, "prop=pageimages"):
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: io.NopCloser(strings.NewReader(""))}, nil
+		default:
+			body = `{"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","revisions":[{"contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Short description|Canadian computer scientist}}\n{{Infobox scientist\n| name = Yoshua Bengio\n| birth_place = [[Paris]], France\n}}"}]}]}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Include: "description,infobox,categories,langlinks,redirects,image"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Canadian computer scientist", response.ShortDescription)
+	assert.EqualValues(t, "scientist", response.Infobox.Type)
+	assert.EqualValues(t, "Paris, France", response.Infobox.Fields["birth_place"])
+	assert.EqualValues(t, []string{"1964 births"}, response.Categories)
+	assert.EqualValues(t, map[string]string{"ja": "ヨシュア・ベンジオ"}, response.Langlinks)
+	assert.EqualValues(t, []string{"Bengio", "Yoshua bengio"}, response.Redirects)
+	// A facet that could not be fetched is left out rather than failing the lookup
+	assert.Nil(t, response.Image)
+	assert.Len(t, urls, 6)
+}
+
+func TestGetContentSummaryUnknownFacet(t *testing.T) {
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Include: "image,awards"})
+	assert.Nil(t, response)
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrInvalidRequest, err.ErrorCode)
+	assert.Contains(t, err.ErrorMessage, `"awards"`)
+}
+
+func TestGetContentSummaryRedirectsCapped(t *testing.T) {
+	var mu sync.Mutex
+	redirects := 0
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		body := `{"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","revisions":[{"content":"{{Short description|Canadian computer scientist}}"}]}]}}`
+		if strings.Contains(url, "prop=redirects") {
+			mu.Lock()
+			redirects++
+			mu.Unlock()
+			// There is always another page of redirects
+			body = `{"batchcomplete":true,"continue":{"rdcontinue":"next","continue":"||"},"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","redirects":[{"pageid":2,"ns":0,"title":"Bengio"}]}]}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Include: "redirects"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, facetLimits.MaxPages, redirects)
+	assert.Len(t, response.Redirects, facetLimits.MaxPages)
+}
+
+func TestGetContentSummaryFacetsFailWithLookup(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"query":{"pages":[{"ns":0,"title":"Nobody At All","missing":true}]}}`))}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Nobody_At_All", Include: "categories"})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrPageNotFound, err.ErrorCode)
+}
+
+func TestGetContentSummaryFacetNotEnabled(t *testing.T) {
+	tenant := &wiki_domain.Tenant{ID: "acme", Facets: []string{"image"}}
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Include: "description,infobox", Tenant: tenant})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrNotEnabled, err.ErrorCode)
+	assert.Contains(t, err.ErrorMessage, "infobox")
+}
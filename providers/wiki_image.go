@@ -0,0 +1,73 @@
+package wiki_provider
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"regexp"
+	"strings"
+
+	wiki_domain "wiki-names/domains"
+)
+
+const (
+	pageImagesUrl = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&prop=pageimages&piprop=thumbnail%%7Coriginal%%7Cname&pithumbsize=%d&titles=%s"
+	imageInfoUrl  = "https://commons.wikimedia.org/w/api.php?action=query&format=json&formatversion=2&prop=imageinfo&iiprop=extmetadata&iiextmetadatafilter=LicenseShortName%%7CLicenseUrl%%7CArtist%%7CAttribution&titles=%s"
+)
+
+// thumbnailSize is the width of the thumbnails we link to, big enough for a UI card
+const thumbnailSize = 320
+
+var htmlTagRe = regexp.MustCompile(`<[^>]*>`)
+
+// getImage looks the page's lead image up, and its license on Commons. Like the Wikidata claims,
+// it is nil rather than an error when anything is missing, the lookup itself still succeeded.
+func getImage(ctx context.Context, locale string, title string) *wiki_domain.Image {
+	var pageImages wiki_domain.PageImages
+	if err := getJSON(ctx, fmt.Sprintf(pageImagesUrl, locale, thumbnailSize, url.QueryEscape(title)), &pageImages); err != nil {
+		log.Printf("error when trying to get the page image of %s: %s", title, err.ErrorMessage)
+		return nil
+	}
+	if len(pageImages.Query.Pages) == 0 || pageImages.Query.Pages[0].Thumbnail == nil {
+		return nil
+	}
+	page := pageImages.Query.Pages[0]
+	image := &wiki_domain.Image{
+		File:      page.Pageimage,
+		Thumbnail: page.Thumbnail.Source,
+		Width:     page.Thumbnail.Width,
+		Height:    page.Thumbnail.Height,
+	}
+	if page.Original != nil {
+		image.Original, image.OriginalWidth, image.OriginalHeight = page.Original.Source, page.Original.Width, page.Original.Height
+	}
+	if page.Pageimage == "" {
+		return image
+	}
+
+	var info wiki_domain.ImageInfo
+	if err := getJSON(ctx, fmt.Sprintf(imageInfoUrl, url.QueryEscape("File:"+page.Pageimage)), &info); err != nil {
+		log.Printf("error when trying to get the license of %s: %s", page.Pageimage, err.ErrorMessage)
+		return image
+	}
+	applyLicense(image, info.Metadata)
+	return image
+}
+
+// applyLicense fills the image's license and credit from its Commons extmetadata
+func applyLicense(image *wiki_domain.Image, metadata func(field string) string) {
+	image.License = plainText(metadata("LicenseShortName"))
+	image.LicenseUrl = plainText(metadata("LicenseUrl"))
+	image.Artist = plainText(metadata("Artist"))
+	image.Attribution = plainText(metadata("Attribution"))
+	if image.Attribution == "" && image.Artist != "" {
+		image.Attribution = strings.TrimSuffix(image.Artist+", "+image.License, ", ")
+	}
+}
+
+// plainText strips the HTML Commons wraps its metadata values in
+func plainText(value string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(htmlTagRe.ReplaceAllString(value, ""))), " ")
+}
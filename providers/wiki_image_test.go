@@ -0,0 +1,63 @@
+package wiki_provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	wiki_client "wiki-names/clients"
+	wiki_domain "wiki-names/domains"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockImageResponses(t *testing.T) *[]string {
+	var mu sync.Mutex
+	var urls []string
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		mu.Lock()
+		urls = append(urls, url)
+		mu.Unlock()
+		var body string
+		switch {
+		case strings.Contains(url, "prop=pageimages"):
+			assert.Contains(t, url, "pithumbsize=320&titles=Yoshua_Bengio")
+			body = `{"batchcomplete":true,"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","thumbnail":{"source":"https://upload.wikimedia.org/wikipedia/commons/thumb/3/3c/Yoshua_Bengio_2017.jpg/320px-Yoshua_Bengio_2017.jpg","width":320,"height":427},"original":{"source":"https://upload.wikimedia.org/wikipedia/commons/3/3c/Yoshua_Bengio_2017.jpg","width":1536,"height":2048},"pageimage":"Yoshua_Bengio_2017.jpg"}]}}`
+		case strings.HasPrefix(url, "https://commons.wikimedia.org/"):
+			assert.Contains(t, url, "titles=File%3AYoshua_Bengio_2017.jpg")
+			body = `{"batchcomplete":true,"query":{"pages":[{"ns":6,"title":"File:Yoshua Bengio 2017.jpg","imageinfo":[{"extmetadata":{"LicenseShortName":{"value":"CC BY-SA 4.0","source":"commons-desc-page"},"LicenseUrl":{"value":"https://creativecommons.org/licenses/by-sa/4.0","source":"commons-desc-page"},"Artist":{"value":"<a href=\"//commons.wikimedia.org/wiki/User:Example\" title=\"User:Example\">Example</a> &amp; friends","source":"commons-desc-page"}}}]}]}}`
+		default:
+			body = `{"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","revisions":[{"contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Short description|Canadian computer scientist}}"}]}]}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+	return &urls
+}
+
+func TestGetContentSummaryIncludeImage(t *testing.T) {
+	mockImageResponses(t)
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Include: "image"})
+	assert.Nil(t, err)
+	assert.NotNil(t, response.Image)
+	assert.EqualValues(t, "Yoshua_Bengio_2017.jpg", response.Image.File)
+	assert.EqualValues(t, 320, response.Image.Width)
+	assert.EqualValues(t, 427, response.Image.Height)
+	assert.EqualValues(t, "https://upload.wikimedia.org/wikipedia/commons/3/3c/Yoshua_Bengio_2017.jpg", response.Image.Original)
+	assert.EqualValues(t, "CC BY-SA 4.0", response.Image.License)
+	assert.EqualValues(t, "Example & friends", response.Image.Artist)
+	assert.EqualValues(t, "Example & friends, CC BY-SA 4.0", response.Image.Attribution)
+}
+
+func TestGetContentSummaryNoImageByDefault(t *testing.T) {
+	urls := mockImageResponses(t)
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio"})
+	assert.Nil(t, err)
+	assert.Nil(t, response.Image)
+	assert.Len(t, *urls, 1)
+}
@@ -0,0 +1,171 @@
+package wiki_provider
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+
+	wiki_domain "wiki-names/domains"
+	wiki_infobox "wiki-names/infoboxes"
+)
+
+const pagesUrl = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&redirects=1&titles=%s&prop=%s"
+
+// pageProps are the props and parameters each facet adds to a batched lookup
+var pageProps = map[string]struct{ prop, params string }{
+	"extract":    {"extracts", "&exintro=1&explaintext=1&exlimit=max"},
+	"image":      {"pageimages", fmt.Sprintf("&piprop=thumbnail%%7Coriginal%%7Cname&pithumbsize=%d&pilimit=max", thumbnailSize)},
+	"infobox":    {"revisions", "&rvprop=content"},
+	"categories": {"categories", "&clshow=%21hidden&cllimit=max"},
+	"langlinks":  {"langlinks", "&lllimit=max"},
+	"redirects":  {"redirects", "&rdprop=title&rdlimit=max"},
+}
+
+// GetPages looks many titles up at once, 50 titles per call with every facet in include fetched by
+// that same call. The result is keyed by the titles given, titles with no page are left out. Unlike
+// GetExtract, the extract is the page's whole intro.
+func (p *WikiProviderStruct) GetPages(ctx context.Context, locale string, titles []string, include string) (map[string]*wiki_domain.Page, *wiki_domain.WikiError) {
+	request := wiki_domain.RequestQuery{Locale: locale}
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	names, err := parseFacets(include)
+	if err != nil {
+		return nil, err
+	}
+	props := []string{"pageprops"}
+	params := "&ppprop=wikibase-shortdesc%7Cwikibase_item"
+	for _, name := range names {
+		props = append(props, pageProps[name].prop)
+		params += pageProps[name].params
+	}
+	upstream := wikiHost(request.Locale)
+	pages := map[string]*wiki_domain.Page{}
+	for start := 0; start < len(titles); start += titlesBatch {
+		end := start + titlesBatch
+		if end > len(titles) {
+			end = len(titles)
+		}
+		escaped := make([]string, 0, end-start)
+		for _, title := range titles[start:end] {
+			escaped = append(escaped, url.QueryEscape(strings.ReplaceAll(title, "_", " ")))
+		}
+		var result wiki_domain.PagesQuery
+		pagesQueryUrl := fmt.Sprintf(pagesUrl, request.Locale, strings.Join(escaped, "%7C"), strings.Join(props, "%7C")) + params
+		if _, err := QueryAll(ctx, pagesQueryUrl, QueryLimits{}, &result); err != nil {
+			return nil, err.WithUpstream(upstream)
+		}
+
+		byTitle := map[string]*wiki_domain.Page{}
+		for _, page := range result.Query.Pages {
+			if page.Missing || page.Invalid {
+				continue
+			}
+			response := &wiki_domain.Response{ShortDescription: page.Pageprops.ShortDescription, Language: request.Locale, Extract: page.Extract}
+			if page.Thumbnail != nil {
+				response.Image = &wiki_domain.Image{File: page.Pageimage, Thumbnail: page.Thumbnail.Source, Width: page.Thumbnail.Width, Height: page.Thumbnail.Height}
+				if page.Original != nil {
+					response.Image.Original, response.Image.OriginalWidth, response.Image.OriginalHeight = page.Original.Source, page.Original.Width, page.Original.Height
+				}
+			}
+			if len(page.Revisions) > 0 {
+				response.Infobox = wiki_infobox.Parse(page.Revisions[0].Content)
+			}
+			if contains(names, "categories") {
+				response.Categories = []string{}
+				for _, category := range page.Categories {
+					response.Categories = append(response.Categories, categoryName(category.Title))
+				}
+			}
+			if contains(names, "langlinks") {
+				response.Langlinks = map[string]string{}
+				for _, link := range page.Langlinks {
+					response.Langlinks[link.Lang] = link.Title
+				}
+			}
+			if contains(names, "redirects") {
+				response.Redirects = []string{}
+				for _, redirect := range page.Redirects {
+					response.Redirects = append(response.Redirects, redirect.Title)
+				}
+			}
+			byTitle[page.Title] = &wiki_domain.Page{Title: page.Title, Response: response}
+		}
+
+		// Follow each title through normalization and redirects to its page
+		normalized, redirects := map[string]string{}, map[string]string{}
+		for _, n := range result.Query.Normalized {
+			normalized[n.From] = n.To
+		}
+		for _, r := range result.Query.Redirects {
+			redirects[r.From] = r.To
+		}
+		for _, title := range titles[start:end] {
+			canonical := strings.ReplaceAll(title, "_", " ")
+			if to, ok := normalized[canonical]; ok {
+				canonical = to
+			}
+			if to, ok := redirects[canonical]; ok {
+				canonical = to
+			}
+			if page, ok := byTitle[canonical]; ok {
+				pages[title] = page
+			}
+		}
+	}
+	if contains(names, "image") {
+		applyLicenses(ctx, pages)
+	}
+	return pages, nil
+}
+
+// applyLicenses looks the licenses of the pages' images up on Commons, 50 files per call. Like
+// getImage, an image whose license can't be fetched is kept without one.
+func applyLicenses(ctx context.Context, pages map[string]*wiki_domain.Page) {
+	images := map[string][]*wiki_domain.Image{}
+	var files []string
+	for _, page := range pages {
+		if page.Image == nil || page.Image.File == "" {
+			continue
+		}
+		file := strings.ReplaceAll(page.Image.File, "_", " ")
+		if _, ok := images[file]; !ok {
+			files = append(files, file)
+		}
+		images[file] = append(images[file], page.Image)
+	}
+	sort.Strings(files)
+	for start := 0; start < len(files); start += titlesBatch {
+		end := start + titlesBatch
+		if end > len(files) {
+			end = len(files)
+		}
+		escaped := make([]string, 0, end-start)
+		for _, file := range files[start:end] {
+			escaped = append(escaped, url.QueryEscape("File:"+file))
+		}
+		var info wiki_domain.ImageInfo
+		if err := getJSON(ctx, fmt.Sprintf(imageInfoUrl, strings.Join(escaped, "%7C")), &info); err != nil {
+			log.Printf("error when trying to get the licenses of %d images: %s", end-start, err.ErrorMessage)
+			continue
+		}
+		for i := range info.Query.Pages {
+			page := &info.Query.Pages[i]
+			for _, image := range images[strings.TrimPrefix(page.Title, "File:")] {
+				applyLicense(image, page.Metadata)
+			}
+		}
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package wiki_provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetPages(t *testing.T) {
+	urls := mockResponses(t,
+		`{"batchcomplete":true,"query":{"normalized":[{"fromencoded":false,"from":"yoshua Bengio","to":"Yoshua Bengio"}],"redirects":[{"from":"Hinton","to":"Geoffrey Hinton"}],"pages":[`+
+			`{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","pageprops":{"wikibase-shortdesc":"Canadian computer scientist"},"pageimage":"Yoshua_Bengio_2017.jpg","thumbnail":{"source":"https://upload.wikimedia.org/320px-Yoshua_Bengio_2017.jpg","width":320,"height":427},`+
+			`"revisions":[{"contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Infobox scientist\n| name = Yoshua Bengio\n| birth_date = {{birth date and age|1964|3|5}}\n}}"}]},`+
+			`{"pageid":1,"ns":0,"title":"Geoffrey Hinton","pageprops":{"wikibase-shortdesc":"British-Canadian computer scientist"}},`+
+			`{"ns":0,"title":"Nobody At All","missing":true}]}}`,
+		`{"batchcomplete":true,"query":{"pages":[{"ns":6,"title":"File:Yoshua Bengio 2017.jpg","imageinfo":[{"extmetadata":{"LicenseShortName":{"value":"CC BY-SA 4.0","source":"commons-desc-page"}}}]}]}}`,
+	)
+
+	pages, err := WikiProvider.GetPages(context.Background(), "en", []string{"yoshua_Bengio", "Hinton", "Nobody At All"}, "image,infobox")
+	assert.Nil(t, err)
+	assert.Contains(t, (*urls)[0], "redirects=1&titles=yoshua+Bengio%7CHinton%7CNobody+At+All&prop=pageprops%7Cpageimages%7Crevisions")
+	assert.Contains(t, (*urls)[1], "titles=File%3AYoshua+Bengio+2017.jpg")
+	assert.Len(t, pages, 2)
+	assert.EqualValues(t, "Yoshua Bengio", pages["yoshua_Bengio"].Title)
+	assert.EqualValues(t, "Canadian computer scientist", pages["yoshua_Bengio"].ShortDescription)
+	assert.EqualValues(t, "CC BY-SA 4.0", pages["yoshua_Bengio"].Image.License)
+	assert.EqualValues(t, "scientist", pages["yoshua_Bengio"].Infobox.Type)
+	assert.EqualValues(t, "Geoffrey Hinton", pages["Hinton"].Title)
+	assert.Nil(t, pages["Hinton"].Image)
+}
+
+func TestGetPagesBatchesFiftyTitles(t *testing.T) {
+	titles := make([]string, 120)
+	for i := range titles {
+		titles[i] = fmt.Sprintf("Person %d", i)
+	}
+	urls := mockResponses(t, `{"batchcomplete":true}`, `{"batchcomplete":true}`, `{"batchcomplete":true}`)
+
+	pages, err := WikiProvider.GetPages(context.Background(), "en", titles, "")
+	assert.Nil(t, err)
+	assert.Empty(t, pages)
+	assert.Len(t, *urls, 3)
+	for i, count := range []int{50, 50, 20} {
+		assert.EqualValues(t, count, strings.Count((*urls)[i], "Person+"))
+	}
+}
+
+func TestGetPagesUnknownFacet(t *testing.T) {
+	_, err := WikiProvider.GetPages(context.Background(), "en", []string{"Yoshua_Bengio"}, "horoscope")
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+}
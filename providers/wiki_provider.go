@@ -1,149 +1,587 @@
 package wiki_provider
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	neturl "net/url"
 	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
+	wiki_bio "wiki-names/bios"
 	wiki_client "wiki-names/clients"
 	wiki_domain "wiki-names/domains"
+	wiki_locale "wiki-names/locales"
+	wiki_name "wiki-names/names"
+	wiki_person "wiki-names/persons"
+	wiki_sentence "wiki-names/sentences"
 )
 
 const (
-	contentUrl = "https://en.wikipedia.org/w/api.php?action=query&prop=revisions&titles=%s&rvlimit=1&formatversion=2&format=json&rvprop=content"
-	extractUrl = "https://%s.wikipedia.org/w/api.php?action=query&format=json&prop=extracts&titles=%s&formatversion=2&exsentences=2&exlimit=1&explaintext=1"
+	contentUrl   = "https://%s.wikipedia.org/w/api.php?action=query&prop=revisions%%7Cpageprops%%7Ccategories&titles=%s&rvlimit=1&formatversion=2&format=json&rvsection=0&rvprop=content%%7Cids%%7Ctimestamp%%7Cuser%%7Cuserid%%7Ccomment&ppprop=wikibase_item&cllimit=max&clprop=hidden"
+	revisionUrl  = "https://%s.wikipedia.org/w/api.php?action=query&prop=revisions%%7Cpageprops%%7Ccategories&revids=%d&formatversion=2&format=json&rvsection=0&rvprop=content%%7Cids%%7Ctimestamp%%7Cuser%%7Cuserid%%7Ccomment&ppprop=wikibase_item&cllimit=max&clprop=hidden"
+	historyUrl   = "https://%s.wikipedia.org/w/api.php?action=query&prop=revisions&titles=%s&rvlimit=%d&formatversion=2&format=json&rvsection=0&rvprop=content%%7Cids%%7Ctimestamp%%7Cuser%%7Cuserid%%7Ccomment"
+	extractUrl   = "https://%s.wikipedia.org/w/api.php?action=query&format=json&prop=extracts%%7Cpageprops&titles=%s&formatversion=2&exlimit=1&explaintext=1&ppprop=wikibase_item"
+	wikidataUrl  = "https://www.wikidata.org/w/api.php?action=wbgetclaims&format=json&entity=%s&property=%s"
+	labelsUrl    = "https://www.wikidata.org/w/api.php?action=wbgetentities&format=json&props=labels&languages=%s%%7Cen&ids=%s"
+	resolveUrl   = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&redirects=1&titles=%s"
+	langlinksUrl = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&prop=langlinks&lllimit=max&titles=%s"
+)
+
+// maxLangs caps how many editions a single ?langs= lookup fans out to
+const maxLangs = 20
+
+// defaultHistoryLimit is how many revisions /history reads when no limit is asked for, one call's
+// worth. historyBatch is the most revisions MediaWiki returns per call when it includes their content,
+// of which we only ask for the lead section, where the short description lives.
+const (
+	defaultHistoryLimit = 50
+	historyBatch        = 50
+)
+
+// defaultExtractSentences is how much of the extract /extract returns when no limit is asked for
+const defaultExtractSentences = 2
+
+var (
+	shortDescriptionRe = regexp.MustCompile(`{{Short description\|([^}][^}]+)}}`)
+	// Disambiguation pages (including the human name ones) carry one of these templates
+	disambiguationRe = regexp.MustCompile(`(?i){{\s*(disambiguation|disambig|dab|hndis|geodis)\s*[|}]`)
+	// Characters MediaWiki never allows in a page title
+	invalidTitleRe = regexp.MustCompile(`[#<>\[\]{}|]`)
 )
 
 type WikiProviderStruct struct{}
 
 type wikiServiceInterface interface {
-	GetContent(request wiki_domain.RequestQuery) (*wiki_domain.Content, *wiki_domain.WikiError)
-	GetContentSummary(request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError)
-	GetExtract(request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError)
+	GetContent(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Content, *wiki_domain.WikiError)
+	GetContentSummary(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError)
+	GetExtract(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError)
+	GetBio(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Bio, *wiki_domain.WikiError)
+	GetTranslations(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Translations, *wiki_domain.WikiError)
+	GetHistory(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.History, *wiki_domain.WikiError)
+	GetCategories(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Categories, *wiki_domain.WikiError)
+	GetCategoryMembers(ctx context.Context, query wiki_domain.CategoryQuery) (*wiki_domain.CategoryMembers, *wiki_domain.WikiError)
+	GetShortDescriptions(ctx context.Context, locale string, titles []string) (map[string]string, *wiki_domain.WikiError)
+	GetSuggestions(ctx context.Context, query wiki_domain.SuggestQuery) (*wiki_domain.Suggestions, *wiki_domain.WikiError)
+	GetPages(ctx context.Context, locale string, titles []string, include string) (map[string]*wiki_domain.Page, *wiki_domain.WikiError)
 }
 
 var WikiProvider wikiServiceInterface = &WikiProviderStruct{}
 
-func (p *WikiProviderStruct) GetContent(request wiki_domain.RequestQuery) (*wiki_domain.Content, *wiki_domain.WikiError) {
-	url := fmt.Sprintf(contentUrl, request.Name)
-	response, err := wiki_client.Client.Get(url)
-	if err != nil {
-		log.Printf("error when trying to get wiki content %s", err.Error())
-		return nil, &wiki_domain.WikiError{
-			Code:         http.StatusBadRequest,
-			ErrorMessage: err.Error(),
-		}
-	}
-	bytes, err := io.ReadAll(response.Body)
-	if err != nil {
-		return nil, &wiki_domain.WikiError{
-			Code:         http.StatusBadRequest,
-			ErrorMessage: err.Error(),
-		}
+func (p *WikiProviderStruct) GetContent(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Content, *wiki_domain.WikiError) {
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
 	}
-	defer response.Body.Close()
-	if err := checkStatusCode(response); err != nil {
+	if err := validateTitle(request.Name); err != nil {
 		return nil, err
 	}
+	if request.Revid > 0 && !request.AsOf.IsZero() {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidRequest, "as_of and revid can't be used together")
+	}
+	upstream := wikiHost(request.Locale)
 	var result wiki_domain.Content
-	if err := json.Unmarshal(bytes, &result); err != nil {
-		log.Printf("error when trying to unmarshal wiki content successful response: %s", err.Error())
-		return nil, &wiki_domain.WikiError{Code: http.StatusInternalServerError, ErrorMessage: "error unmarshaling wiki fetch response"}
+	if err := getJSON(ctx, contentQueryUrl(request), &result); err != nil {
+		return nil, err.WithUpstream(upstream)
+	}
+	if result.Error != nil {
+		log.Printf("wiki content error response: %s %s", result.Error.Code, result.Error.Info)
+		return nil, result.Error.ToWikiError().WithUpstream(upstream)
+	}
+	logWarnings(result.Warnings)
+	if request.Revid > 0 {
+		// revids= ignores the title, so make sure the revision really belongs to the page asked for,
+		// which may be a redirect to it
+		if len(result.Query.Badrevids) > 0 || len(result.Query.Pages) == 0 || !isTitleOf(ctx, request.Locale, request.Name, result.Query.Pages[0].Title) {
+			return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, fmt.Sprintf("Revision %d of %s does not exist", request.Revid, request.Name)).WithUpstream(upstream)
+		}
 	}
 
 	return &result, nil
 }
 
-func (p *WikiProviderStruct) GetContentSummary(request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
-	result, err := p.GetContent(request)
+// GetContentSummary looks the short description up, along with the facets asked for in request.Include
+func (p *WikiProviderStruct) GetContentSummary(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	return p.withFacets(ctx, request, p.describe)
+}
+
+func (p *WikiProviderStruct) describe(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	result, err := p.GetContent(ctx, request)
 	if err != nil {
 		return nil, err
 	}
+	upstream := wikiHost(request.Locale)
 	// Validate the JSON hierarchy structure
+	if len(result.Query.Pages) > 0 && result.Query.Pages[0].Invalid {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, result.Query.Pages[0].InvalidReason).WithUpstream(upstream)
+	}
+	if len(result.Query.Pages) > 0 && result.Query.Pages[0].Missing {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, fmt.Sprintf("Page %s does not exist", result.Query.Pages[0].Title)).WithUpstream(upstream)
+	}
 	if len(result.Query.Pages) == 0 ||
 		len(result.Query.Pages[0].Revisions) == 0 {
 		message := "Missing page revisions in json response body"
-		log.Println(message)
-		return nil, &wiki_domain.WikiError{
-			Code:         http.StatusNotFound,
-			ErrorMessage: message,
+		if !request.AsOf.IsZero() {
+			message = fmt.Sprintf("Page %s did not exist as of %s", request.Name, request.AsOf.UTC().Format(time.RFC3339))
 		}
+		log.Println(message)
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, message).WithUpstream(upstream)
+	}
+	page := result.Query.Pages[0]
+	content := page.Revisions[0].Content
+	if disambiguationRe.MatchString(content) {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrAmbiguous, fmt.Sprintf("%s is a disambiguation page", page.Title)).WithUpstream(upstream)
+	}
+	categories := make([]string, 0, len(page.Categories))
+	for _, category := range page.Categories {
+		categories = append(categories, category.Title)
+	}
+	// The name claims are fetched along with P31 rather than after it, most lookups are of people
+	claims := getClaims(ctx, page.Pageprops.WikibaseItem, "P31", "P735", "P734")
+	isPerson := classifyPerson(claims, categories, content)
+	if err := checkType(request, page.Title, isPerson); err != nil {
+		return nil, err.WithUpstream(upstream)
 	}
 	// Find the Short Description placeholder text and extract the value using regex
-	re := regexp.MustCompile(`{{Short description\|([^}][^}]+)}}`)
-	matches := re.FindStringSubmatch(result.Query.Pages[0].Revisions[0].Content)
+	matches := shortDescriptionRe.FindStringSubmatch(content)
 	if len(matches) < 1 {
 		message := "Missing `Short description` in json response body"
 		log.Println(message)
-		/* ***NOTE***: If the "ShortDescription text is missing, I took the "executive decision"
-		   to return a 206 status code with the full Content text in the HTTP BODY
-			This needs to be fleshed out in a Agile daily meeting and align with PM and TechLead.
-			I'm not overly comfortable with this inelegant solution.  If there is a standard for
-			"short description"and it's translation, I would  do a version 2, with a mapping
-			function to include the transaltions for the different language markup.
-
-			As a version 1.5, I added an API endpoint to call Extract as an altertative for multilingual text,
-			and get the first two sentences.
-
-			This could be a solution for the multilinugal solution.  But I found that the # of
-			sentence parameter has some strange behaviour, it seems to count periods (fullstops)
-			without checking if it is part of a acronym or shorthand wording.  I'd recommend, we revisit
-			the sentence count from "Extract" API to come up with a better way to delimit sentence
-			boundaries.
-		*/
-		return nil, &wiki_domain.WikiError{
-			Code:         http.StatusPartialContent,
-			ErrorMessage: result.Query.Pages[0].Revisions[0].Content,
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageMissingDescription, message).WithUpstream(upstream)
+	}
+	response := &wiki_domain.Response{ShortDescription: matches[1], Language: request.Locale, IsPerson: isPerson, Revision: page.Revisions[0].Info()}
+	if isPerson == nil || *isPerson {
+		response.Name = parseName(ctx, page.Title, request.Locale, claims)
+	}
+	return response, nil
+}
+
+// GetExtract returns the start of the page's extract as its description, along with the facets asked for in request.Include
+func (p *WikiProviderStruct) GetExtract(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	return p.withFacets(ctx, request, p.extract)
+}
+
+func (p *WikiProviderStruct) extract(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	if err := validateTitle(request.Name); err != nil {
+		return nil, err
+	}
+	upstream := wikiHost(request.Locale)
+	// MediaWiki's exsentences splits on every full stop, so fetch the extract and cut it ourselves
+	limits := request.Limits()
+	if limits.IsZero() {
+		limits.Sentences = defaultExtractSentences
+	}
+	url := fmt.Sprintf(extractUrl, request.Locale, neturl.QueryEscape(request.Name))
+	// The lead is enough unless more than its first paragraph is asked for
+	if limits.Paragraphs <= 1 {
+		url += "&exintro=1"
+	}
+	if request.Variant != "" {
+		url += "&variant=" + request.Variant
+	}
+	var result wiki_domain.Extract
+	if err := getJSON(ctx, url, &result); err != nil {
+		return nil, err.WithUpstream(upstream)
+	}
+	if result.Error != nil {
+		log.Printf("wiki extract error response: %s %s", result.Error.Code, result.Error.Info)
+		return nil, result.Error.ToWikiError().WithUpstream(upstream)
+	}
+	logWarnings(result.Warnings)
+
+	if len(result.Query.Pages) == 0 || result.Query.Pages[0].Missing {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, fmt.Sprintf("Page %s does not exist", request.Name)).WithUpstream(upstream)
+	}
+	if result.Query.Pages[0].Invalid {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, result.Query.Pages[0].InvalidReason).WithUpstream(upstream)
+	}
+	isPerson := classifyPerson(getClaims(ctx, result.Query.Pages[0].Pageprops.WikibaseItem, "P31"), nil, "")
+	if err := checkType(request, result.Query.Pages[0].Title, isPerson); err != nil {
+		return nil, err.WithUpstream(upstream)
+	}
+	description := wiki_sentence.Truncate(result.Query.Pages[0].Extract, request.Locale, limits)
+	if description == "" {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageMissingDescription, "Missing extract in json response body").WithUpstream(upstream)
+	}
+	return &wiki_domain.Response{ShortDescription: description, Language: servedLanguage(request), IsPerson: isPerson}, nil
+}
+
+// GetBio parses the dates, nationality and occupations out of the lead sentence of the English extract
+func (p *WikiProviderStruct) GetBio(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Bio, *wiki_domain.WikiError) {
+	// The lead sentence patterns the parser knows are the English Wikipedia ones
+	request.Locale, request.Variant = "en", ""
+	request.Sentences, request.Chars, request.Paragraphs = 1, 0, 0
+	extract, err := p.GetExtract(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return wiki_bio.ParseLead(extract.ShortDescription), nil
+}
+
+// GetTranslations follows the page's interlanguage links and fetches the extract in each of request.Langs concurrently
+func (p *WikiProviderStruct) GetTranslations(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Translations, *wiki_domain.WikiError) {
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	if err := validateTitle(request.Name); err != nil {
+		return nil, err
+	}
+	langs, err := parseLangs(request.Langs)
+	if err != nil {
+		return nil, err
+	}
+	upstream := wikiHost(request.Locale)
+	var result wiki_domain.LangLinks
+	// Pages linked from hundreds of editions need more than one call
+	if _, err := QueryAll(ctx, fmt.Sprintf(langlinksUrl, request.Locale, neturl.QueryEscape(request.Name)), QueryLimits{}, &result); err != nil {
+		return nil, err.WithUpstream(upstream)
+	}
+	if len(result.Query.Pages) == 0 || result.Query.Pages[0].Missing {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, fmt.Sprintf("Page %s does not exist", request.Name)).WithUpstream(upstream)
+	}
+	page := result.Query.Pages[0]
+	if page.Invalid {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, page.InvalidReason).WithUpstream(upstream)
+	}
+
+	titles := map[string]string{request.Locale: page.Title}
+	for _, link := range page.Langlinks {
+		titles[link.Lang] = link.Title
+	}
+	translations := &wiki_domain.Translations{
+		Title:     page.Title,
+		Language:  request.Locale,
+		Languages: map[string]*wiki_domain.Translation{},
+		Missing:   []string{},
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, locale := range langs {
+		query := request
+		query.Locale, query.Variant, query.Langs = locale.Code, locale.Variant, ""
+		key := servedLanguage(query)
+		title, ok := titles[locale.Code]
+		if !ok {
+			translations.Missing = append(translations.Missing, key)
+			continue
 		}
+		query.Name = strings.ReplaceAll(title, " ", "_")
+		wg.Add(1)
+		go func(query wiki_domain.RequestQuery, key string, title string) {
+			defer wg.Done()
+			extract, err := p.GetExtract(ctx, query)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if translations.Errors == nil {
+					translations.Errors = map[string]*wiki_domain.WikiError{}
+				}
+				translations.Errors[key] = err
+				return
+			}
+			translations.Languages[key] = &wiki_domain.Translation{Title: title, Response: extract}
+		}(query, key, title)
 	}
-	return &wiki_domain.Response{ShortDescription: matches[1]}, nil
+	wg.Wait()
+	return translations, nil
 }
 
-func (p *WikiProviderStruct) GetExtract(request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
-	url := fmt.Sprintf(extractUrl, request.Locale, request.Name)
-	response, err := wiki_client.Client.Get(url)
+// GetHistory walks the page's revisions from the newest one, following the continuation until request.Limit
+// revisions are read, and keeps the ones where the short description changed
+func (p *WikiProviderStruct) GetHistory(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.History, *wiki_domain.WikiError) {
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	if err := validateTitle(request.Name); err != nil {
+		return nil, err
+	}
+	limit := request.Limit
+	if limit == 0 {
+		limit = defaultHistoryLimit
+	}
+	upstream := wikiHost(request.Locale)
+	history := &wiki_domain.History{Language: request.Locale, Changes: []wiki_domain.DescriptionChange{}}
+	batch := historyBatch
+	if limit < batch {
+		batch = limit
+	}
+	var result wiki_domain.Content
+	complete, err := QueryAll(ctx, fmt.Sprintf(historyUrl, request.Locale, neturl.QueryEscape(request.Name), batch), QueryLimits{MaxItems: limit}, &result)
 	if err != nil {
-		log.Printf("error when trying to get wiki extract %s", err.Error())
-		return nil, &wiki_domain.WikiError{
-			Code:         http.StatusBadRequest,
-			ErrorMessage: err.Error(),
+		return nil, err.WithUpstream(upstream)
+	}
+	if len(result.Query.Pages) == 0 || result.Query.Pages[0].Missing {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, fmt.Sprintf("Page %s does not exist", request.Name)).WithUpstream(upstream)
+	}
+	if result.Query.Pages[0].Invalid {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, result.Query.Pages[0].InvalidReason).WithUpstream(upstream)
+	}
+	history.Title, history.Complete = result.Query.Pages[0].Title, complete
+	revisions := result.Query.Pages[0].Revisions
+	if len(revisions) > limit {
+		revisions, history.Complete = revisions[:limit], false
+	}
+
+	history.Revisions = len(revisions)
+	previous := ""
+	for i := len(revisions) - 1; i >= 0; i-- {
+		// Revisions whose text was deleted tell us nothing about the description
+		if revisions[i].Texthidden {
+			continue
+		}
+		description := ""
+		if matches := shortDescriptionRe.FindStringSubmatch(revisions[i].Content); len(matches) > 1 {
+			description = matches[1]
+		}
+		if len(history.Changes) > 0 && description == previous {
+			continue
+		}
+		previous = description
+		history.Changes = append(history.Changes, wiki_domain.DescriptionChange{ShortDescription: description, Revision: revisions[i].Info()})
+	}
+	return history, nil
+}
+
+// parseLangs normalizes the comma separated ?langs= list into known editions, dropping duplicates
+func parseLangs(langs string) ([]wiki_locale.Locale, *wiki_domain.WikiError) {
+	var locales []wiki_locale.Locale
+	seen := map[wiki_locale.Locale]bool{}
+	for _, tag := range strings.Split(langs, ",") {
+		if strings.TrimSpace(tag) == "" {
+			continue
+		}
+		locale, err := wiki_locale.Locales.Normalize(tag)
+		if err != nil {
+			return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidLocale, err.Error())
+		}
+		if seen[locale] {
+			continue
+		}
+		seen[locale] = true
+		locales = append(locales, locale)
+	}
+	if len(locales) == 0 {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidRequest, "langs must list at least one language")
+	}
+	if len(locales) > maxLangs {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidRequest, fmt.Sprintf("langs can list at most %d languages", maxLangs))
+	}
+	return locales, nil
+}
+
+// getClaims returns the Wikidata claims of a page's item for the properties, nil when it has none
+// or Wikidata can't be reached. Each property is asked for on its own, the whole claim set of a
+// well known person runs to hundreds of KB, so they are asked for at the same time.
+func getClaims(ctx context.Context, wikibaseItem string, properties ...string) *wiki_domain.WikidataClaims {
+	if wikibaseItem == "" {
+		return nil
+	}
+	claims := &wiki_domain.WikidataClaims{Claims: map[string][]wiki_domain.WikidataClaim{}}
+	failed := false
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, property := range properties {
+		wg.Add(1)
+		go func(property string) {
+			defer wg.Done()
+			var result wiki_domain.WikidataClaims
+			err := getJSON(ctx, fmt.Sprintf(wikidataUrl, neturl.QueryEscape(wikibaseItem), property), &result)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				// Not being able to enrich the page is no reason to fail the lookup
+				log.Printf("error when trying to get wikidata claims for %s: %s", wikibaseItem, err.ErrorMessage)
+				failed = true
+				return
+			}
+			if result.Error != nil {
+				log.Printf("wikidata claims error response for %s: %s %s", wikibaseItem, result.Error.Code, result.Error.Info)
+				failed = true
+				return
+			}
+			claims.Claims[property] = result.Claims[property]
+		}(property)
+	}
+	wg.Wait()
+	if failed {
+		return nil
+	}
+	return claims
+}
+
+// classifyPerson trusts the Wikidata P31 claims when there are some, and weighs the page's own hints otherwise
+func classifyPerson(claims *wiki_domain.WikidataClaims, categories []string, wikitext string) *bool {
+	signals := wiki_person.Signals{Categories: categories, Wikitext: wikitext}
+	if claims != nil {
+		signals.InstanceOf, signals.HasWikidata = claims.ItemIDs("P31"), true
+	}
+	return wiki_person.Classify(signals)
+}
+
+// parseName splits the title into name parts, using the labels of the P735 (given name) and P734 (family name) items when there are any
+func parseName(ctx context.Context, title string, locale string, claims *wiki_domain.WikidataClaims) *wiki_domain.PersonName {
+	name := wiki_name.Parse(title)
+	if claims == nil {
+		return name
+	}
+	given, family := claims.ItemIDs("P735"), claims.ItemIDs("P734")
+	ids := append(append([]string{}, given...), family...)
+	if len(ids) == 0 {
+		return name
+	}
+	var entities wiki_domain.WikidataEntities
+	if err := getJSON(ctx, fmt.Sprintf(labelsUrl, locale, strings.Join(ids, "%7C")), &entities); err != nil || entities.Error != nil {
+		log.Printf("error when trying to get wikidata name labels for %s", title)
+		return name
+	}
+	labels := func(ids []string) []string {
+		var values []string
+		for _, id := range ids {
+			if label := entities.Label(id, locale, "en"); label != "" {
+				values = append(values, label)
+			}
 		}
+		return values
+	}
+	wiki_name.ApplyWikidata(name, labels(given), labels(family))
+	return name
+}
+
+// checkType rejects pages that are not known to be about a human when the caller asked for type=person
+func checkType(request wiki_domain.RequestQuery, title string, isPerson *bool) *wiki_domain.WikiError {
+	if request.Type == "person" && (isPerson == nil || !*isPerson) {
+		return wiki_domain.NewTypedError(wiki_domain.ErrNotAPerson, fmt.Sprintf("%s is not known to be about a person", title))
+	}
+	return nil
+}
+
+// contentQueryUrl asks for the page's latest revision, its latest one at request.AsOf, or request.Revid
+func contentQueryUrl(request wiki_domain.RequestQuery) string {
+	if request.Revid > 0 {
+		return fmt.Sprintf(revisionUrl, request.Locale, request.Revid)
+	}
+	url := fmt.Sprintf(contentUrl, request.Locale, neturl.QueryEscape(request.Name))
+	if !request.AsOf.IsZero() {
+		url += "&rvdir=older&rvstart=" + request.AsOf.UTC().Format(time.RFC3339)
+	}
+	return url
+}
+
+// isTitleOf tells whether name is the page's title, as MediaWiki normalizes it or once its redirect is followed
+func isTitleOf(ctx context.Context, locale string, name string, title string) bool {
+	if displayTitle(name) == title {
+		return true
+	}
+	var result wiki_domain.Content
+	if err := getJSON(ctx, fmt.Sprintf(resolveUrl, locale, neturl.QueryEscape(name)), &result); err != nil || result.Error != nil {
+		return false
+	}
+	return len(result.Query.Pages) > 0 && result.Query.Pages[0].Title == title
+}
+
+// displayTitle is how MediaWiki normalizes a title: spaces for underscores and an upper case first letter
+func displayTitle(name string) string {
+	title := []rune(strings.TrimSpace(strings.ReplaceAll(name, "_", " ")))
+	if len(title) > 0 {
+		title[0] = unicode.ToUpper(title[0])
+	}
+	return string(title)
+}
+
+// getJSON fetches a MediaWiki API url and decodes the body into result
+func getJSON(ctx context.Context, url string, result interface{}) *wiki_domain.WikiError {
+	response, err := wiki_client.Client.Get(ctx, url)
+	if err != nil {
+		log.Printf("error when trying to get wiki url %s", err.Error())
+		return wiki_domain.NewTypedError(wiki_domain.ErrUpstreamUnavailable, err.Error())
 	}
 	bytes, err := io.ReadAll(response.Body)
 	if err != nil {
-		return nil, &wiki_domain.WikiError{
-			Code:         http.StatusBadRequest,
-			ErrorMessage: err.Error(),
-		}
+		return wiki_domain.NewTypedError(wiki_domain.ErrUpstreamUnavailable, err.Error())
 	}
 	defer response.Body.Close()
+	if err := checkStatusCode(response); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(bytes, result); err != nil {
+		log.Printf("error when trying to unmarshal wiki successful response: %s", err.Error())
+		return &wiki_domain.WikiError{Code: http.StatusInternalServerError, ErrorMessage: "error unmarshaling wiki fetch response"}
+	}
+	return nil
+}
 
-	error := checkStatusCode(response)
-	if error != nil {
-		return nil, error
+// normalizeLocale defaults the locale to the tenant's, or en, and swaps it for a known edition,
+// a bad locale is never allowed to pick the subdomain we call
+func normalizeLocale(request *wiki_domain.RequestQuery) *wiki_domain.WikiError {
+	if request.Locale == "" {
+		request.Locale = request.Tenant.LocaleOr(wiki_locale.DefaultLocale)
 	}
-	var result wiki_domain.Extract
-	if err := json.Unmarshal(bytes, &result); err != nil {
-		log.Printf("error when trying to unmarshal wiki extract successful response: %s", err.Error())
-		return nil, &wiki_domain.WikiError{Code: http.StatusInternalServerError, ErrorMessage: "error unmarshaling wiki fetch response"}
+	locale, err := wiki_locale.Locales.Normalize(request.Locale)
+	if err != nil {
+		return wiki_domain.NewTypedError(wiki_domain.ErrInvalidLocale, err.Error())
+	}
+	if !request.Tenant.AllowsLocale(locale.Code) {
+		return wiki_domain.NewTypedError(wiki_domain.ErrNotEnabled, fmt.Sprintf("locale %s is not enabled for tenant %s", locale.Code, request.Tenant.ID))
+	}
+	if request.Variant == "" {
+		request.Variant = locale.Variant
+	}
+	request.Locale = locale.Code
+	return nil
+}
+
+// servedLanguage is the language tag of the text we return, the variant when one was applied
+func servedLanguage(request wiki_domain.RequestQuery) string {
+	if request.Variant != "" {
+		return request.Variant
 	}
+	return request.Locale
+}
 
-	description := result.Query.Pages[0].Extract
-	return &wiki_domain.Response{ShortDescription: description}, nil
+// wikiHost is the Wikipedia edition errors are attributed to
+func wikiHost(locale string) string {
+	return locale + ".wikipedia.org"
+}
+
+func validateTitle(name string) *wiki_domain.WikiError {
+	if name == "" || invalidTitleRe.MatchString(name) {
+		return wiki_domain.NewTypedError(wiki_domain.ErrInvalidTitle, fmt.Sprintf("Invalid page title %q", name))
+	}
+	return nil
+}
+
+func logWarnings(warnings wiki_domain.WarningsType) {
+	for _, warning := range warnings.Messages() {
+		log.Printf("wiki api warning: %s", warning)
+	}
 }
 
 func checkStatusCode(response *http.Response) *wiki_domain.WikiError {
 	// The api owner can decide to change datatypes, etc. When this happen, it might affect the error format returned
 	if response.StatusCode > 299 {
-		message := fmt.Sprintf("invalid json response body: %d", response.StatusCode)
+		message := fmt.Sprintf("unexpected upstream response status: %d", response.StatusCode)
 		log.Println(message)
-		return &wiki_domain.WikiError{
-			Code:         http.StatusInternalServerError,
-			ErrorMessage: message,
+		switch response.StatusCode {
+		case http.StatusNotFound:
+			return wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, message)
+		case http.StatusTooManyRequests:
+			err := wiki_domain.NewTypedError(wiki_domain.ErrUpstreamRateLimited, message)
+			err.RetryAfter, _ = strconv.Atoi(response.Header.Get("Retry-After"))
+			return err
 		}
+		return wiki_domain.NewTypedError(wiki_domain.ErrUpstreamUnavailable, message)
 	}
 	return nil
 }
@@ -1,13 +1,16 @@
 package wiki_provider
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	wiki_client "wiki-names/clients"
 	wiki_domain "wiki-names/domains"
@@ -21,7 +24,7 @@ var getContentMockFunc func(url string) (*http.Response, error)
 type getClientMock struct{}
 
 // We are mocking the client method "Get"
-func (cm *getClientMock) Get(request string) (*http.Response, error) {
+func (cm *getClientMock) Get(ctx context.Context, request string) (*http.Response, error) {
 	return getContentMockFunc(request)
 }
 
@@ -35,7 +38,7 @@ func TestGetContentSummaryNoError(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContentSummary(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.Nil(t, err)
 	assert.NotNil(t, response)
 	assert.EqualValues(t, response.ShortDescription, "Canadian computer scientist")
@@ -52,7 +55,7 @@ func TestGetContentNoError(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContent(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.NotNil(t, response)
 	assert.Nil(t, err)
 
@@ -86,7 +89,7 @@ func TestGetContentSummaryNoShortDecription(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContent(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.NotNil(t, response)
 	assert.Nil(t, err)
 	assert.Contains(t, "{{Descripcion corto|Canadian computer scientist}}\n{{Use mdy dates|date=March 2019}}}", response.Query.Pages[0].Revisions[0].Content)
@@ -122,7 +125,7 @@ func TestGetContentSummaryMissingPageRevisions(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContentSummary(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.Nil(t, response)
 	assert.NotNil(t, err)
 	assert.EqualValues(t, 404, err.Code)
@@ -140,7 +143,7 @@ func TestGetContentInvalidJsonError(t *testing.T) {
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
 	request := wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"}
-	got, err := WikiProvider.GetContentSummary(request)
+	got, err := WikiProvider.GetContentSummary(context.Background(), request)
 
 	assert.Nil(t, got)
 	assert.NotNil(t, err)
@@ -158,12 +161,13 @@ func TestGetContentInvalidWikiPermissions(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	got, err := WikiProvider.GetContent(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	got, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 
 	assert.NotNil(t, err)
 	assert.Nil(t, got)
-	assert.EqualValues(t, http.StatusInternalServerError, err.Code)
-	assert.EqualValues(t, "invalid json response body: 403", err.ErrorMessage)
+	assert.EqualValues(t, http.StatusBadGateway, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrUpstreamUnavailable, err.ErrorCode)
+	assert.EqualValues(t, "unexpected upstream response status: 403", err.ErrorMessage)
 }
 
 func TestGetContentBadRequest(t *testing.T) {
@@ -175,11 +179,11 @@ func TestGetContentBadRequest(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContent(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.NotNil(t, err)
 	assert.Nil(t, response)
-	assert.EqualValues(t, http.StatusInternalServerError, err.Code)
-	assert.EqualValues(t, "invalid json response body: 400", err.ErrorMessage)
+	assert.EqualValues(t, http.StatusBadGateway, err.Code)
+	assert.EqualValues(t, "unexpected upstream response status: 400", err.ErrorMessage)
 }
 
 func TestGetContentSummaryInvalidHttpBodyResponse(t *testing.T) {
@@ -191,11 +195,11 @@ func TestGetContentSummaryInvalidHttpBodyResponse(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContentSummary(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.NotNil(t, err)
 	assert.Nil(t, response)
-	assert.EqualValues(t, http.StatusInternalServerError, err.Code)
-	assert.EqualValues(t, "invalid json response body: 400", err.ErrorMessage)
+	assert.EqualValues(t, http.StatusBadGateway, err.Code)
+	assert.EqualValues(t, "unexpected upstream response status: 400", err.ErrorMessage)
 }
 
 type mockReadCloser struct {
@@ -228,10 +232,10 @@ func TestGetContentInvalidWikiResponseBody(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContent(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.NotNil(t, err)
 	assert.Nil(t, response)
-	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	assert.EqualValues(t, http.StatusBadGateway, err.Code)
 	assert.EqualValues(t, "error reading", err.ErrorMessage)
 }
 
@@ -245,10 +249,10 @@ func TestGetContentSummaryInvalidWikiResponseBody2(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContentSummary(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.Nil(t, response)
 	assert.NotNil(t, err)
-	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	assert.EqualValues(t, http.StatusBadGateway, err.Code)
 	assert.EqualValues(t, "invalid argument", err.ErrorMessage)
 }
 
@@ -263,15 +267,178 @@ func TestGetContentSummary404(t *testing.T) {
 	}
 	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
 
-	response, err := WikiProvider.GetContentSummary(wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
 	assert.Nil(t, response)
 	assert.NotNil(t, err)
 	assert.EqualValues(t, http.StatusNotFound, err.Code)
 	assert.EqualValues(t, "Missing page revisions in json response body", err.ErrorMessage)
 }
 
-// I did not add unit tests for GetExtract API call.  They would be very similar to the GetContent unit tests.
-// Given more time I would add them in a commercial setting.
+func TestGetContentSummaryMissingShortDescription(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","revisions":[{"contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Use mdy dates|date=March 2019}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "en"})
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusNotFound, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrPageMissingDescription, err.ErrorCode)
+}
+
+func TestGetContentSummaryMissingPage(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"pages":[{"ns":0,"title":"Bob Smithers Jr","missing":true}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smithers_Jr", Locale: "en"})
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusNotFound, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrPageNotFound, err.ErrorCode)
+}
+
+func TestGetContentSummaryDisambiguation(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":1,"ns":0,"title":"John Smith","revisions":[{"contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Short description|Topics referred to by the same term}}\n'''John Smith''' may refer to:\n{{hndis|Smith, John}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "John_Smith", Locale: "en"})
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrAmbiguous, err.ErrorCode)
+}
+
+func TestGetContentEscapesName(t *testing.T) {
+	var urls []string
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		urls = append(urls, url)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":1,"ns":0,"title":"AT&T","revisions":[{"content":"{{Short description|American telecommunications company}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	_, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "AT&T&rvprop=user", Locale: "en"})
+	assert.NotEmpty(t, urls)
+	assert.Nil(t, err)
+	// The name stays in the titles parameter, it can't add one of its own
+	assert.Contains(t, urls[0], "titles=AT%26T%26rvprop%3Duser&")
+	assert.NotContains(t, urls[0], "&rvprop=user&")
+}
+
+func TestGetContentMediaWikiError(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"error":{"code":"ratelimited","info":"You've exceeded your rate limit. Please wait some time and try again."},"servedby":"mw1234"}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusServiceUnavailable, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrUpstreamRateLimited, err.ErrorCode)
+	assert.Contains(t, err.ErrorMessage, "ratelimited")
+}
+
+func TestGetContentUpstreamRateLimited(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": []string{"30"}},
+			Body:       io.NopCloser(strings.NewReader(`Too many requests`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, wiki_domain.ErrUpstreamRateLimited, err.ErrorCode)
+	assert.EqualValues(t, 30, err.RetryAfter)
+}
+
+func TestGetContentInvalidTitle(t *testing.T) {
+	response, err := WikiProvider.GetContent(context.Background(), wiki_domain.RequestQuery{Name: "Bob{{Smith}}", Locale: "en"})
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrInvalidTitle, err.ErrorCode)
+}
+
+func TestGetExtractNoError(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		assert.Contains(t, url, "https://fr.wikipedia.org/")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","extract":"Yoshua Bengio est un chercheur canadien."}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "fr"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Yoshua Bengio est un chercheur canadien.", response.ShortDescription)
+}
+
+func TestGetExtractIntro(t *testing.T) {
+	var requested string
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		requested = url
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","extract":"Yoshua Bengio is a computer scientist.\nHe was born in Paris."}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	_, err := WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "en", Sentences: 1})
+	assert.Nil(t, err)
+	assert.Contains(t, requested, "&exintro=1")
+	// More than one paragraph can go past the lead
+	_, err = WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "en", Paragraphs: 2})
+	assert.Nil(t, err)
+	assert.NotContains(t, requested, "exintro")
+}
+
+func TestGetExtractMissingPage(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"pages":[]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "en"})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrPageNotFound, err.ErrorCode)
+}
+
+func TestGetExtractInvalidLocale(t *testing.T) {
+	response, err := WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Bob_Smith", Locale: "evil.example.com/"})
+	assert.Nil(t, response)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrInvalidLocale, err.ErrorCode)
+}
 
 func Test_getClientMock_Get(t *testing.T) {
 	type args struct {
@@ -288,7 +455,7 @@ func Test_getClientMock_Get(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := tt.cm.Get(tt.args.request)
+			got, err := tt.cm.Get(context.Background(), tt.args.request)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getClientMock.Get() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -343,3 +510,371 @@ func Test_mockReadCloser_Close(t *testing.T) {
 		})
 	}
 }
+
+func TestGetExtractNormalizesLocale(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		assert.True(t, strings.HasPrefix(url, "https://zh.wikipedia.org/"))
+		assert.True(t, strings.HasSuffix(url, "&variant=zh-hant"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"約書亞·本吉奧","extract":"約書亞·本吉奧是加拿大計算機科學家。"}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "約書亞·本吉奧", Locale: "zh-Hant"})
+	assert.Nil(t, err)
+	assert.NotNil(t, response)
+}
+
+func TestGetExtractUnknownLocale(t *testing.T) {
+	response, err := WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Foo", Locale: "enn"})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrInvalidLocale, err.ErrorCode)
+	assert.Contains(t, err.ErrorMessage, "unknown Wikipedia language edition")
+}
+
+func TestGetExtractLimits(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		assert.NotContains(t, url, "exsentences")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","extract":"Yoshua Bengio (born March 5, 1964) is a Canadian computer scientist. He is a professor at the Université de Montréal and scientific director of Mila. He received the 2018 A.M. Turing Award.\n\n== Early life ==\nBengio was born in Paris, France."}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "en"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Yoshua Bengio (born March 5, 1964) is a Canadian computer scientist. He is a professor at the Université de Montréal and scientific director of Mila.", response.ShortDescription)
+
+	response, err = WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "en", Sentences: 1})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Yoshua Bengio (born March 5, 1964) is a Canadian computer scientist.", response.ShortDescription)
+
+	response, err = WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "en", Paragraphs: 2})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(response.ShortDescription, "\nBengio was born in Paris, France."))
+}
+
+func TestGetBio(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		assert.True(t, strings.HasPrefix(url, "https://en.wikipedia.org/"))
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","extract":"Yoshua Bengio OC FRS FRSC (born March 5, 1964) is a Canadian computer scientist, most noted for his work on artificial neural networks and deep learning. He is a professor at the Université de Montréal."}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetBio(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "fr"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Yoshua Bengio", response.Name.Value)
+	assert.EqualValues(t, "1964-03-05", response.BirthDate.Value)
+	assert.EqualValues(t, "Canadian", response.Nationality.Value)
+	assert.NotContains(t, response.LeadSentence, "professor")
+}
+
+func TestGetContentSummaryIsPerson(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		if strings.HasPrefix(url, "https://www.wikidata.org/") {
+			assert.Contains(t, url, "entity=Q3572699&property=P")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"claims":{"P31":[{"mainsnak":{"snaktype":"value","property":"P31","datavalue":{"value":{"entity-type":"item","numeric-id":5,"id":"Q5"},"type":"wikibase-entityid"}}}]}}`)),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","pageprops":{"wikibase_item":"Q3572699"},"revisions":[{"contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Short description|Canadian computer scientist}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Type: "person"})
+	assert.Nil(t, err)
+	assert.True(t, *response.IsPerson)
+}
+
+func TestGetContentSummaryTypePersonRejectsFilm(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":1,"ns":0,"title":"Heat (1995 film)","categories":[{"ns":14,"title":"Category:1995 films"}],"revisions":[{"contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Short description|1995 film by Michael Mann}}\n{{Infobox film\n| name = Heat}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Heat_(1995_film)"})
+	assert.Nil(t, err)
+	assert.False(t, *response.IsPerson)
+
+	response, err = WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Heat_(1995_film)", Type: "person"})
+	assert.Nil(t, response)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrNotAPerson, err.ErrorCode)
+}
+
+func TestGetContentSummaryNameFromWikidata(t *testing.T) {
+	var mu sync.Mutex
+	var properties []string
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		// Only the properties we use are asked for, each on its own and only once
+		claims := map[string]string{
+			"P31":  `"P31":[{"mainsnak":{"snaktype":"value","property":"P31","datavalue":{"value":{"id":"Q5"},"type":"wikibase-entityid"}}}]`,
+			"P735": `"P735":[{"mainsnak":{"snaktype":"value","property":"P735","datavalue":{"value":{"id":"Q4160262"},"type":"wikibase-entityid"}}}]`,
+			"P734": `"P734":[{"mainsnak":{"snaktype":"value","property":"P734","datavalue":{"value":{"id":"Q1363539"},"type":"wikibase-entityid"}}},` +
+				`{"mainsnak":{"snaktype":"value","property":"P734","datavalue":{"value":{"id":"Q2007485"},"type":"wikibase-entityid"}}}]`,
+		}
+		if strings.Contains(url, "action=wbgetclaims") {
+			property := url[strings.LastIndex(url, "&property=")+len("&property="):]
+			assert.Contains(t, claims, property)
+			mu.Lock()
+			properties = append(properties, property)
+			mu.Unlock()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"claims":{` + claims[property] + `}}`)),
+			}, nil
+		}
+		if strings.Contains(url, "action=wbgetentities") {
+			assert.Contains(t, url, "ids=Q4160262%7CQ1363539%7CQ2007485")
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body: io.NopCloser(strings.NewReader(`{"entities":{` +
+					`"Q4160262":{"labels":{"en":{"language":"en","value":"Gabriel"}}},` +
+					`"Q1363539":{"labels":{"en":{"language":"en","value":"García"}}},` +
+					`"Q2007485":{"labels":{"en":{"language":"en","value":"Márquez"}}}}}`)),
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":1,"ns":0,"title":"Gabriel García Márquez","pageprops":{"wikibase_item":"Q5878"},"revisions":[{"contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Short description|Colombian writer (1927–2014)}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Gabriel_García_Márquez"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Gabriel"}, response.Name.Given)
+	assert.EqualValues(t, []string{"García", "Márquez"}, response.Name.Family)
+	assert.EqualValues(t, "wikidata", response.Name.Source)
+	assert.ElementsMatch(t, []string{"P31", "P735", "P734"}, properties)
+}
+
+func TestGetTranslations(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		var body string
+		switch {
+		case strings.Contains(url, "prop=langlinks"):
+			assert.True(t, strings.HasPrefix(url, "https://en.wikipedia.org/"))
+			body = `{"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","langlinks":[{"lang":"fr","title":"Yoshua Bengio"},{"lang":"ja","title":"ヨシュア・ベンジオ"}]}]}}`
+		case strings.HasPrefix(url, "https://fr.wikipedia.org/"):
+			assert.Contains(t, url, "titles=Yoshua_Bengio")
+			body = `{"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","extract":"Yoshua Bengio, né le 5 mars 1964 à Paris, est un chercheur en intelligence artificielle canadien. Il est professeur."}]}}`
+		case strings.HasPrefix(url, "https://ja.wikipedia.org/"):
+			assert.Contains(t, url, "titles=%E3%83%A8%E3%82%B7%E3%83%A5%E3%82%A2%E3%83%BB%E3%83%99%E3%83%B3%E3%82%B8%E3%82%AA")
+			body = `{"query":{"pages":[{"pageid":2,"ns":0,"title":"ヨシュア・ベンジオ","extract":"ヨシュア・ベンジオはカナダの計算機科学者。"}]}}`
+		default:
+			t.Errorf("unexpected url %s", url)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetTranslations(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Langs: "fr,de,ja,fr", Sentences: 1})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Yoshua Bengio", response.Title)
+	assert.EqualValues(t, "en", response.Language)
+	assert.EqualValues(t, []string{"de"}, response.Missing)
+	assert.Len(t, response.Languages, 2)
+	assert.EqualValues(t, "Yoshua Bengio, né le 5 mars 1964 à Paris, est un chercheur en intelligence artificielle canadien.", response.Languages["fr"].ShortDescription)
+	assert.EqualValues(t, "ヨシュア・ベンジオ", response.Languages["ja"].Title)
+	assert.EqualValues(t, "ja", response.Languages["ja"].Language)
+}
+
+func TestGetTranslationsInvalidLangs(t *testing.T) {
+	_, err := WikiProvider.GetTranslations(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Langs: "fr,xx-notreal"})
+	assert.EqualValues(t, wiki_domain.ErrInvalidLocale, err.ErrorCode)
+
+	_, err = WikiProvider.GetTranslations(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Langs: " , "})
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrInvalidRequest, err.ErrorCode)
+}
+
+func TestGetContentSummaryAsOf(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		assert.Contains(t, url, "titles=Yoshua_Bengio")
+		assert.Contains(t, url, "&rvdir=older&rvstart=2021-06-01T00:00:00Z")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","revisions":[{"revid":1025871226,"parentid":1024409432,"user":"Example editor","userid":42,"timestamp":"2021-05-29T16:02:44Z","comment":"update","contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Short description|Canadian computer scientist}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	asOf, _ := time.Parse(time.RFC3339, "2021-06-01T02:00:00+02:00")
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", AsOf: asOf})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Canadian computer scientist", response.ShortDescription)
+	assert.EqualValues(t, 1025871226, response.Revision.Revid)
+	assert.EqualValues(t, 1024409432, response.Revision.Parentid)
+	assert.EqualValues(t, "Example editor", response.Revision.User)
+	assert.EqualValues(t, "2021-05-29T16:02:44Z", response.Revision.Timestamp.Format(time.RFC3339))
+}
+
+func TestGetContentSummaryRevid(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		if strings.Contains(url, "redirects=1") {
+			// Bengio redirects to Yoshua Bengio, Geoffrey Hinton is a page of its own
+			title := "Geoffrey Hinton"
+			if strings.HasSuffix(url, "titles=Bengio") {
+				title = "Yoshua Bengio"
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":1,"ns":0,"title":"` + title + `"}]}}`)),
+			}, nil
+		}
+		assert.Contains(t, url, "revids=1025871226")
+		assert.NotContains(t, url, "titles=")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","revisions":[{"revid":1025871226,"parentid":1024409432,"user":"Example editor","timestamp":"2021-05-29T16:02:44Z","contentformat":"text/x-wiki","contentmodel":"wikitext","content":"{{Short description|Canadian computer scientist}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "yoshua_Bengio", Revid: 1025871226})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1025871226, response.Revision.Revid)
+
+	// Through a redirect to the page
+	response, err = WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Bengio", Revid: 1025871226})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1025871226, response.Revision.Revid)
+
+	// The revision belongs to another page
+	response, err = WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Geoffrey_Hinton", Revid: 1025871226})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrPageNotFound, err.ErrorCode)
+}
+
+func TestGetContentSummaryBadRevid(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"badrevids":{"999999999999":{"revid":999999999999,"missing":true}}}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	response, err := WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Revid: 999999999999})
+	assert.Nil(t, response)
+	assert.EqualValues(t, http.StatusNotFound, err.Code)
+
+	_, err = WikiProvider.GetContentSummary(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Revid: 1, AsOf: time.Now()})
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	assert.EqualValues(t, wiki_domain.ErrInvalidRequest, err.ErrorCode)
+}
+
+func TestGetHistory(t *testing.T) {
+	calls := 0
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		calls++
+		var body string
+		if calls == 1 {
+			assert.NotContains(t, url, "rvcontinue")
+			assert.Contains(t, url, "rvlimit=50&")
+			assert.Contains(t, url, "&rvsection=0&")
+			body = `{"continue":{"rvcontinue":"20210101000000|4","continue":"||"},"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","revisions":[` +
+				`{"revid":5,"parentid":4,"user":"Editor","timestamp":"2022-01-01T00:00:00Z","content":"{{Short description|Canadian computer scientist}}"},` +
+				`{"revid":4,"parentid":3,"user":"Patroller","timestamp":"2021-01-01T00:00:00Z","comment":"Reverted","content":"{{Short description|Canadian computer scientist}}"}]}]}}`
+		} else {
+			assert.Contains(t, url, "&continue=%7C%7C&rvcontinue=20210101000000%7C4")
+			body = `{"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","revisions":[` +
+				`{"revid":3,"parentid":2,"user":"Vandal","timestamp":"2020-12-31T00:00:00Z","content":"{{Short description|Something rude}}"},` +
+				`{"revid":2,"parentid":1,"user":"Editor","timestamp":"2019-01-01T00:00:00Z","content":"{{Short description|Computer scientist}}"},` +
+				`{"revid":6,"parentid":1,"texthidden":true,"timestamp":"2018-06-01T00:00:00Z"},` +
+				`{"revid":1,"parentid":0,"user":"Creator","timestamp":"2018-01-01T00:00:00Z","content":"'''Yoshua Bengio''' is a computer scientist."}]}]}}`
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	history, err := WikiProvider.GetHistory(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, calls)
+	assert.True(t, history.Complete)
+	assert.EqualValues(t, 6, history.Revisions)
+	var descriptions []string
+	var revids []int64
+	for _, change := range history.Changes {
+		descriptions = append(descriptions, change.ShortDescription)
+		revids = append(revids, change.Revision.Revid)
+	}
+	assert.EqualValues(t, []string{"", "Computer scientist", "Something rude", "Canadian computer scientist"}, descriptions)
+	assert.EqualValues(t, []int64{1, 2, 3, 4}, revids)
+	assert.EqualValues(t, "Vandal", history.Changes[2].Revision.User)
+}
+
+func TestGetHistoryLimit(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		assert.Contains(t, url, "rvlimit=2&")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: io.NopCloser(strings.NewReader(`{"continue":{"rvcontinue":"20210101000000|4","continue":"||"},"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","revisions":[` +
+				`{"revid":5,"parentid":4,"timestamp":"2022-01-01T00:00:00Z","content":"{{Short description|Canadian computer scientist}}"},` +
+				`{"revid":4,"parentid":3,"timestamp":"2021-01-01T00:00:00Z","content":"{{Short description|Computer scientist}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	history, err := WikiProvider.GetHistory(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Limit: 2})
+	assert.Nil(t, err)
+	assert.False(t, history.Complete)
+	assert.Len(t, history.Changes, 2)
+}
+
+func TestGetHistoryCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		calls++
+		// The client hangs up while the first batch is read
+		cancel()
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body: io.NopCloser(strings.NewReader(`{"continue":{"rvcontinue":"20210101000000|4","continue":"||"},"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","revisions":[` +
+				`{"revid":5,"parentid":4,"timestamp":"2022-01-01T00:00:00Z","content":"{{Short description|Canadian computer scientist}}"}]}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+
+	history, err := WikiProvider.GetHistory(ctx, wiki_domain.RequestQuery{Name: "Yoshua_Bengio"})
+	assert.Nil(t, history)
+	assert.EqualValues(t, wiki_domain.ErrUpstreamUnavailable, err.ErrorCode)
+	assert.EqualValues(t, 1, calls)
+}
+
+func TestGetExtractTenantLocales(t *testing.T) {
+	getContentMockFunc = func(url string) (*http.Response, error) {
+		assert.Contains(t, url, "https://fr.wikipedia.org/")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Yoshua Bengio","extract":"Yoshua Bengio est un chercheur canadien."}]}}`)),
+		}, nil
+	}
+	wiki_client.Client = &getClientMock{} // without this line, the real api is fired
+	tenant := &wiki_domain.Tenant{ID: "acme", DefaultLocale: "fr", AllowedLocales: []string{"fr", "de"}}
+
+	// The tenant's default locale replaces en
+	response, err := WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Tenant: tenant})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "fr", response.Language)
+
+	response, err = WikiProvider.GetExtract(context.Background(), wiki_domain.RequestQuery{Name: "Yoshua_Bengio", Locale: "EN", Tenant: tenant})
+	assert.Nil(t, response)
+	assert.EqualValues(t, wiki_domain.ErrNotEnabled, err.ErrorCode)
+	assert.EqualValues(t, http.StatusForbidden, err.Code)
+}
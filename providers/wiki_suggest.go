@@ -0,0 +1,50 @@
+package wiki_provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	wiki_domain "wiki-names/domains"
+)
+
+const suggestUrl = "https://%s.wikipedia.org/w/api.php?action=query&format=json&formatversion=2&generator=prefixsearch&gpssearch=%s&gpslimit=%d&prop=pageprops&ppprop=wikibase-shortdesc"
+
+// defaultSuggestLimit is how many suggestions are returned when no limit is asked for, maxSuggestLimit the most prefixsearch gives
+const (
+	defaultSuggestLimit = 10
+	maxSuggestLimit     = 50
+)
+
+// GetSuggestions lists the pages whose title starts with query.Prefix, best match first, with their short descriptions
+func (p *WikiProviderStruct) GetSuggestions(ctx context.Context, query wiki_domain.SuggestQuery) (*wiki_domain.Suggestions, *wiki_domain.WikiError) {
+	request := wiki_domain.RequestQuery{Locale: query.Locale}
+	if err := normalizeLocale(&request); err != nil {
+		return nil, err
+	}
+	prefix := strings.TrimSpace(query.Prefix)
+	if prefix == "" {
+		return nil, &wiki_domain.WikiError{Code: http.StatusBadRequest, ErrorMessage: "prefix can't be empty"}
+	}
+	if query.Limit < 0 || query.Limit > maxSuggestLimit {
+		return nil, &wiki_domain.WikiError{Code: http.StatusBadRequest, ErrorMessage: fmt.Sprintf("limit must be between 1 and %d", maxSuggestLimit)}
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultSuggestLimit
+	}
+	var result wiki_domain.PrefixSearch
+	if err := getJSON(ctx, fmt.Sprintf(suggestUrl, request.Locale, url.QueryEscape(prefix), query.Limit), &result); err != nil {
+		return nil, err.WithUpstream(wikiHost(request.Locale))
+	}
+	// Pages come back in page ID order, index is their rank in the search
+	pages := result.Query.Pages
+	sort.SliceStable(pages, func(i, j int) bool { return pages[i].Index < pages[j].Index })
+	suggestions := &wiki_domain.Suggestions{Language: request.Locale, Suggestions: []wiki_domain.Suggestion{}}
+	for _, page := range pages {
+		suggestions.Suggestions = append(suggestions.Suggestions, wiki_domain.Suggestion{Title: page.Title, ShortDescription: page.Pageprops.ShortDescription})
+	}
+	return suggestions, nil
+}
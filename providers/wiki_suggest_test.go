@@ -0,0 +1,51 @@
+package wiki_provider
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	wiki_domain "wiki-names/domains"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSuggestions(t *testing.T) {
+	urls := mockResponses(t,
+		`{"batchcomplete":true,"query":{"pages":[{"pageid":47749536,"ns":0,"title":"Yoshua Bengio","index":1,"pageprops":{"wikibase-shortdesc":"Canadian computer scientist"}},{"pageid":60000000,"ns":0,"title":"Yoshua Bengio (disambiguation)","index":2}]}}`,
+	)
+
+	response, err := WikiProvider.GetSuggestions(context.Background(), wiki_domain.SuggestQuery{Prefix: "Yoshua Ben", Locale: "en"})
+	assert.Nil(t, err)
+	assert.Contains(t, (*urls)[0], "gpssearch=Yoshua+Ben&gpslimit=10")
+	assert.EqualValues(t, "en", response.Language)
+	assert.EqualValues(t, []wiki_domain.Suggestion{
+		{Title: "Yoshua Bengio", ShortDescription: "Canadian computer scientist"},
+		{Title: "Yoshua Bengio (disambiguation)"},
+	}, response.Suggestions)
+}
+
+func TestGetSuggestionsRanksByIndex(t *testing.T) {
+	mockResponses(t, `{"batchcomplete":true,"query":{"pages":[{"pageid":1,"ns":0,"title":"Alan Kay","index":2},{"pageid":2,"ns":0,"title":"Alan Turing","index":1}]}}`)
+
+	response, err := WikiProvider.GetSuggestions(context.Background(), wiki_domain.SuggestQuery{Prefix: "Alan", Limit: 2})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Alan Turing", response.Suggestions[0].Title)
+	assert.EqualValues(t, "Alan Kay", response.Suggestions[1].Title)
+}
+
+func TestGetSuggestionsNoMatch(t *testing.T) {
+	mockResponses(t, `{"batchcomplete":true}`)
+
+	response, err := WikiProvider.GetSuggestions(context.Background(), wiki_domain.SuggestQuery{Prefix: "Zzzzqx"})
+	assert.Nil(t, err)
+	assert.Empty(t, response.Suggestions)
+}
+
+func TestGetSuggestionsBadRequest(t *testing.T) {
+	_, err := WikiProvider.GetSuggestions(context.Background(), wiki_domain.SuggestQuery{Prefix: "  "})
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+
+	_, err = WikiProvider.GetSuggestions(context.Background(), wiki_domain.SuggestQuery{Prefix: "Alan", Limit: 500})
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+}
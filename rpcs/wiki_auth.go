@@ -0,0 +1,149 @@
+package wiki_rpc
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+	wiki_middleware "wiki-names/middlewares"
+	wiki_tenant "wiki-names/tenants"
+	wiki_token "wiki-names/tokens"
+)
+
+// Auth checks each call the way the HTTP middleware checks a request: the API key sent in the
+// x-api-key metadata or as a wk_ bearer token, the JWT sent in authorization, the tenant they
+// belong to, the method's scope and its rate limit. Like on HTTP, a nil Keys, Verifier or Limiter
+// skips its check, so the two servers are exactly as open as each other.
+type Auth struct {
+	Keys         *wiki_key.Manager
+	KeysRequired bool
+	Verifier     *wiki_token.Verifier
+	Tenants      *wiki_tenant.Registry
+	Limiter      *wiki_middleware.RateLimiter
+}
+
+// methodPolicy is the scope a method needs, and the route it is rate limited as: the HTTP route
+// serving the same lookup, so RATE_LIMIT_ROUTES covers both servers
+type methodPolicy struct {
+	scope string
+	route string
+}
+
+// methodPolicies are the methods Auth checks. Health checking and reflection aren't in there, load
+// balancers and grpcurl use them without credentials.
+var methodPolicies = map[string]methodPolicy{
+	"/" + ServiceName + "/GetSummary":  {wiki_domain.ScopeLookupRead, "/search/:name"},
+	"/" + ServiceName + "/GetExtract":  {wiki_domain.ScopeLookupRead, "/extract/:name"},
+	"/" + ServiceName + "/Suggest":     {wiki_domain.ScopeLookupRead, "/" + ServiceName + "/Suggest"},
+	"/" + ServiceName + "/BatchLookup": {wiki_domain.ScopeBatchWrite, "/search/batch"},
+}
+
+// serverOptions installs the checks on every unary and streaming call
+func (a *Auth) serverOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{grpc.ChainUnaryInterceptor(a.unary), grpc.ChainStreamInterceptor(a.stream)}
+}
+
+func (a *Auth) unary(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return handler(ctx, request)
+}
+
+func (a *Auth) stream(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return toStatus(err)
+	}
+	return handler(server, &authorizedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorizedStream hands the handler the context carrying the call's tenant
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorize returns ctx with the caller's tenant, or the error the call is refused with
+func (a *Auth) authorize(ctx context.Context, method string) (context.Context, *wiki_domain.WikiError) {
+	policy, ok := methodPolicies[method]
+	if !ok {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	token := firstValue(md, strings.ToLower(wiki_middleware.APIKeyHeader))
+	bearer := strings.TrimPrefix(firstValue(md, "authorization"), "Bearer ")
+	if token == "" && strings.HasPrefix(bearer, wiki_key.KeyPrefix) {
+		token = bearer
+	}
+
+	var key *wiki_domain.APIKey
+	if a.Keys != nil {
+		if token == "" && a.KeysRequired {
+			return ctx, wiki_domain.NewTypedError(wiki_domain.ErrInvalidAPIKey, "an API key is required, send it in "+strings.ToLower(wiki_middleware.APIKeyHeader))
+		}
+		if token != "" {
+			var err *wiki_domain.WikiError
+			if key, err = a.Keys.Authenticate(token); err != nil {
+				return ctx, err
+			}
+			if _, err = a.Keys.Allow(key); err != nil {
+				return ctx, err
+			}
+		}
+	}
+	var claims *wiki_domain.Claims
+	if a.Verifier != nil && !strings.HasPrefix(bearer, wiki_key.KeyPrefix) && strings.Count(bearer, ".") == 2 {
+		var err *wiki_domain.WikiError
+		if claims, err = a.Verifier.Verify(bearer); err != nil {
+			return ctx, err
+		}
+	}
+	if a.Tenants != nil {
+		if tenant := wiki_middleware.ResolveTenant(a.Tenants, key, claims); tenant != nil {
+			ctx = wiki_domain.WithTenant(ctx, tenant)
+		}
+	}
+	if a.Limiter != nil {
+		if err := a.Limiter.Check(policy.route, a.Limiter.Client(key, claims, peerIP(ctx))); err != nil {
+			return ctx, err
+		}
+	}
+	// Scopes are only checked with bearer tokens configured, as on HTTP
+	if a.Verifier != nil {
+		if err := wiki_middleware.CheckScope(policy.scope, key, claims); err != nil {
+			return ctx, err
+		}
+	}
+	return ctx, nil
+}
+
+func firstValue(md metadata.MD, name string) string {
+	if values := md.Get(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerIP is the address the call came from, without its port
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return host
+	}
+	return p.Addr.String()
+}
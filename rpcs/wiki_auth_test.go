@@ -0,0 +1,80 @@
+package wiki_rpc
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	wiki_domain "wiki-names/domains"
+	wiki_key "wiki-names/keys"
+	wiki_middleware "wiki-names/middlewares"
+	wiki_proto "wiki-names/protos"
+	wiki_tenant "wiki-names/tenants"
+)
+
+func setupAuth(t *testing.T) (*Auth, string) {
+	store, err := wiki_key.NewFileStore(filepath.Join(t.TempDir(), "keys.json"))
+	assert.Nil(t, err)
+	keys := wiki_key.NewManager(store, wiki_key.NewMemoryCounters())
+	issued, _ := keys.Issue(wiki_domain.APIKeyRequest{Tenant: "acme"})
+	tenants := wiki_tenant.NewRegistry()
+	assert.Nil(t, tenants.Set(&wiki_domain.Tenant{ID: "acme", MaxBatchSize: 1}))
+	limiter := wiki_middleware.NewRateLimiter(wiki_key.NewMemoryCounters(), wiki_middleware.LimitByAPIKey)
+	limiter.Default = wiki_middleware.RateLimit{Requests: 2, Window: time.Minute}
+	return &Auth{Keys: keys, KeysRequired: true, Tenants: tenants, Limiter: limiter}, issued.Key
+}
+
+func TestAuthUnary(t *testing.T) {
+	auth, key := setupAuth(t)
+	client, conn, mock := setupServerWithAuth(t, auth)
+
+	_, err := client.GetSummary(context.Background(), &wiki_proto.LookupRequest{Name: "Yoshua Bengio"})
+	assert.EqualValues(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.GetSummary(metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "wk_nope"), &wiki_proto.LookupRequest{Name: "Yoshua Bengio"})
+	assert.EqualValues(t, codes.Unauthenticated, status.Code(err))
+
+	// The key picks the tenant the lookup is made for, and counts against the route's limit
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+key)
+	_, err = client.GetSummary(ctx, &wiki_proto.LookupRequest{Name: "Yoshua Bengio"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "acme", mock.requests[0].Tenant.ID)
+	_, err = client.GetSummary(ctx, &wiki_proto.LookupRequest{Name: "Yoshua Bengio"})
+	assert.Nil(t, err)
+	_, err = client.GetSummary(ctx, &wiki_proto.LookupRequest{Name: "Yoshua Bengio"})
+	assert.EqualValues(t, codes.ResourceExhausted, status.Code(err))
+
+	// Health checks need no credentials
+	response, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: ServiceName})
+	assert.Nil(t, err)
+	assert.EqualValues(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+}
+
+func TestAuthStream(t *testing.T) {
+	auth, key := setupAuth(t)
+	client, _, _ := setupServerWithAuth(t, auth)
+
+	stream, err := client.BatchLookup(context.Background(), &wiki_proto.BatchLookupRequest{Names: []string{"Yoshua Bengio"}})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.EqualValues(t, codes.Unauthenticated, status.Code(err))
+
+	// The tenant's batch size caps the names
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	stream, err = client.BatchLookup(ctx, &wiki_proto.BatchLookupRequest{Names: []string{"Yoshua Bengio", "Geoffrey Hinton"}})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.EqualValues(t, codes.InvalidArgument, status.Code(err))
+
+	stream, err = client.BatchLookup(ctx, &wiki_proto.BatchLookupRequest{Names: []string{"Yoshua Bengio"}})
+	assert.Nil(t, err)
+	result, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Canadian computer scientist", result.GetSummary().ShortDescription)
+}
@@ -0,0 +1,245 @@
+package wiki_rpc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+
+	wiki_domain "wiki-names/domains"
+	wiki_proto "wiki-names/protos"
+	wiki_provider "wiki-names/providers"
+)
+
+// ServiceName is the name the service is registered and health checked under
+const ServiceName = "wikinames.v1.WikiNames"
+
+// maxBatchNames caps the names of one BatchLookup, batchWorkers how many of them are looked up at once
+const (
+	maxBatchNames = 500
+	batchWorkers  = 8
+)
+
+type wikiNamesServer struct {
+	wiki_proto.UnimplementedWikiNamesServer
+}
+
+// NewServer builds the gRPC server with the lookup service, health checking and reflection registered,
+// checking the lookup calls with auth when it isn't nil. The health server is returned so shutdown can
+// report NOT_SERVING before draining.
+func NewServer(auth *Auth, options ...grpc.ServerOption) (*grpc.Server, *health.Server) {
+	if auth != nil {
+		options = append(options, auth.serverOptions()...)
+	}
+	server := grpc.NewServer(options...)
+	wiki_proto.RegisterWikiNamesServer(server, &wikiNamesServer{})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	reflection.Register(server)
+	return server, healthServer
+}
+
+func (s *wikiNamesServer) GetSummary(ctx context.Context, in *wiki_proto.LookupRequest) (*wiki_proto.Summary, error) {
+	result, err := wiki_provider.WikiProvider.GetContentSummary(ctx, lookupQuery(ctx, in))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toSummary(result), nil
+}
+
+func (s *wikiNamesServer) GetExtract(ctx context.Context, in *wiki_proto.LookupRequest) (*wiki_proto.Summary, error) {
+	result, err := wiki_provider.WikiProvider.GetExtract(ctx, lookupQuery(ctx, in))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toSummary(result), nil
+}
+
+// BatchLookup looks the names up concurrently and streams each result once it is ready. A name
+// that fails is streamed with its error, only a bad request or a cancelled stream ends the call. The
+// caller's tenant can lower the cap on the names, like it does for /search/batch.
+func (s *wikiNamesServer) BatchLookup(in *wiki_proto.BatchLookupRequest, stream wiki_proto.WikiNames_BatchLookupServer) error {
+	ctx := stream.Context()
+	if len(in.Names) == 0 {
+		return status.Error(codes.InvalidArgument, "names can't be empty")
+	}
+	if max := wiki_domain.TenantFrom(ctx).BatchSize(maxBatchNames); len(in.Names) > max {
+		return status.Errorf(codes.InvalidArgument, "at most %d names can be looked up at once", max)
+	}
+	names := make(chan string)
+	results := make(chan *wiki_proto.BatchLookupResult)
+	var wg sync.WaitGroup
+	for i := 0; i < batchWorkers && i < len(in.Names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range names {
+				results <- lookupResult(ctx, name, in)
+			}
+		}()
+	}
+	go func() {
+		defer close(names)
+		for _, name := range in.Names {
+			select {
+			case names <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var sendErr error
+	for result := range results {
+		// Keep draining so the workers can finish, but stop sending once the stream is broken
+		if sendErr != nil {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			sendErr = status.FromContextError(err).Err()
+			continue
+		}
+		sendErr = stream.Send(result)
+	}
+	return sendErr
+}
+
+func (s *wikiNamesServer) Suggest(ctx context.Context, in *wiki_proto.SuggestRequest) (*wiki_proto.SuggestResponse, error) {
+	result, err := wiki_provider.WikiProvider.GetSuggestions(ctx, wiki_domain.SuggestQuery{Prefix: in.Prefix, Locale: in.Locale, Limit: int(in.Limit)})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	response := &wiki_proto.SuggestResponse{Language: result.Language}
+	for _, suggestion := range result.Suggestions {
+		response.Suggestions = append(response.Suggestions, &wiki_proto.Suggestion{Title: suggestion.Title, ShortDescription: suggestion.ShortDescription})
+	}
+	return response, nil
+}
+
+func lookupResult(ctx context.Context, name string, in *wiki_proto.BatchLookupRequest) *wiki_proto.BatchLookupResult {
+	result, err := wiki_provider.WikiProvider.GetContentSummary(ctx, lookupQuery(ctx, &wiki_proto.LookupRequest{Name: name, Locale: in.Locale, Include: in.Include}))
+	if err != nil {
+		return &wiki_proto.BatchLookupResult{Name: name, Result: &wiki_proto.BatchLookupResult_Error{Error: &wiki_proto.Error{
+			Status:   int32(err.Code),
+			Reason:   string(err.ErrorCode),
+			Detail:   err.ErrorMessage,
+			Upstream: err.UpstreamWiki,
+		}}}
+	}
+	return &wiki_proto.BatchLookupResult{Name: name, Result: &wiki_proto.BatchLookupResult_Summary{Summary: toSummary(result)}}
+}
+
+// lookupQuery turns the request into the query the HTTP handlers bind, names are titles with spaces or underscores
+func lookupQuery(ctx context.Context, in *wiki_proto.LookupRequest) wiki_domain.RequestQuery {
+	return wiki_domain.RequestQuery{
+		Tenant:     wiki_domain.TenantFrom(ctx),
+		Name:       strings.ReplaceAll(in.Name, " ", "_"),
+		Locale:     in.Locale,
+		Sentences:  int(in.Sentences),
+		Chars:      int(in.Chars),
+		Paragraphs: int(in.Paragraphs),
+		Type:       in.Type,
+		Include:    strings.Join(in.Include, ","),
+	}
+}
+
+func toSummary(response *wiki_domain.Response) *wiki_proto.Summary {
+	summary := &wiki_proto.Summary{
+		ShortDescription: response.ShortDescription,
+		Language:         response.Language,
+		IsPerson:         response.IsPerson,
+		Extract:          response.Extract,
+		Categories:       response.Categories,
+		Langlinks:        response.Langlinks,
+		Redirects:        response.Redirects,
+	}
+	if name := response.Name; name != nil {
+		summary.Name = &wiki_proto.PersonName{Given: name.Given, Particles: name.Particles, Family: name.Family, Suffixes: name.Suffixes, Disambiguator: name.Disambiguator, Source: name.Source}
+	}
+	if revision := response.Revision; revision != nil {
+		summary.Revision = &wiki_proto.Revision{
+			Revid:      revision.Revid,
+			Parentid:   revision.Parentid,
+			Timestamp:  revision.Timestamp.Format(time.RFC3339),
+			User:       revision.User,
+			Userid:     revision.Userid,
+			Userhidden: revision.UserHidden,
+			Comment:    revision.Comment,
+		}
+	}
+	if image := response.Image; image != nil {
+		summary.Image = &wiki_proto.Image{
+			File:           image.File,
+			Thumbnail:      image.Thumbnail,
+			Width:          int32(image.Width),
+			Height:         int32(image.Height),
+			Original:       image.Original,
+			OriginalWidth:  int32(image.OriginalWidth),
+			OriginalHeight: int32(image.OriginalHeight),
+			License:        image.License,
+			LicenseUrl:     image.LicenseUrl,
+			Artist:         image.Artist,
+			Attribution:    image.Attribution,
+		}
+	}
+	if infobox := response.Infobox; infobox != nil {
+		summary.Infobox = &wiki_proto.Infobox{Type: infobox.Type, Fields: infobox.Fields}
+	}
+	return summary
+}
+
+// grpcCodes maps the HTTP statuses our errors carry onto the closest gRPC codes
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.Aborted,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusUnprocessableEntity: codes.FailedPrecondition,
+	http.StatusBadGateway:          codes.Unavailable,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+	http.StatusGatewayTimeout:      codes.DeadlineExceeded,
+}
+
+// toStatus converts the error into a gRPC status, with the reason, upstream and retry hint as an ErrorInfo detail
+func toStatus(err *wiki_domain.WikiError) error {
+	code, ok := grpcCodes[err.Code]
+	if !ok {
+		code = codes.Internal
+	}
+	st := status.New(code, err.ErrorMessage)
+	if err.ErrorCode == "" {
+		return st.Err()
+	}
+	metadata := map[string]string{}
+	if err.UpstreamWiki != "" {
+		metadata["upstream"] = err.UpstreamWiki
+	}
+	if err.RetryAfter > 0 {
+		metadata["retry_after"] = strconv.Itoa(err.RetryAfter)
+	}
+	detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{Reason: string(err.ErrorCode), Domain: "wiki-names", Metadata: metadata})
+	if detailErr != nil {
+		return status.Error(code, fmt.Sprintf("%s: %s", err.ErrorCode, err.ErrorMessage))
+	}
+	return detailed.Err()
+}
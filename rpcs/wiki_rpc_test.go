@@ -0,0 +1,165 @@
+package wiki_rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	wiki_domain "wiki-names/domains"
+	wiki_proto "wiki-names/protos"
+	wiki_provider "wiki-names/providers"
+)
+
+type providerMock struct {
+	wiki_provider.WikiProviderStruct
+	mu       sync.Mutex
+	requests []wiki_domain.RequestQuery
+}
+
+// We are mocking the provider lookups, only Yoshua_Bengio and Geoffrey_Hinton have a page
+func (pm *providerMock) GetContentSummary(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	pm.mu.Lock()
+	pm.requests = append(pm.requests, request)
+	pm.mu.Unlock()
+	switch request.Name {
+	case "Yoshua_Bengio":
+		isPerson := true
+		return &wiki_domain.Response{ShortDescription: "Canadian computer scientist", Language: "en", IsPerson: &isPerson,
+			Infobox: &wiki_domain.Infobox{Type: "scientist", Fields: map[string]string{"birth_place": "Paris, France"}}}, nil
+	case "Geoffrey_Hinton":
+		return &wiki_domain.Response{ShortDescription: "British-Canadian computer scientist", Language: "en"}, nil
+	}
+	return nil, wiki_domain.NewTypedError(wiki_domain.ErrPageNotFound, "Page "+request.Name+" does not exist").WithUpstream("en.wikipedia.org")
+}
+
+func (pm *providerMock) GetExtract(ctx context.Context, request wiki_domain.RequestQuery) (*wiki_domain.Response, *wiki_domain.WikiError) {
+	pm.mu.Lock()
+	pm.requests = append(pm.requests, request)
+	pm.mu.Unlock()
+	return &wiki_domain.Response{ShortDescription: "Canadian computer scientist", Extract: "Yoshua Bengio is a Canadian computer scientist."}, nil
+}
+
+func (pm *providerMock) GetSuggestions(ctx context.Context, query wiki_domain.SuggestQuery) (*wiki_domain.Suggestions, *wiki_domain.WikiError) {
+	return &wiki_domain.Suggestions{Language: "en", Suggestions: []wiki_domain.Suggestion{{Title: query.Prefix + "gio", ShortDescription: "Canadian computer scientist"}}}, nil
+}
+
+func setupServer(t *testing.T) (wiki_proto.WikiNamesClient, *grpc.ClientConn, *providerMock) {
+	return setupServerWithAuth(t, nil)
+}
+
+func setupServerWithAuth(t *testing.T, auth *Auth) (wiki_proto.WikiNamesClient, *grpc.ClientConn, *providerMock) {
+	mock := &providerMock{}
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = mock
+	t.Cleanup(func() { wiki_provider.WikiProvider = original })
+
+	listener := bufconn.Listen(1024 * 1024)
+	server, _ := NewServer(auth)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return listener.DialContext(ctx)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return wiki_proto.NewWikiNamesClient(conn), conn, mock
+}
+
+func TestGetSummary(t *testing.T) {
+	client, _, mock := setupServer(t)
+
+	summary, err := client.GetSummary(context.Background(), &wiki_proto.LookupRequest{Name: "Yoshua Bengio", Include: []string{"infobox", "image"}})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Canadian computer scientist", summary.ShortDescription)
+	assert.True(t, summary.GetIsPerson())
+	assert.EqualValues(t, "Paris, France", summary.Infobox.Fields["birth_place"])
+	assert.Nil(t, summary.Image)
+	assert.EqualValues(t, "Yoshua_Bengio", mock.requests[0].Name)
+	assert.EqualValues(t, "infobox,image", mock.requests[0].Include)
+}
+
+func TestGetSummaryNotFound(t *testing.T) {
+	client, _, _ := setupServer(t)
+
+	_, err := client.GetSummary(context.Background(), &wiki_proto.LookupRequest{Name: "Nobody At All"})
+	st := status.Convert(err)
+	assert.EqualValues(t, codes.NotFound, st.Code())
+	assert.Len(t, st.Details(), 1)
+	info := st.Details()[0].(*errdetails.ErrorInfo)
+	assert.EqualValues(t, "page_not_found", info.Reason)
+	assert.EqualValues(t, "en.wikipedia.org", info.Metadata["upstream"])
+}
+
+func TestGetExtract(t *testing.T) {
+	client, _, mock := setupServer(t)
+
+	summary, err := client.GetExtract(context.Background(), &wiki_proto.LookupRequest{Name: "Yoshua_Bengio", Locale: "en", Sentences: 1})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "Yoshua Bengio is a Canadian computer scientist.", summary.Extract)
+	assert.EqualValues(t, 1, mock.requests[0].Sentences)
+}
+
+func TestBatchLookup(t *testing.T) {
+	client, _, _ := setupServer(t)
+
+	stream, err := client.BatchLookup(context.Background(), &wiki_proto.BatchLookupRequest{Names: []string{"Yoshua Bengio", "Nobody At All", "Geoffrey Hinton"}})
+	assert.Nil(t, err)
+	results := map[string]*wiki_proto.BatchLookupResult{}
+	for {
+		result, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		results[result.Name] = result
+	}
+	names := make([]string, 0, len(results))
+	for name := range results {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	assert.EqualValues(t, []string{"Geoffrey Hinton", "Nobody At All", "Yoshua Bengio"}, names)
+	assert.EqualValues(t, "Canadian computer scientist", results["Yoshua Bengio"].GetSummary().ShortDescription)
+	assert.EqualValues(t, 404, results["Nobody At All"].GetError().Status)
+	assert.EqualValues(t, "page_not_found", results["Nobody At All"].GetError().Reason)
+}
+
+func TestBatchLookupEmpty(t *testing.T) {
+	client, _, _ := setupServer(t)
+
+	stream, err := client.BatchLookup(context.Background(), &wiki_proto.BatchLookupRequest{})
+	assert.Nil(t, err)
+	_, err = stream.Recv()
+	assert.EqualValues(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSuggest(t *testing.T) {
+	client, _, _ := setupServer(t)
+
+	response, err := client.Suggest(context.Background(), &wiki_proto.SuggestRequest{Prefix: "Yoshua Ben"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "en", response.Language)
+	assert.EqualValues(t, "Yoshua Bengio", response.Suggestions[0].Title)
+}
+
+func TestHealth(t *testing.T) {
+	_, conn, _ := setupServer(t)
+
+	response, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: ServiceName})
+	assert.Nil(t, err)
+	assert.EqualValues(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+}
@@ -0,0 +1,263 @@
+package wiki_sentence
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Limits caps how much of an extract is returned, a zero field means no limit
+type Limits struct {
+	Sentences  int
+	Chars      int
+	Paragraphs int
+}
+
+// IsZero reports whether no limit at all was requested
+func (l Limits) IsZero() bool {
+	return l.Sentences == 0 && l.Chars == 0 && l.Paragraphs == 0
+}
+
+// abbreviations never end a sentence, keyed by language with "*" shared by all of them
+var abbreviations = map[string][]string{
+	"*":  {"dr", "prof", "st", "jr", "sr", "vs", "ca", "approx", "fl", "nr"},
+	"en": {"no", "mr", "mrs", "ms", "mt", "gen", "col", "lt", "sgt", "capt", "rev", "hon", "inc", "ltd", "co", "corp", "est", "jan", "feb", "mar", "apr", "jun", "jul", "aug", "sep", "sept", "oct", "nov", "dec", "dept", "univ", "ft", "op", "vol"},
+	"de": {"bzw", "usw", "geb", "gest", "evtl", "hr", "fr", "str", "vgl", "sog", "jh", "mio", "mrd", "dt", "ehem"},
+	"fr": {"mme", "mlle", "mgr", "pr", "ste", "av", "apr", "env", "vol", "janv", "févr", "avr", "juil", "sept", "oct", "nov", "déc"},
+	"es": {"sra", "srta", "dra", "ud", "uds", "dña", "av", "pág", "aprox", "esq"},
+	"it": {"sig", "sigg", "dott", "ecc", "avv", "ing", "sen", "on"},
+	"pt": {"sra", "dra", "av", "aprox", "pág"},
+	"nl": {"dhr", "mevr", "mr", "ir", "ing", "bijv", "ca", "enz"},
+}
+
+// ordinalLanguages write ordinals as a number and a full stop ("am 5. März"), so that is not a sentence end
+var ordinalLanguages = map[string]bool{
+	"de": true, "da": true, "no": true, "nn": true, "fi": true, "is": true,
+	"cs": true, "sk": true, "sl": true, "hr": true, "sr": true, "bs": true,
+	"hu": true, "pl": true, "et": true, "lv": true, "lt": true, "tr": true,
+}
+
+// capitalMonths are the months of the ordinal languages that write them with a capital, as in
+// "am 5. März", the others write them in lower case like any word that can follow an ordinal
+var capitalMonths = map[string][]string{
+	"de": {"januar", "jänner", "februar", "feber", "märz", "april", "mai", "juni", "juli", "august", "september", "oktober", "november", "dezember"},
+	"tr": {"ocak", "şubat", "mart", "nisan", "mayıs", "haziran", "temmuz", "ağustos", "eylül", "ekim", "kasım", "aralık"},
+}
+
+// cjkLanguages run sentences together without a space after the full stop
+var cjkLanguages = map[string]bool{"zh": true, "ja": true, "zh-yue": true, "zh-classical": true, "wuu": true, "gan": true}
+
+// acronymRe matches dotted abbreviations such as U.S, e.g or z.B (the final dot is the candidate boundary)
+var acronymRe = regexp.MustCompile(`^(\pL{1,2}\.)+\pL{1,2}$`)
+
+const closers = "\"'”’»)]」』）】"
+
+// Paragraphs splits a plaintext extract into its non empty paragraphs, dropping "== Section ==" headings
+func Paragraphs(text string) []string {
+	var paragraphs []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || (strings.HasPrefix(line, "==") && strings.HasSuffix(line, "==")) {
+			continue
+		}
+		paragraphs = append(paragraphs, line)
+	}
+	return paragraphs
+}
+
+// Split returns the sentences of text, as written in the language lang
+func Split(text string, lang string) []string {
+	var sentences []string
+	for _, paragraph := range Paragraphs(text) {
+		runes := []rune(paragraph)
+		start := 0
+		for _, end := range boundaries(runes, lang) {
+			sentences = append(sentences, strings.TrimSpace(string(runes[start:end])))
+			start = end
+		}
+	}
+	return sentences
+}
+
+// Truncate keeps the first paragraphs, then the first sentences, then cuts to chars runes on
+// the last sentence boundary that fits (or the last word, with an ellipsis, when none does)
+func Truncate(text string, lang string, limits Limits) string {
+	paragraphs := Paragraphs(text)
+	if limits.Paragraphs > 0 && len(paragraphs) > limits.Paragraphs {
+		paragraphs = paragraphs[:limits.Paragraphs]
+	}
+
+	var out []rune
+	var ends []int
+	count := 0
+	for _, paragraph := range paragraphs {
+		if limits.Sentences > 0 && count >= limits.Sentences {
+			break
+		}
+		if len(out) > 0 {
+			out = append(out, '\n')
+		}
+		runes := []rune(paragraph)
+		offset := len(out)
+		cut := len(runes)
+		for _, end := range boundaries(runes, lang) {
+			count++
+			ends = append(ends, offset+end)
+			if limits.Sentences > 0 && count == limits.Sentences {
+				cut = end
+				break
+			}
+		}
+		out = append(out, runes[:cut]...)
+	}
+
+	if limits.Chars > 0 && len(out) > limits.Chars {
+		for i := len(ends) - 1; i >= 0; i-- {
+			if ends[i] <= limits.Chars {
+				return strings.TrimSpace(string(out[:ends[i]]))
+			}
+		}
+		return cutWord(out, limits.Chars)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// cutWord shortens runes to at most limit runes including the trailing ellipsis
+func cutWord(runes []rune, limit int) string {
+	if limit < 2 {
+		return string(runes[:limit])
+	}
+	cut := limit - 1
+	for i := cut; i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimSpace(string(runes[:cut])) + "…"
+}
+
+// boundaries returns the rune offsets just after each sentence of a single paragraph, the last one is always len(runes)
+func boundaries(runes []rune, lang string) []int {
+	var ends []int
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '。' || r == '！' || r == '？' || r == '।' || r == '؟' || r == '۔':
+			end := skipClosers(runes, i+1)
+			ends = append(ends, end)
+			i = end - 1
+		case r == '.' || r == '!' || r == '?' || r == '…':
+			end := skipClosers(runes, i+1)
+			// CJK text may follow a latin full stop straight away, everything else needs a space
+			if end < len(runes) && !unicode.IsSpace(runes[end]) && !(cjkLanguages[lang] && isCJK(runes[end])) {
+				continue
+			}
+			if end < len(runes) && !startsSentence(runes, end) {
+				continue
+			}
+			if r == '.' && !periodEndsSentence(runes, i, lang) {
+				continue
+			}
+			ends = append(ends, end)
+			i = end - 1
+		}
+	}
+	if lastEnd(ends) < len(runes) {
+		ends = append(ends, len(runes))
+	}
+	return ends
+}
+
+func lastEnd(ends []int) int {
+	if len(ends) == 0 {
+		return 0
+	}
+	return ends[len(ends)-1]
+}
+
+func skipClosers(runes []rune, i int) int {
+	for i < len(runes) && strings.ContainsRune(closers, runes[i]) {
+		i++
+	}
+	return i
+}
+
+// startsSentence is false when the next word starts in lower case, as in "Yahoo! is" or "approx. three"
+func startsSentence(runes []rune, i int) bool {
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i >= len(runes) || !unicode.IsLower(runes[i])
+}
+
+// periodEndsSentence looks at the word in front of the full stop at i for initials, abbreviations and ordinals
+func periodEndsSentence(runes []rune, i int, lang string) bool {
+	start := i
+	for start > 0 && !unicode.IsSpace(runes[start-1]) {
+		start--
+	}
+	word := strings.TrimLeft(string(runes[start:i]), "\"'“‘«([")
+	if word == "" {
+		return true
+	}
+	wordRunes := []rune(word)
+	// Initials such as "J. R. R. Tolkien" or "b. 1964"
+	if len(wordRunes) == 1 && unicode.IsLetter(wordRunes[0]) {
+		return false
+	}
+	if acronymRe.MatchString(word) {
+		return false
+	}
+	lower := strings.ToLower(word)
+	for _, key := range []string{"*", lang} {
+		for _, abbreviation := range abbreviations[key] {
+			if lower == abbreviation {
+				return false
+			}
+		}
+	}
+	// "5. März" or "2. ledna" is an ordinal, "im Jahr 1990. Danach" is a year ending the sentence
+	if ordinalLanguages[lang] && isNumber(word) && len(wordRunes) < 4 {
+		next := nextWord(runes, i+1)
+		if next != "" && (unicode.IsLower([]rune(next)[0]) || isMonth(next, lang)) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextWord is the word starting after the spaces from i, without its punctuation
+func nextWord(runes []rune, i int) string {
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	end := i
+	for end < len(runes) && unicode.IsLetter(runes[end]) {
+		end++
+	}
+	return string(runes[i:end])
+}
+
+func isMonth(word string, lang string) bool {
+	lower := strings.ToLower(word)
+	for _, month := range capitalMonths[lang] {
+		if lower == month {
+			return true
+		}
+	}
+	return false
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
@@ -0,0 +1,110 @@
+package wiki_sentence
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitEnglish(t *testing.T) {
+	text := "Yoshua Bengio OC FRS FRSC (born March 5, 1964) is a Canadian computer scientist. He received the 2018 A.M. Turing Award with Dr. Geoffrey Hinton and Yann LeCun. He studied at McGill University in the U.S. and Canada? No, only Canada! Pi is approx. 3.14 in J. R. R. Tolkien's notes."
+	assert.EqualValues(t, []string{
+		"Yoshua Bengio OC FRS FRSC (born March 5, 1964) is a Canadian computer scientist.",
+		"He received the 2018 A.M. Turing Award with Dr. Geoffrey Hinton and Yann LeCun.",
+		"He studied at McGill University in the U.S. and Canada?",
+		"No, only Canada!",
+		"Pi is approx. 3.14 in J. R. R. Tolkien's notes.",
+	}, Split(text, "en"))
+}
+
+func TestSplitQuotesAndParagraphs(t *testing.T) {
+	text := "He said \"It works.\" Then he left.\n\n== Early life ==\nBorn in Paris (France.) He moved."
+	assert.EqualValues(t, []string{
+		"He said \"It works.\"",
+		"Then he left.",
+		"Born in Paris (France.)",
+		"He moved.",
+	}, Split(text, "en"))
+}
+
+func TestSplitGerman(t *testing.T) {
+	text := "Yoshua Bengio (* 5. März 1964 in Paris) ist ein kanadischer Informatiker. Er lehrt u.a. an der Universität Montreal bzw. am Mila. Seit dem 1. Januar 2000 ist er z. B. Professor."
+	assert.EqualValues(t, []string{
+		"Yoshua Bengio (* 5. März 1964 in Paris) ist ein kanadischer Informatiker.",
+		"Er lehrt u.a. an der Universität Montreal bzw. am Mila.",
+		"Seit dem 1. Januar 2000 ist er z. B. Professor.",
+	}, Split(text, "de"))
+}
+
+func TestSplitGermanNumbers(t *testing.T) {
+	text := "Er wurde im Jahr 1990. Danach zog er nach Berlin. Dort lebt er seit dem 3. Oktober und wurde 2. in der Wahl."
+	assert.EqualValues(t, []string{
+		"Er wurde im Jahr 1990.",
+		"Danach zog er nach Berlin.",
+		"Dort lebt er seit dem 3. Oktober und wurde 2. in der Wahl.",
+	}, Split(text, "de"))
+	assert.EqualValues(t, "Er wurde im Jahr 1990.", Truncate("Er wurde im Jahr 1990. Danach zog er nach Berlin. Dort lebt er.", "de", Limits{Sentences: 1}))
+}
+
+func TestSplitFrench(t *testing.T) {
+	text := "Yoshua Bengio, né le 5 mars 1964 à Paris, est un chercheur canadien. Il a travaillé avec Mme. Dupont et M. Martin. Il vit à Montréal."
+	assert.EqualValues(t, []string{
+		"Yoshua Bengio, né le 5 mars 1964 à Paris, est un chercheur canadien.",
+		"Il a travaillé avec Mme. Dupont et M. Martin.",
+		"Il vit à Montréal.",
+	}, Split(text, "fr"))
+}
+
+func TestSplitSpanish(t *testing.T) {
+	text := "¿Quién es Yoshua Bengio? Es un informático canadiense. Trabajó con la Dra. Pineau en la Av. Principal."
+	assert.EqualValues(t, []string{
+		"¿Quién es Yoshua Bengio?",
+		"Es un informático canadiense.",
+		"Trabajó con la Dra. Pineau en la Av. Principal.",
+	}, Split(text, "es"))
+}
+
+func TestSplitChinese(t *testing.T) {
+	text := "约书亚·本吉奥是加拿大计算机科学家。他于2018年获得图灵奖！他的研究领域是深度学习，例如3.5版本的模型。"
+	assert.EqualValues(t, []string{
+		"约书亚·本吉奥是加拿大计算机科学家。",
+		"他于2018年获得图灵奖！",
+		"他的研究领域是深度学习，例如3.5版本的模型。",
+	}, Split(text, "zh"))
+}
+
+func TestSplitJapanese(t *testing.T) {
+	text := "ヨシュア・ベンジオは、カナダの計算機科学者。「深層学習」の研究で知られる。"
+	assert.EqualValues(t, []string{
+		"ヨシュア・ベンジオは、カナダの計算機科学者。",
+		"「深層学習」の研究で知られる。",
+	}, Split(text, "ja"))
+}
+
+func TestSplitHindi(t *testing.T) {
+	text := "योशुआ बेंगियो एक कनाडाई कंप्यूटर वैज्ञानिक हैं। वे मॉन्ट्रियल में रहते हैं।"
+	assert.EqualValues(t, []string{
+		"योशुआ बेंगियो एक कनाडाई कंप्यूटर वैज्ञानिक हैं।",
+		"वे मॉन्ट्रियल में रहते हैं।",
+	}, Split(text, "hi"))
+}
+
+func TestTruncateSentences(t *testing.T) {
+	text := "First sentence. Second sentence. Third sentence.\nSecond paragraph. More."
+	assert.EqualValues(t, "First sentence. Second sentence.", Truncate(text, "en", Limits{Sentences: 2}))
+	assert.EqualValues(t, "First sentence. Second sentence. Third sentence.\nSecond paragraph.", Truncate(text, "en", Limits{Sentences: 4}))
+	assert.EqualValues(t, "First sentence. Second sentence. Third sentence.", Truncate(text, "en", Limits{Paragraphs: 1}))
+	assert.EqualValues(t, "First sentence.", Truncate(text, "en", Limits{Paragraphs: 1, Sentences: 1}))
+	assert.EqualValues(t, text, Truncate(text, "en", Limits{}))
+}
+
+func TestTruncateChars(t *testing.T) {
+	text := "First sentence. Second sentence. Third sentence."
+	assert.EqualValues(t, "First sentence. Second sentence.", Truncate(text, "en", Limits{Chars: 40}))
+	assert.EqualValues(t, "First sentence.", Truncate(text, "en", Limits{Chars: 15}))
+	// No sentence fits, so cut on a word and mark it
+	assert.EqualValues(t, "First…", Truncate(text, "en", Limits{Chars: 10}))
+	assert.EqualValues(t, "约书亚·本吉…", Truncate("约书亚·本吉奥是加拿大计算机科学家。", "zh", Limits{Chars: 7}))
+	// Deterministic: the same input always gives the same output
+	assert.EqualValues(t, Truncate(text, "en", Limits{Chars: 33}), Truncate(text, "en", Limits{Chars: 33}))
+}
@@ -0,0 +1,104 @@
+package wiki_tenant
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	wiki_domain "wiki-names/domains"
+	wiki_locale "wiki-names/locales"
+)
+
+// Registry holds the tenants, read from a JSON list of them
+type Registry struct {
+	mu      sync.RWMutex
+	tenants map[string]*wiki_domain.Tenant
+}
+
+// Tenants are the tenants API keys resolve to, empty until TENANTS_FILE is loaded
+var Tenants = NewRegistry()
+
+func NewRegistry() *Registry {
+	return &Registry{tenants: map[string]*wiki_domain.Tenant{}}
+}
+
+// Load replaces the tenants with the ones in path. Their locales are normalized, and a tenant
+// whose default locale it may not use is an error, as it could never serve a request without one.
+func (r *Registry) Load(path string) error {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var list []*wiki_domain.Tenant
+	if err := json.Unmarshal(bytes, &list); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	tenants := map[string]*wiki_domain.Tenant{}
+	for _, tenant := range list {
+		if tenant.ID == "" {
+			return fmt.Errorf("%s: a tenant has no id", path)
+		}
+		if _, ok := tenants[tenant.ID]; ok {
+			return fmt.Errorf("%s: tenant %s is there twice", path, tenant.ID)
+		}
+		if err := normalize(tenant); err != nil {
+			return fmt.Errorf("%s: tenant %s: %w", path, tenant.ID, err)
+		}
+		tenants[tenant.ID] = tenant
+	}
+	r.mu.Lock()
+	r.tenants = tenants
+	r.mu.Unlock()
+	return nil
+}
+
+// Set adds or replaces a tenant
+func (r *Registry) Set(tenant *wiki_domain.Tenant) error {
+	if err := normalize(tenant); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants[tenant.ID] = tenant
+	return nil
+}
+
+// Get is the tenant, nil when there is none with that id
+func (r *Registry) Get(id string) *wiki_domain.Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.tenants[id]
+}
+
+// List is every tenant, by id
+func (r *Registry) List() []*wiki_domain.Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tenants := make([]*wiki_domain.Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenants = append(tenants, tenant)
+	}
+	sort.Slice(tenants, func(i, j int) bool { return tenants[i].ID < tenants[j].ID })
+	return tenants
+}
+
+func normalize(tenant *wiki_domain.Tenant) error {
+	for i, code := range tenant.AllowedLocales {
+		locale, err := wiki_locale.Locales.Normalize(code)
+		if err != nil {
+			return err
+		}
+		tenant.AllowedLocales[i] = locale.Code
+	}
+	// The default keeps its variant, only its edition has to be allowed
+	locale, err := wiki_locale.Locales.Normalize(tenant.LocaleOr(wiki_locale.DefaultLocale))
+	if err != nil {
+		return err
+	}
+	if !tenant.AllowsLocale(locale.Code) {
+		return fmt.Errorf("default locale %s is not one of its allowed locales", locale.Code)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package wiki_tenant
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+)
+
+func write(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	assert.Nil(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	registry := NewRegistry()
+	err := registry.Load(write(t, `[
+		{"id": "acme", "cache_ttl": 600, "allowed_locales": ["FR", "de-CH"], "default_locale": "fr", "facets": ["image"], "max_batch_size": 20},
+		{"id": "globex"}
+	]`))
+	assert.Nil(t, err)
+
+	acme := registry.Get("acme")
+	assert.EqualValues(t, []string{"fr", "de"}, acme.AllowedLocales)
+	assert.EqualValues(t, 600, acme.CacheTTL)
+	assert.EqualValues(t, 20, acme.BatchSize(500))
+	assert.True(t, acme.AllowsFacet("image"))
+	assert.False(t, acme.AllowsFacet("infobox"))
+	assert.EqualValues(t, "en", registry.Get("globex").LocaleOr("en"))
+	assert.Nil(t, registry.Get("initech"))
+	assert.Len(t, registry.List(), 2)
+	assert.EqualValues(t, "acme", registry.List()[0].ID)
+}
+
+func TestLoadErrors(t *testing.T) {
+	registry := NewRegistry()
+	assert.Nil(t, registry.Set(&wiki_domain.Tenant{ID: "kept"}))
+
+	// Without a default, en has to be allowed
+	assert.NotNil(t, registry.Load(write(t, `[{"id": "acme", "allowed_locales": ["fr"]}]`)))
+	assert.NotNil(t, registry.Load(write(t, `[{"id": "acme", "allowed_locales": ["xx-nowhere"]}]`)))
+	assert.NotNil(t, registry.Load(write(t, `[{"id": "acme"}, {"id": "acme"}]`)))
+	assert.NotNil(t, registry.Load(write(t, `[{"name": "no id"}]`)))
+	assert.NotNil(t, registry.Load(filepath.Join(t.TempDir(), "missing.json")))
+	// A file that fails to load leaves the tenants as they were
+	assert.NotNil(t, registry.Get("kept"))
+}
@@ -0,0 +1,227 @@
+package wiki_token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeySet is the identity provider's signing keys, read from a JWKS URL or file and kept for
+// Refresh. A token signed with a key we don't know reads the set again, so a rotated key is
+// picked up straight away, but no more than once every MinRefresh.
+type KeySet struct {
+	URL  string
+	File string
+	// Refresh is how long the keys are kept before they are read again
+	Refresh    time.Duration
+	MinRefresh time.Duration
+	Client     *http.Client
+
+	mu        sync.RWMutex
+	keys      map[string]*publicKey
+	fetched   time.Time
+	refreshMu sync.Mutex
+	attempted time.Time
+	lastErr   error
+	now       func() time.Time
+}
+
+// publicKey is one key of the set, Algorithm is the alg the JWK restricts it to, if any
+type publicKey struct {
+	ID        string
+	Algorithm string
+	Key       crypto.PublicKey
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func NewKeySet(url string, file string) *KeySet {
+	return &KeySet{
+		URL:        url,
+		File:       file,
+		Refresh:    time.Hour,
+		MinRefresh: time.Minute,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+}
+
+// Key is the key with that id, or the only key when the token names none. Keys that can't be
+// read again are kept, an identity provider being down shouldn't log everyone out.
+func (s *KeySet) Key(id string) (*publicKey, error) {
+	s.mu.RLock()
+	stale := s.keys == nil || s.now().Sub(s.fetched) > s.Refresh
+	key := s.find(id)
+	s.mu.RUnlock()
+	if key != nil && !stale {
+		return key, nil
+	}
+	if err := s.refresh(); err != nil {
+		if key != nil {
+			log.Printf("JWKS: keeping the keys we have, failed to read them again: %s", err.Error())
+			return key, nil
+		}
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if key := s.find(id); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("no signing key %q in the key set", id)
+}
+
+func (s *KeySet) find(id string) *publicKey {
+	if id == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key
+		}
+	}
+	return s.keys[id]
+}
+
+// refresh reads the keys once for every caller waiting on it, and no more than once every
+// MinRefresh whatever the outcome, so a flood of unknown key ids can't hammer the identity provider
+func (s *KeySet) refresh() error {
+	started := s.now()
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	s.mu.RLock()
+	fetched, attempted, lastErr := s.fetched, s.attempted, s.lastErr
+	s.mu.RUnlock()
+	if fetched.After(started) || (!attempted.IsZero() && s.now().Sub(attempted) < s.MinRefresh) {
+		return lastErr
+	}
+
+	keys, err := s.read()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempted, s.lastErr = s.now(), err
+	if err == nil {
+		s.keys, s.fetched = keys, s.now()
+	}
+	return err
+}
+
+func (s *KeySet) read() (map[string]*publicKey, error) {
+	var bytes []byte
+	var err error
+	if s.File != "" {
+		bytes, err = os.ReadFile(s.File)
+	} else {
+		bytes, err = s.fetch()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(bytes, &set); err != nil {
+		return nil, fmt.Errorf("reading the key set: %w", err)
+	}
+	keys := map[string]*publicKey{}
+	for _, key := range set.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		parsed, err := parseKey(key)
+		if err != nil {
+			log.Printf("JWKS: skipping key %q: %s", key.Kid, err.Error())
+			continue
+		}
+		if parsed != nil {
+			keys[key.Kid] = parsed
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("the key set has no signing keys we can use")
+	}
+	return keys, nil
+}
+
+func (s *KeySet) fetch() ([]byte, error) {
+	response, err := s.Client.Get(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %d", s.URL, response.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(response.Body, 1<<20))
+}
+
+// parseKey reads RSA and EC keys, it returns nil for the other key types
+func parseKey(key jwk) (*publicKey, error) {
+	switch key.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("bad RSA exponent")
+		}
+		public := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		if public.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys need at least 2048 bits")
+		}
+		return &publicKey{ID: key.Kid, Algorithm: key.Alg, Key: public}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch key.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unknown curve %q", key.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		public := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(public.X, public.Y) {
+			return nil, errors.New("the point is not on the curve")
+		}
+		return &publicKey{ID: key.Kid, Algorithm: key.Alg, Key: public}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,171 @@
+package wiki_token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// algorithms are the signatures we accept, anything else (none, HMAC) is refused
+var algorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// Verifier validates the bearer tokens of our identity provider and maps their claims to a tenant
+// and scopes. The scope claim can be a space separated string, as OAuth has it, or a list.
+type Verifier struct {
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+	// TenantClaim and ScopeClaim are the claims the tenant and the scopes are read from
+	TenantClaim string
+	ScopeClaim  string
+	// Leeway allows for the clocks of the identity provider and ours drifting apart
+	Leeway time.Duration
+
+	now func() time.Time
+}
+
+func NewVerifier(keys *KeySet, issuer string, audience string) *Verifier {
+	return &Verifier{Keys: keys, Issuer: issuer, Audience: audience, TenantClaim: "tenant", ScopeClaim: "scope", Leeway: time.Minute, now: time.Now}
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// Verify checks the token's signature, issuer, audience and expiry, and returns its claims
+func (v *Verifier) Verify(token string) (*wiki_domain.Claims, *wiki_domain.WikiError) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, invalid("the token is not a JWT")
+	}
+	var head header
+	if err := decodePart(parts[0], &head); err != nil {
+		return nil, invalid("the token header can't be read")
+	}
+	hash, ok := algorithms[head.Alg]
+	if !ok {
+		return nil, invalid(fmt.Sprintf("tokens signed with %q are not accepted", head.Alg))
+	}
+	key, err := v.Keys.Key(head.Kid)
+	if err != nil {
+		return nil, invalid(err.Error())
+	}
+	if key.Algorithm != "" && key.Algorithm != head.Alg {
+		return nil, invalid(fmt.Sprintf("key %q is not for %s", key.ID, head.Alg))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !verifySignature(key.Key, head.Alg, hash, parts[0]+"."+parts[1], signature) {
+		return nil, invalid("the token signature is not valid")
+	}
+
+	var claims map[string]interface{}
+	if err := decodePart(parts[1], &claims); err != nil {
+		return nil, invalid("the token claims can't be read")
+	}
+	return v.validate(claims)
+}
+
+func (v *Verifier) validate(claims map[string]interface{}) (*wiki_domain.Claims, *wiki_domain.WikiError) {
+	now := v.now()
+	if issuer, _ := claims["iss"].(string); issuer != v.Issuer {
+		return nil, invalid(fmt.Sprintf("the token was issued by %q", issuer))
+	}
+	if !contains(claimStrings(claims["aud"]), v.Audience) {
+		return nil, invalid("the token is not for " + v.Audience)
+	}
+	expires, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, invalid("the token has no expiry")
+	}
+	expiresAt := time.Unix(int64(expires), 0).UTC()
+	if now.After(expiresAt.Add(v.Leeway)) {
+		return nil, invalid("the token has expired")
+	}
+	if notBefore, ok := claims["nbf"].(float64); ok && now.Add(v.Leeway).Before(time.Unix(int64(notBefore), 0)) {
+		return nil, invalid("the token is not valid yet")
+	}
+	subject, _ := claims["sub"].(string)
+	tenant, _ := claims[v.TenantClaim].(string)
+	scopes := claimStrings(claims[v.ScopeClaim])
+	if value, ok := claims[v.ScopeClaim].(string); ok {
+		scopes = strings.Fields(value)
+	}
+	return &wiki_domain.Claims{Subject: subject, Tenant: tenant, Scopes: scopes, ExpiresAt: expiresAt}, nil
+}
+
+func verifySignature(key interface{}, alg string, hash crypto.Hash, signed string, signature []byte) bool {
+	digest := hash.New()
+	digest.Write([]byte(signed))
+	sum := digest.Sum(nil)
+	switch public := key.(type) {
+	case *rsa.PublicKey:
+		return strings.HasPrefix(alg, "RS") && rsa.VerifyPKCS1v15(public, hash, sum, signature) == nil
+	case *ecdsa.PublicKey:
+		// ES signatures are r and s side by side, each as long as the curve's order, and each
+		// algorithm has its own curve
+		bits := public.Curve.Params().BitSize
+		size := (bits + 7) / 8
+		if alg != map[int]string{256: "ES256", 384: "ES384", 521: "ES512"}[bits] || len(signature) != 2*size {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(signature[:size]), new(big.Int).SetBytes(signature[size:])
+		return ecdsa.Verify(public, sum, r, s)
+	}
+	return false
+}
+
+func decodePart(part string, value interface{}) error {
+	bytes, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(bytes, value)
+}
+
+// claimStrings reads a claim that is a string or a list of them
+func claimStrings(value interface{}) []string {
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func invalid(message string) *wiki_domain.WikiError {
+	return wiki_domain.NewTypedError(wiki_domain.ErrInvalidToken, message)
+}
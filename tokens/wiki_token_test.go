@@ -0,0 +1,250 @@
+package wiki_token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// clock is a time the tests move by hand
+type clock struct {
+	at time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.at
+}
+
+// signingKey is a key generated for the test, with the JWK the identity provider would publish
+type signingKey struct {
+	id  string
+	alg string
+	key crypto.Signer
+}
+
+func newRSAKey(t *testing.T, id string) *signingKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	return &signingKey{id: id, alg: "RS256", key: key}
+}
+
+func newECKey(t *testing.T, id string) *signingKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	return &signingKey{id: id, alg: "ES256", key: key}
+}
+
+func (k *signingKey) jwk() map[string]string {
+	encode := base64.RawURLEncoding.EncodeToString
+	switch public := k.key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kid": k.id, "kty": "RSA", "use": "sig", "alg": k.alg, "n": encode(public.N.Bytes()), "e": encode(big.NewInt(int64(public.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kid": k.id, "kty": "EC", "crv": "P-256", "x": encode(public.X.FillBytes(make([]byte, 32))), "y": encode(public.Y.FillBytes(make([]byte, 32)))}
+	}
+	return nil
+}
+
+func (k *signingKey) sign(t *testing.T, claims map[string]interface{}) string {
+	return signWith(t, k.key, map[string]string{"alg": k.alg, "kid": k.id, "typ": "JWT"}, claims)
+}
+
+func signWith(t *testing.T, key crypto.Signer, header map[string]string, claims map[string]interface{}) string {
+	head, err := json.Marshal(header)
+	assert.Nil(t, err)
+	body, err := json.Marshal(claims)
+	assert.Nil(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(head) + "." + base64.RawURLEncoding.EncodeToString(body)
+	sum := crypto.SHA256.New()
+	sum.Write([]byte(signed))
+	var signature []byte
+	switch private := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, private, crypto.SHA256, sum.Sum(nil))
+		assert.Nil(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, private, sum.Sum(nil))
+		assert.Nil(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func jwks(keys ...*signingKey) []byte {
+	set := struct {
+		Keys []map[string]string `json:"keys"`
+	}{}
+	for _, key := range keys {
+		set.Keys = append(set.Keys, key.jwk())
+	}
+	bytes, _ := json.Marshal(set)
+	return bytes
+}
+
+func writeJWKS(t *testing.T, path string, keys ...*signingKey) {
+	assert.Nil(t, os.WriteFile(path, jwks(keys...), 0o600))
+}
+
+func setupVerifier(t *testing.T, keys ...*signingKey) (*Verifier, *clock, string) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, keys...)
+	clock := &clock{at: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	keySet := NewKeySet("", path)
+	keySet.now = clock.now
+	verifier := NewVerifier(keySet, "https://id.example.com", "wiki-names")
+	verifier.now = clock.now
+	return verifier, clock, path
+}
+
+func claims(at time.Time, extra map[string]interface{}) map[string]interface{} {
+	claims := map[string]interface{}{
+		"iss": "https://id.example.com",
+		"aud": "wiki-names",
+		"sub": "reports",
+		"exp": at.Add(time.Hour).Unix(),
+	}
+	for name, value := range extra {
+		claims[name] = value
+	}
+	return claims
+}
+
+func TestVerify(t *testing.T) {
+	rsaKey, ecKey := newRSAKey(t, "rsa-1"), newECKey(t, "ec-1")
+	verifier, clock, _ := setupVerifier(t, rsaKey, ecKey)
+
+	verified, err := verifier.Verify(rsaKey.sign(t, claims(clock.at, map[string]interface{}{"tenant": "acme", "scope": "lookup:read batch:write"})))
+	assert.Nil(t, err)
+	assert.EqualValues(t, &wiki_domain.Claims{Subject: "reports", Tenant: "acme", Scopes: []string{"lookup:read", "batch:write"}, ExpiresAt: clock.at.Add(time.Hour)}, verified)
+
+	// The scopes can be a list, and the audience one of several
+	verified, err = verifier.Verify(ecKey.sign(t, claims(clock.at, map[string]interface{}{"aud": []string{"other", "wiki-names"}, "scope": []string{"admin"}})))
+	assert.Nil(t, err)
+	assert.True(t, verified.HasScope(wiki_domain.ScopeAdmin))
+	assert.False(t, verified.HasScope(wiki_domain.ScopeLookupRead))
+
+	verifier.TenantClaim, verifier.ScopeClaim = "org", "scp"
+	verified, err = verifier.Verify(rsaKey.sign(t, claims(clock.at, map[string]interface{}{"org": "globex", "scp": []string{"lookup:read"}})))
+	assert.Nil(t, err)
+	assert.EqualValues(t, "globex", verified.Tenant)
+	assert.EqualValues(t, []string{"lookup:read"}, verified.Scopes)
+}
+
+func TestVerifyRejects(t *testing.T) {
+	rsaKey, ecKey := newRSAKey(t, "rsa-1"), newECKey(t, "ec-1")
+	verifier, clock, _ := setupVerifier(t, rsaKey, ecKey)
+	other := newRSAKey(t, "rsa-1")
+
+	tokens := map[string]string{
+		"wrong issuer":     rsaKey.sign(t, claims(clock.at, map[string]interface{}{"iss": "https://evil.example.com"})),
+		"wrong audience":   rsaKey.sign(t, claims(clock.at, map[string]interface{}{"aud": "other"})),
+		"expired":          rsaKey.sign(t, claims(clock.at, map[string]interface{}{"exp": clock.at.Add(-2 * time.Minute).Unix()})),
+		"no expiry":        rsaKey.sign(t, claims(clock.at, map[string]interface{}{"exp": nil})),
+		"not valid yet":    rsaKey.sign(t, claims(clock.at, map[string]interface{}{"nbf": clock.at.Add(5 * time.Minute).Unix()})),
+		"other key":        other.sign(t, claims(clock.at, nil)),
+		"unknown key":      signWith(t, other.key, map[string]string{"alg": "RS256", "kid": "rsa-2"}, claims(clock.at, nil)),
+		"alg of other key": signWith(t, rsaKey.key, map[string]string{"alg": "ES256", "kid": "rsa-1"}, claims(clock.at, nil)),
+		"alg none":         strings.TrimSuffix(signWith(t, rsaKey.key, map[string]string{"alg": "none", "kid": "rsa-1"}, claims(clock.at, nil)), "."),
+		// HMAC is refused whatever the token is signed with, the public key can't be a secret
+		"alg HS256": signWith(t, rsaKey.key, map[string]string{"alg": "HS256", "kid": "rsa-1"}, claims(clock.at, nil)),
+		"not a jwt": "wk_0123",
+	}
+	for name, token := range tokens {
+		verified, err := verifier.Verify(token)
+		assert.Nil(t, verified, name)
+		if assert.NotNil(t, err, name) {
+			assert.EqualValues(t, wiki_domain.ErrInvalidToken, err.ErrorCode, name)
+			assert.EqualValues(t, http.StatusUnauthorized, err.Code, name)
+		}
+	}
+
+	// Within the leeway an expired token still passes
+	_, err := verifier.Verify(rsaKey.sign(t, claims(clock.at, map[string]interface{}{"exp": clock.at.Add(-30 * time.Second).Unix()})))
+	assert.Nil(t, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, rotated := newRSAKey(t, "2024-02"), newRSAKey(t, "2024-03")
+	var reads int32
+	served := jwks(old)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&reads, 1)
+		w.Write(served)
+	}))
+	defer server.Close()
+	clock := &clock{at: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)}
+	keys := NewKeySet(server.URL, "")
+	keys.now = clock.now
+	verifier := NewVerifier(keys, "https://id.example.com", "wiki-names")
+	verifier.now = clock.now
+
+	_, err := verifier.Verify(old.sign(t, claims(clock.at, nil)))
+	assert.Nil(t, err)
+	_, err = verifier.Verify(old.sign(t, claims(clock.at, nil)))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&reads))
+
+	// A token of the new key reads the set again, but only once a minute
+	served = jwks(old, rotated)
+	_, err = verifier.Verify(rotated.sign(t, claims(clock.at, nil)))
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 1, atomic.LoadInt32(&reads))
+	clock.at = clock.at.Add(time.Minute)
+	_, err = verifier.Verify(rotated.sign(t, claims(clock.at, nil)))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(&reads))
+
+	// The old key is gone after Refresh, unless the set can't be read, then the keys we have are kept
+	served = jwks(rotated)
+	clock.at = clock.at.Add(2 * time.Hour)
+	_, err = verifier.Verify(old.sign(t, claims(clock.at, nil)))
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 3, atomic.LoadInt32(&reads))
+
+	served = []byte("not json")
+	clock.at = clock.at.Add(2 * time.Hour)
+	_, err = verifier.Verify(rotated.sign(t, claims(clock.at, nil)))
+	assert.Nil(t, err)
+	assert.EqualValues(t, 4, atomic.LoadInt32(&reads))
+}
+
+func TestKeySetFile(t *testing.T) {
+	key := newECKey(t, "")
+	verifier, clock, path := setupVerifier(t, key)
+	// A token without a kid is for the only key of the set
+	_, apiError := verifier.Verify(key.sign(t, claims(clock.at, nil)))
+	assert.Nil(t, apiError)
+
+	writeJWKS(t, path)
+	clock.at = clock.at.Add(2 * time.Hour)
+	_, apiError = verifier.Verify(key.sign(t, claims(clock.at, nil)))
+	assert.Nil(t, apiError, "the keys we have are kept")
+
+	// Small RSA keys and keys for encryption are skipped
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+	encryption := newRSAKey(t, "enc")
+	jwk := encryption.jwk()
+	jwk["use"] = "enc"
+	bytes, _ := json.Marshal(map[string]interface{}{"keys": []interface{}{(&signingKey{id: "small", alg: "RS256", key: small}).jwk(), jwk}})
+	assert.Nil(t, os.WriteFile(path, bytes, 0o600))
+	_, err = NewKeySet("", path).read()
+	assert.EqualError(t, err, "the key set has no signing keys we can use")
+}
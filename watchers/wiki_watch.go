@@ -0,0 +1,317 @@
+package wiki_watch
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	wiki_domain "wiki-names/domains"
+	wiki_locale "wiki-names/locales"
+	wiki_provider "wiki-names/providers"
+)
+
+// Counters for the watch hub, published on /debug/vars
+var (
+	watchSubscribers = expvar.NewInt("watch_subscribers")
+	watchPolls       = expvar.NewInt("watch_polls")
+	watchChanges     = expvar.NewInt("watch_changes")
+	watchDropped     = expvar.NewInt("watch_dropped")
+)
+
+// Hub polls the short descriptions of every watched name on each Interval and pushes the ones
+// that changed to the subscriptions watching them. A subscription that doesn't keep up with its
+// events is dropped rather than holding the others back.
+type Hub struct {
+	Interval time.Duration
+	// Heartbeat is how often connections with nothing to send tell the client they're still alive
+	Heartbeat      time.Duration
+	MaxSubscribers int
+	// MaxNames is how many names one subscription may watch
+	MaxNames int
+	// Buffer is how many events a subscription can fall behind by before it is dropped
+	Buffer int
+
+	mu            sync.Mutex
+	subscriptions map[*Subscription]bool
+	// limited counts the subscriptions made through Subscribe
+	limited int
+	// seen is the last description of each watched name, keyed by locale|name
+	seen   map[string]seenDescription
+	wake   chan struct{}
+	closed bool
+}
+
+type seenDescription struct {
+	description string
+	changedAt   time.Time
+}
+
+// Subscription is one client's set of watched names, Events is closed when it is dropped or the hub shuts down
+type Subscription struct {
+	Locale string
+	Names  []string
+	Events <-chan wiki_domain.WatchEvent
+
+	events  chan wiki_domain.WatchEvent
+	hub     *Hub
+	limited bool
+}
+
+// Watcher is the hub behind /watch, app.RunApp configures and runs it
+var Watcher = NewHub()
+
+func NewHub() *Hub {
+	return &Hub{
+		Interval:       time.Minute,
+		Heartbeat:      15 * time.Second,
+		MaxSubscribers: 1000,
+		MaxNames:       50,
+		Buffer:         16,
+		subscriptions:  map[*Subscription]bool{},
+		seen:           map[string]seenDescription{},
+		wake:           make(chan struct{}, 1),
+	}
+}
+
+// Subscribe watches the names on a locale's wiki, the descriptions already known are sent straight
+// away and the others are looked up without waiting for the next tick. The locale defaults to the
+// tenant's, and must be one it allows.
+func (h *Hub) Subscribe(tenant *wiki_domain.Tenant, locale string, names []string) (*Subscription, *wiki_domain.WikiError) {
+	code, watched, err := h.normalize(tenant, locale, names)
+	if err != nil {
+		return nil, err
+	}
+	if len(watched) > h.MaxNames {
+		return nil, &wiki_domain.WikiError{Code: http.StatusBadRequest, ErrorMessage: fmt.Sprintf("at most %d names can be watched at once", h.MaxNames)}
+	}
+	return h.add(code, watched, h.Buffer, true)
+}
+
+// Follow is Subscribe for the service's own listeners, such as webhooks: it doesn't count towards
+// MaxSubscribers or MaxNames, and buffer is how many events it may fall behind by
+func (h *Hub) Follow(locale string, names []string, buffer int) (*Subscription, *wiki_domain.WikiError) {
+	code, watched, err := h.normalize(nil, locale, names)
+	if err != nil {
+		return nil, err
+	}
+	return h.add(code, watched, buffer, false)
+}
+
+func (h *Hub) normalize(tenant *wiki_domain.Tenant, locale string, names []string) (string, []string, *wiki_domain.WikiError) {
+	if locale == "" {
+		locale = tenant.LocaleOr(wiki_locale.DefaultLocale)
+	}
+	normalized, err := wiki_locale.Locales.Normalize(locale)
+	if err != nil {
+		return "", nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidLocale, err.Error())
+	}
+	if !tenant.AllowsLocale(normalized.Code) {
+		return "", nil, wiki_domain.NewTypedError(wiki_domain.ErrNotEnabled, fmt.Sprintf("locale %s is not enabled for tenant %s", normalized.Code, tenant.ID))
+	}
+	var watched []string
+	for _, name := range names {
+		name = strings.ReplaceAll(strings.TrimSpace(name), " ", "_")
+		if name != "" && !contains(watched, name) {
+			watched = append(watched, name)
+		}
+	}
+	if len(watched) == 0 {
+		return "", nil, &wiki_domain.WikiError{Code: http.StatusBadRequest, ErrorMessage: "names must list at least one name"}
+	}
+	return normalized.Code, watched, nil
+}
+
+func (h *Hub) add(locale string, names []string, buffer int, limited bool) (*Subscription, *wiki_domain.WikiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, &wiki_domain.WikiError{Code: http.StatusServiceUnavailable, ErrorMessage: "the server is shutting down"}
+	}
+	if limited && h.limited >= h.MaxSubscribers {
+		apiError := wiki_domain.NewTypedError(wiki_domain.ErrTooManySubscribers, fmt.Sprintf("already serving the maximum of %d watchers", h.MaxSubscribers))
+		apiError.RetryAfter = int(h.Interval.Seconds())
+		return nil, apiError
+	}
+	// Leave room for the descriptions we already know on top of the usual buffer
+	events := make(chan wiki_domain.WatchEvent, buffer+len(names))
+	subscription := &Subscription{Locale: locale, Names: names, Events: events, events: events, hub: h, limited: limited}
+	h.subscriptions[subscription] = true
+	if limited {
+		h.limited++
+		watchSubscribers.Add(1)
+	}
+	for _, name := range names {
+		if seen, ok := h.seen[locale+"|"+name]; ok && seen.description != "" {
+			events <- wiki_domain.WatchEvent{Name: name, Language: locale, ShortDescription: seen.description, Initial: true, ChangedAt: seen.changedAt}
+		}
+	}
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+	return subscription, nil
+}
+
+// Close stops watching, it's fine to call it after the hub dropped the subscription
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+func (s *Subscription) watches(locale string, name string) bool {
+	return s.Locale == locale && contains(s.Names, name)
+}
+
+// Run polls on every Interval, and when a subscription brings new names, until the context is
+// cancelled. The hub is closed when it returns.
+func (h *Hub) Run(ctx context.Context) {
+	log.Printf("Watch: polling watched names every %s", h.Interval)
+	ticker := time.NewTicker(h.Interval)
+	defer ticker.Stop()
+	defer h.Close()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Watch: hub stopped")
+			return
+		case <-ticker.C:
+		case <-h.wake:
+		}
+		h.poll(ctx)
+	}
+}
+
+// Close ends every subscription and turns new ones away, so the connections streaming them can return
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for subscription := range h.subscriptions {
+		h.remove(subscription)
+	}
+}
+
+// Subscribers is how many subscriptions made through Subscribe are open
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.limited
+}
+
+// poll looks the watched names up, one GetShortDescriptions per locale, and publishes what changed.
+// A locale whose lookup fails is skipped until the next poll rather than reported as removed.
+func (h *Hub) poll(ctx context.Context) {
+	watchPolls.Add(1)
+	h.mu.Lock()
+	watched := map[string][]string{}
+	for subscription := range h.subscriptions {
+		for _, name := range subscription.Names {
+			if !contains(watched[subscription.Locale], name) {
+				watched[subscription.Locale] = append(watched[subscription.Locale], name)
+			}
+		}
+	}
+	h.mu.Unlock()
+
+	locales := make([]string, 0, len(watched))
+	for locale := range watched {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	for _, locale := range locales {
+		names := watched[locale]
+		sort.Strings(names)
+		descriptions, err := wiki_provider.WikiProvider.GetShortDescriptions(ctx, locale, names)
+		if err != nil {
+			log.Printf("Watch: failed to poll %d names on %s: %d %s", len(names), locale, err.Code, err.ErrorMessage)
+			continue
+		}
+		h.publish(locale, names, descriptions)
+	}
+
+	// Forget the names nobody watches anymore
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for key := range h.seen {
+		locale, name := splitKey(key)
+		if !h.watched(locale, name) {
+			delete(h.seen, key)
+		}
+	}
+}
+
+func (h *Hub) publish(locale string, names []string, descriptions map[string]string) {
+	now := time.Now().UTC()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, name := range names {
+		key := locale + "|" + name
+		seen, known := h.seen[key]
+		current := descriptions[name]
+		if known && current == seen.description {
+			continue
+		}
+		h.seen[key] = seenDescription{description: current, changedAt: now}
+		// A name with no description yet gets its first event once one is added
+		if !known && current == "" {
+			continue
+		}
+		watchChanges.Add(1)
+		change := wiki_domain.WatchEvent{Name: name, Language: locale, ShortDescription: current, Previous: seen.description, Initial: !known, ChangedAt: now}
+		for subscription := range h.subscriptions {
+			if !subscription.watches(locale, name) {
+				continue
+			}
+			select {
+			case subscription.events <- change:
+			default:
+				log.Printf("Watch: dropping a subscription %d events behind", cap(subscription.events))
+				watchDropped.Add(1)
+				h.remove(subscription)
+			}
+		}
+	}
+}
+
+func (h *Hub) watched(locale string, name string) bool {
+	for subscription := range h.subscriptions {
+		if subscription.watches(locale, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove needs the lock held
+func (h *Hub) remove(subscription *Subscription) {
+	if !h.subscriptions[subscription] {
+		return
+	}
+	delete(h.subscriptions, subscription)
+	if subscription.limited {
+		h.limited--
+		watchSubscribers.Add(-1)
+	}
+	close(subscription.events)
+}
+
+func splitKey(key string) (string, string) {
+	i := strings.Index(key, "|")
+	return key[:i], key[i+1:]
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
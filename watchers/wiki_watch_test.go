@@ -0,0 +1,232 @@
+package wiki_watch
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_provider "wiki-names/providers"
+)
+
+type providerMock struct {
+	wiki_provider.WikiProviderStruct
+	mu           sync.Mutex
+	descriptions map[string]string
+	calls        [][]string
+	fail         bool
+}
+
+// We are mocking the provider method "GetShortDescriptions", names missing from descriptions have none
+func (pm *providerMock) GetShortDescriptions(ctx context.Context, locale string, titles []string) (map[string]string, *wiki_domain.WikiError) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.calls = append(pm.calls, append([]string{locale}, titles...))
+	if pm.fail {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrUpstreamUnavailable, "connection refused")
+	}
+	descriptions := map[string]string{}
+	for _, title := range titles {
+		if description, ok := pm.descriptions[title]; ok {
+			descriptions[title] = description
+		}
+	}
+	return descriptions, nil
+}
+
+func (pm *providerMock) set(title string, description string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.descriptions[title] = description
+}
+
+func setupProvider(t *testing.T, descriptions map[string]string) *providerMock {
+	mock := &providerMock{descriptions: descriptions}
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = mock
+	t.Cleanup(func() { wiki_provider.WikiProvider = original })
+	return mock
+}
+
+// received drains what is waiting on the subscription without blocking
+func received(subscription *Subscription) []wiki_domain.WatchEvent {
+	var events []wiki_domain.WatchEvent
+	for {
+		select {
+		case event, ok := <-subscription.Events:
+			if !ok {
+				return events
+			}
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestPollPublishesChanges(t *testing.T) {
+	mock := setupProvider(t, map[string]string{"Yoshua_Bengio": "Canadian computer scientist"})
+	hub := NewHub()
+
+	subscription, err := hub.Subscribe(nil, "", []string{"Yoshua Bengio", "Geoffrey_Hinton", "Yoshua_Bengio"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, []string{"Yoshua_Bengio", "Geoffrey_Hinton"}, subscription.Names)
+
+	hub.poll(context.Background())
+	events := received(subscription)
+	assert.Len(t, events, 1)
+	assert.EqualValues(t, "Yoshua_Bengio", events[0].Name)
+	assert.EqualValues(t, "en", events[0].Language)
+	assert.EqualValues(t, "Canadian computer scientist", events[0].ShortDescription)
+	assert.Empty(t, events[0].Previous)
+	assert.True(t, events[0].Initial)
+
+	// Nothing changed, nothing is sent
+	hub.poll(context.Background())
+	assert.Empty(t, received(subscription))
+
+	mock.set("Yoshua_Bengio", "Canadian computer scientist and Turing Award laureate")
+	mock.set("Geoffrey_Hinton", "British-Canadian computer scientist")
+	hub.poll(context.Background())
+	events = received(subscription)
+	assert.Len(t, events, 2)
+	assert.EqualValues(t, "Geoffrey_Hinton", events[0].Name)
+	assert.EqualValues(t, "Canadian computer scientist", events[1].Previous)
+	// Geoffrey Hinton had no description before, it was added rather than seen for the first time
+	assert.False(t, events[0].Initial)
+	assert.EqualValues(t, []string{"en", "Geoffrey_Hinton", "Yoshua_Bengio"}, mock.calls[0])
+}
+
+func TestSubscribeSendsKnownDescriptions(t *testing.T) {
+	setupProvider(t, map[string]string{"Yoshua_Bengio": "Canadian computer scientist"})
+	hub := NewHub()
+	first, _ := hub.Subscribe(nil, "en", []string{"Yoshua_Bengio"})
+	hub.poll(context.Background())
+	received(first)
+
+	second, err := hub.Subscribe(nil, "en", []string{"Yoshua_Bengio"})
+	assert.Nil(t, err)
+	events := received(second)
+	assert.Len(t, events, 1)
+	assert.EqualValues(t, "Canadian computer scientist", events[0].ShortDescription)
+
+	// A different wiki is watched separately
+	french, _ := hub.Subscribe(nil, "fr", []string{"Yoshua_Bengio"})
+	assert.Empty(t, received(french))
+}
+
+func TestSubscribeTenantLocales(t *testing.T) {
+	setupProvider(t, map[string]string{})
+	hub := NewHub()
+	tenant := &wiki_domain.Tenant{ID: "acme", DefaultLocale: "fr", AllowedLocales: []string{"fr", "de"}}
+
+	subscription, err := hub.Subscribe(tenant, "", []string{"Yoshua_Bengio"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "fr", subscription.Locale)
+	_, err = hub.Subscribe(tenant, "en", []string{"Yoshua_Bengio"})
+	assert.EqualValues(t, wiki_domain.ErrNotEnabled, err.ErrorCode)
+}
+
+func TestSubscribeLimits(t *testing.T) {
+	setupProvider(t, map[string]string{})
+	hub := NewHub()
+	hub.MaxSubscribers, hub.MaxNames = 1, 2
+
+	_, err := hub.Subscribe(nil, "en", []string{"a", "b", "c"})
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	_, err = hub.Subscribe(nil, "en", []string{" ", ""})
+	assert.EqualValues(t, http.StatusBadRequest, err.Code)
+	_, err = hub.Subscribe(nil, "xx-nowhere", []string{"a"})
+	assert.EqualValues(t, wiki_domain.ErrInvalidLocale, err.ErrorCode)
+
+	subscription, err := hub.Subscribe(nil, "en", []string{"a"})
+	assert.Nil(t, err)
+	_, err = hub.Subscribe(nil, "en", []string{"b"})
+	assert.EqualValues(t, wiki_domain.ErrTooManySubscribers, err.ErrorCode)
+	assert.EqualValues(t, http.StatusServiceUnavailable, err.Code)
+	assert.EqualValues(t, 60, err.RetryAfter)
+
+	// Closing makes room again, and closing twice is fine
+	subscription.Close()
+	subscription.Close()
+	_, err = hub.Subscribe(nil, "en", []string{"b"})
+	assert.Nil(t, err)
+}
+
+func TestFollowIsNotLimited(t *testing.T) {
+	setupProvider(t, map[string]string{})
+	hub := NewHub()
+	hub.MaxSubscribers, hub.MaxNames = 0, 1
+
+	follower, err := hub.Follow("en", []string{"a", "b"}, 100)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, hub.Subscribers())
+	assert.EqualValues(t, 102, cap(follower.Events))
+	_, err = hub.Subscribe(nil, "en", []string{"a"})
+	assert.EqualValues(t, wiki_domain.ErrTooManySubscribers, err.ErrorCode)
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	mock := setupProvider(t, map[string]string{})
+	hub := NewHub()
+	hub.Buffer = 1
+	slow, _ := hub.Subscribe(nil, "en", []string{"a"})
+	fast, _ := hub.Subscribe(nil, "en", []string{"a"})
+
+	for _, description := range []string{"one", "two", "three"} {
+		mock.set("a", description)
+		hub.poll(context.Background())
+		received(fast)
+	}
+	assert.EqualValues(t, 1, hub.Subscribers())
+	events := received(slow)
+	// The channel is closed once what it buffered has been read
+	assert.Len(t, events, 2)
+	_, ok := <-slow.Events
+	assert.False(t, ok)
+}
+
+func TestPollKeepsDescriptionsOnError(t *testing.T) {
+	mock := setupProvider(t, map[string]string{"a": "one"})
+	hub := NewHub()
+	subscription, _ := hub.Subscribe(nil, "en", []string{"a"})
+	hub.poll(context.Background())
+	received(subscription)
+
+	mock.fail = true
+	hub.poll(context.Background())
+	mock.fail = false
+	hub.poll(context.Background())
+	assert.Empty(t, received(subscription))
+}
+
+func TestRunClosesSubscriptions(t *testing.T) {
+	setupProvider(t, map[string]string{"a": "one"})
+	hub := NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(stopped)
+	}()
+
+	subscription, _ := hub.Subscribe(nil, "en", []string{"a"})
+	// The new name is looked up straight away, not on the next tick
+	select {
+	case event := <-subscription.Events:
+		assert.EqualValues(t, "one", event.ShortDescription)
+	case <-time.After(time.Second):
+		t.Fatal("no event for the new subscription")
+	}
+
+	cancel()
+	<-stopped
+	_, ok := <-subscription.Events
+	assert.False(t, ok)
+	_, err := hub.Subscribe(nil, "en", []string{"a"})
+	assert.EqualValues(t, http.StatusServiceUnavailable, err.Code)
+}
@@ -0,0 +1,39 @@
+package wiki_webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// callbackTransport is the transport of the webhook client. Its dialer checks the address it is
+// about to connect to, after the name was resolved, so a callback URL can't reach our own network
+// however its DNS answers.
+func callbackTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second, Control: refusePrivate}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would make the connection for us, out of the dialer's sight
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
+
+func refusePrivate(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+		return fmt.Errorf("refusing to connect to %s, callbacks can't reach private addresses", host)
+	}
+	return nil
+}
+
+// publicIP is false for loopback, private, link-local (cloud metadata endpoints among them),
+// unspecified and multicast addresses
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
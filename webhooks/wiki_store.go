@@ -0,0 +1,58 @@
+package wiki_webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	wiki_domain "wiki-names/domains"
+)
+
+// State is what a Store keeps across restarts
+type State struct {
+	Webhooks   []*wiki_domain.Webhook  `json:"webhooks"`
+	Deliveries []*wiki_domain.Delivery `json:"deliveries"`
+}
+
+type Store interface {
+	Load() (*State, error)
+	Save(state *State) error
+}
+
+// FileStore keeps the state in one JSON file, replaced as a whole on every save
+type FileStore struct {
+	Path string
+}
+
+// Load reads the file, a file that doesn't exist yet is an empty state
+func (s *FileStore) Load() (*State, error) {
+	bytes, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state State
+	if err := json.Unmarshal(bytes, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// Save writes to a temporary file first, so a crash mid-write leaves the previous state in place
+func (s *FileStore) Save(state *State) error {
+	bytes, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	temporary := s.Path + ".tmp"
+	if err := os.WriteFile(temporary, bytes, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(temporary, s.Path)
+}
@@ -0,0 +1,699 @@
+package wiki_webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	wiki_domain "wiki-names/domains"
+	wiki_locale "wiki-names/locales"
+	wiki_provider "wiki-names/providers"
+	wiki_watch "wiki-names/watchers"
+)
+
+// The headers every payload is sent with, the signature is over "<timestamp>.<body>"
+const (
+	DeliveryHeader  = "X-Webhook-Delivery"
+	EventHeader     = "X-Webhook-Event"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+
+	descriptionChanged = "description.changed"
+)
+
+// Counters for the webhook deliveries, published on /debug/vars
+var (
+	webhookDelivered = expvar.NewInt("webhook_delivered")
+	webhookFailed    = expvar.NewInt("webhook_failed_attempts")
+	webhookDead      = expvar.NewInt("webhook_dead")
+)
+
+// Manager keeps the registered webhooks following their names on the watch hub, and POSTs a
+// signed payload to the webhook's URL for every change. A delivery that fails is retried with
+// exponential backoff, and one that fails MaxAttempts times goes to the dead-letter list.
+type Manager struct {
+	Store  Store
+	Client *http.Client
+	// Hub is the watch hub followed, wiki_watch.Watcher when nil
+	Hub *wiki_watch.Hub
+	// MaxWebhooks is how many webhooks each tenant can have
+	MaxWebhooks int
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubled after each one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Resync is how often category members are looked up again
+	Resync             time.Duration
+	MaxCategoryMembers int
+	// LogSize is how many delivered and how many dead deliveries are kept
+	LogSize int
+	Workers int
+
+	mu         sync.Mutex
+	webhooks   map[string]*wiki_domain.Webhook
+	deliveries []*wiki_domain.Delivery
+	follows    map[string]*wiki_watch.Subscription
+	inFlight   map[string]bool
+	dirty      bool
+	// running is the context of Run while it runs, the follows it starts end with it
+	running context.Context
+	wake    chan struct{}
+}
+
+// Webhooks is the manager behind /webhooks, app.RunApp gives it its store and runs it
+var Webhooks = NewManager()
+
+func NewManager() *Manager {
+	return &Manager{
+		Client:             &http.Client{Timeout: 10 * time.Second, Transport: callbackTransport()},
+		MaxWebhooks:        1000,
+		MaxAttempts:        8,
+		Backoff:            30 * time.Second,
+		MaxBackoff:         time.Hour,
+		Resync:             10 * time.Minute,
+		MaxCategoryMembers: 500,
+		LogSize:            1000,
+		Workers:            4,
+		webhooks:           map[string]*wiki_domain.Webhook{},
+		follows:            map[string]*wiki_watch.Subscription{},
+		inFlight:           map[string]bool{},
+		wake:               make(chan struct{}, 1),
+	}
+}
+
+// Sign is the hex HMAC-SHA256 of "<timestamp>.<body>" with the webhook's secret, receivers
+// compute the same and compare it to the X-Webhook-Signature header after "sha256="
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Load reads the webhooks and the deliveries not finished yet from the store
+func (m *Manager) Load() error {
+	if m.Store == nil {
+		return nil
+	}
+	state, err := m.Store.Load()
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, webhook := range state.Webhooks {
+		m.webhooks[webhook.ID] = webhook
+	}
+	m.deliveries = state.Deliveries
+	log.Printf("Webhooks: loaded %d webhooks and %d deliveries", len(state.Webhooks), len(state.Deliveries))
+	return nil
+}
+
+// Run follows every webhook's names and sends the deliveries as they come due, until the context
+// is cancelled. It then waits for the deliveries in flight and saves.
+func (m *Manager) Run(ctx context.Context) {
+	m.mu.Lock()
+	m.running = ctx
+	m.mu.Unlock()
+	// Following takes upstream calls for the categories, so it runs beside the deliveries
+	go func() {
+		resync := time.NewTicker(m.Resync)
+		defer resync.Stop()
+		for {
+			m.resync(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-resync.C:
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	workers := make(chan struct{}, m.Workers)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			m.mu.Lock()
+			m.running = nil
+			m.flush()
+			m.mu.Unlock()
+			log.Println("Webhooks: stopped")
+			return
+		case <-ticker.C:
+		case <-m.wake:
+		}
+		for _, due := range m.due() {
+			select {
+			case <-ctx.Done():
+				m.finish(due, func() {})
+			case workers <- struct{}{}:
+				wg.Add(1)
+				go func(delivery *wiki_domain.Delivery) {
+					defer wg.Done()
+					defer func() { <-workers }()
+					m.attempt(ctx, delivery)
+				}(due)
+			}
+		}
+		m.mu.Lock()
+		m.flush()
+		m.mu.Unlock()
+	}
+}
+
+// resync follows the webhooks the hub isn't sending to, and looks the members of categories up again
+func (m *Manager) resync(ctx context.Context) {
+	m.mu.Lock()
+	var stale []string
+	for id, webhook := range m.webhooks {
+		if _, followed := m.follows[id]; !followed || len(webhook.Categories) > 0 {
+			stale = append(stale, id)
+		}
+	}
+	m.mu.Unlock()
+	for _, id := range stale {
+		m.follow(ctx, id)
+	}
+}
+
+// Create registers a webhook of the tenant and returns it with its secret, the only time the secret is shown
+func (m *Manager) Create(tenant *wiki_domain.Tenant, request wiki_domain.WebhookRequest) (*wiki_domain.Webhook, *wiki_domain.WikiError) {
+	// Webhooks are scoped to their tenant, callers without one would all share them
+	if tenantID(tenant) == "" {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrNotEnabled, "webhooks need an API key or token that belongs to a tenant")
+	}
+	if callback, err := url.Parse(request.URL); err != nil || (callback.Scheme != "http" && callback.Scheme != "https") {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidRequest, "url must be an http or https URL")
+	}
+	if len(request.Names) == 0 && len(request.Categories) == 0 {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidRequest, "names or categories must list at least one name")
+	}
+	if request.Locale == "" {
+		request.Locale = tenant.LocaleOr(wiki_locale.DefaultLocale)
+	}
+	locale, err := wiki_locale.Locales.Normalize(request.Locale)
+	if err != nil {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrInvalidLocale, err.Error())
+	}
+	if !tenant.AllowsLocale(locale.Code) {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrNotEnabled, fmt.Sprintf("locale %s is not enabled for tenant %s", locale.Code, tenant.ID))
+	}
+	webhook := &wiki_domain.Webhook{
+		ID:         newID(16),
+		Tenant:     tenantID(tenant),
+		URL:        request.URL,
+		Locale:     locale.Code,
+		Names:      cleanNames(request.Names),
+		Categories: cleanNames(request.Categories),
+		Secret:     request.Secret,
+		CreatedAt:  time.Now().UTC(),
+		Seen:       map[string]string{},
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = newID(32)
+	}
+
+	m.mu.Lock()
+	owned := 0
+	for _, existing := range m.webhooks {
+		if existing.Tenant == webhook.Tenant {
+			owned++
+		}
+	}
+	if owned >= m.MaxWebhooks {
+		m.mu.Unlock()
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrTooManySubscribers, fmt.Sprintf("tenant %s already has the maximum of %d webhooks", webhook.Tenant, m.MaxWebhooks))
+	}
+	m.webhooks[webhook.ID] = webhook
+	m.dirty = true
+	m.flush()
+	running := m.running
+	created := *webhook
+	m.mu.Unlock()
+
+	if running != nil {
+		// Resolving the categories takes upstream calls, the caller doesn't need to wait for them
+		go m.follow(running, webhook.ID)
+	}
+	created.Seen = nil
+	return &created, nil
+}
+
+// Get returns the tenant's webhook without its secret, another tenant's webhook is reported as missing
+func (m *Manager) Get(tenant *wiki_domain.Tenant, id string) (*wiki_domain.Webhook, *wiki_domain.WikiError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	webhook, apiError := m.owned(tenant, id)
+	if apiError != nil {
+		return nil, apiError
+	}
+	return public(webhook), nil
+}
+
+// Delete stops following the tenant's webhook's names and drops its pending deliveries, the finished ones stay in the log
+func (m *Manager) Delete(tenant *wiki_domain.Tenant, id string) *wiki_domain.WikiError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, apiError := m.owned(tenant, id); apiError != nil {
+		return apiError
+	}
+	delete(m.webhooks, id)
+	if follow, ok := m.follows[id]; ok {
+		delete(m.follows, id)
+		follow.Close()
+	}
+	kept := m.deliveries[:0]
+	for _, delivery := range m.deliveries {
+		if delivery.Webhook != id || delivery.Status != wiki_domain.DeliveryPending {
+			kept = append(kept, delivery)
+		}
+	}
+	m.deliveries = kept
+	m.dirty = true
+	m.flush()
+	return nil
+}
+
+func (m *Manager) owned(tenant *wiki_domain.Tenant, id string) (*wiki_domain.Webhook, *wiki_domain.WikiError) {
+	webhook, ok := m.webhooks[id]
+	if !ok || webhook.Tenant != tenantID(tenant) {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrNotFound, fmt.Sprintf("webhook %s does not exist", id))
+	}
+	return webhook, nil
+}
+
+// List returns the webhooks without their secrets, oldest first, only the tenant's when tenant isn't empty
+func (m *Manager) List(tenant string) []wiki_domain.Webhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.list(tenant)
+}
+
+func (m *Manager) list(tenant string) []wiki_domain.Webhook {
+	webhooks := []wiki_domain.Webhook{}
+	for _, webhook := range m.webhooks {
+		if tenant == "" || webhook.Tenant == tenant {
+			webhooks = append(webhooks, *public(webhook))
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].ID < webhooks[j].ID
+		}
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks
+}
+
+// Deliveries returns the delivery log, newest first
+func (m *Manager) Deliveries(query wiki_domain.DeliveryQuery) []wiki_domain.Delivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if query.Limit == 0 {
+		query.Limit = 100
+	}
+	deliveries := []wiki_domain.Delivery{}
+	for i := len(m.deliveries) - 1; i >= 0 && len(deliveries) < query.Limit; i-- {
+		delivery := m.deliveries[i]
+		if (query.Webhook == "" || delivery.Webhook == query.Webhook) && (query.Status == "" || delivery.Status == query.Status) {
+			deliveries = append(deliveries, *delivery)
+		}
+	}
+	return deliveries
+}
+
+// Retry sends a dead delivery again, with a fresh set of attempts
+func (m *Manager) Retry(id string) (*wiki_domain.Delivery, *wiki_domain.WikiError) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delivery, apiError := m.deadLetter(id)
+	if apiError != nil {
+		return nil, apiError
+	}
+	if _, ok := m.webhooks[delivery.Webhook]; !ok {
+		return nil, wiki_domain.NewTypedError(wiki_domain.ErrConflict, fmt.Sprintf("webhook %s has been deleted", delivery.Webhook))
+	}
+	delivery.Status, delivery.Attempts, delivery.NextAttempt = wiki_domain.DeliveryPending, 0, time.Now().UTC()
+	delivery.UpdatedAt = delivery.NextAttempt
+	m.dirty = true
+	m.flush()
+	m.signal()
+	retried := *delivery
+	return &retried, nil
+}
+
+// Discard drops a dead delivery from the dead-letter list
+func (m *Manager) Discard(id string) *wiki_domain.WikiError {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, apiError := m.deadLetter(id); apiError != nil {
+		return apiError
+	}
+	for i, delivery := range m.deliveries {
+		if delivery.ID == id {
+			m.deliveries = append(m.deliveries[:i], m.deliveries[i+1:]...)
+			break
+		}
+	}
+	m.dirty = true
+	m.flush()
+	return nil
+}
+
+func (m *Manager) deadLetter(id string) (*wiki_domain.Delivery, *wiki_domain.WikiError) {
+	for _, delivery := range m.deliveries {
+		if delivery.ID == id && delivery.Status == wiki_domain.DeliveryDead {
+			return delivery, nil
+		}
+	}
+	return nil, wiki_domain.NewTypedError(wiki_domain.ErrNotFound, fmt.Sprintf("dead letter %s does not exist", id))
+}
+
+// follow (re)subscribes the webhook to its names and the current members of its categories
+func (m *Manager) follow(ctx context.Context, id string) {
+	m.mu.Lock()
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		m.mu.Unlock()
+		return
+	}
+	locale, names, categories := webhook.Locale, append([]string{}, webhook.Names...), webhook.Categories
+	m.mu.Unlock()
+
+	for _, category := range categories {
+		names = append(names, m.members(ctx, locale, category)...)
+	}
+	names = cleanNames(names)
+	if len(names) == 0 {
+		return
+	}
+	hub := m.Hub
+	if hub == nil {
+		hub = wiki_watch.Watcher
+	}
+	subscription, apiError := hub.Follow(locale, names, 256)
+	if apiError != nil {
+		log.Printf("Webhooks: failed to follow the names of %s: %s", id, apiError.ErrorMessage)
+		return
+	}
+
+	m.mu.Lock()
+	if _, ok := m.webhooks[id]; !ok {
+		m.mu.Unlock()
+		subscription.Close()
+		return
+	}
+	if previous, ok := m.follows[id]; ok {
+		previous.Close()
+	}
+	m.follows[id] = subscription
+	// Forget the names the webhook doesn't follow anymore
+	for name := range webhook.Seen {
+		if !contains(names, name) {
+			delete(webhook.Seen, name)
+			m.dirty = true
+		}
+	}
+	m.mu.Unlock()
+
+	go func() {
+		for event := range subscription.Events {
+			m.observe(id, event)
+		}
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		// The hub dropped us, the next resync follows again
+		if m.follows[id] == subscription {
+			delete(m.follows, id)
+		}
+	}()
+}
+
+// members lists up to MaxCategoryMembers pages of the category, a lookup that fails leaves the category out until the next resync
+func (m *Manager) members(ctx context.Context, locale string, category string) []string {
+	var names []string
+	query := wiki_domain.CategoryQuery{Category: category, Locale: locale, Limit: 500}
+	for len(names) < m.MaxCategoryMembers {
+		members, apiError := wiki_provider.WikiProvider.GetCategoryMembers(ctx, query)
+		if apiError != nil {
+			log.Printf("Webhooks: failed to list the members of %s: %s", category, apiError.ErrorMessage)
+			break
+		}
+		for _, member := range members.Members {
+			names = append(names, member.Title)
+		}
+		if members.Cursor == "" {
+			break
+		}
+		query.Cursor = members.Cursor
+	}
+	if len(names) > m.MaxCategoryMembers {
+		names = names[:m.MaxCategoryMembers]
+	}
+	return names
+}
+
+// observe queues a delivery when the description differs from the last one the webhook saw. The
+// hub's first description of a name is only recorded, unless the webhook saw another one before.
+func (m *Manager) observe(id string, event wiki_domain.WatchEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	webhook, ok := m.webhooks[id]
+	if !ok {
+		return
+	}
+	if webhook.Seen == nil {
+		webhook.Seen = map[string]string{}
+	}
+	last, known := webhook.Seen[event.Name]
+	if known && last == event.ShortDescription {
+		return
+	}
+	webhook.Seen[event.Name] = event.ShortDescription
+	m.dirty = true
+	if !known && event.Initial {
+		return
+	}
+	if known {
+		event.Previous = last
+	}
+	event.Initial = false
+
+	now := time.Now().UTC()
+	delivery := &wiki_domain.Delivery{ID: newID(16), Webhook: id, Status: wiki_domain.DeliveryPending, NextAttempt: now, CreatedAt: now, UpdatedAt: now}
+	delivery.Payload, _ = json.Marshal(wiki_domain.WebhookPayload{ID: delivery.ID, Event: descriptionChanged, Webhook: id, Data: event})
+	m.deliveries = append(m.deliveries, delivery)
+	m.signal()
+}
+
+// due marks the pending deliveries whose time has come as in flight and returns them
+func (m *Manager) due() []*wiki_domain.Delivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var due []*wiki_domain.Delivery
+	for _, delivery := range m.deliveries {
+		if delivery.Status == wiki_domain.DeliveryPending && !m.inFlight[delivery.ID] && !delivery.NextAttempt.After(now) {
+			m.inFlight[delivery.ID] = true
+			due = append(due, delivery)
+		}
+	}
+	return due
+}
+
+// attempt POSTs the delivery once. A 2xx delivers it, anything else schedules the next attempt or,
+// after MaxAttempts, moves it to the dead letters. An attempt cut short by shutdown doesn't count.
+func (m *Manager) attempt(ctx context.Context, delivery *wiki_domain.Delivery) {
+	m.mu.Lock()
+	webhook, ok := m.webhooks[delivery.Webhook]
+	var callback, secret string
+	if ok {
+		callback, secret = webhook.URL, webhook.Secret
+	}
+	m.mu.Unlock()
+	if !ok {
+		m.finish(delivery, func() {})
+		return
+	}
+
+	status, retryAfter, err := m.post(ctx, delivery, callback, secret)
+	if err != nil && ctx.Err() != nil {
+		m.finish(delivery, func() {})
+		return
+	}
+	m.finish(delivery, func() {
+		now := time.Now().UTC()
+		delivery.Attempts++
+		delivery.ResponseStatus = status
+		delivery.UpdatedAt = now
+		if err == nil {
+			delivery.Status, delivery.LastError, delivery.NextAttempt = wiki_domain.DeliveryDelivered, "", time.Time{}
+			webhookDelivered.Add(1)
+			return
+		}
+		delivery.LastError = err.Error()
+		webhookFailed.Add(1)
+		if delivery.Attempts >= m.MaxAttempts {
+			delivery.Status, delivery.NextAttempt = wiki_domain.DeliveryDead, time.Time{}
+			webhookDead.Add(1)
+			log.Printf("Webhooks: delivery %s to %s is dead after %d attempts: %s", delivery.ID, delivery.Webhook, delivery.Attempts, delivery.LastError)
+			return
+		}
+		wait := m.backoff(delivery.Attempts)
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		delivery.NextAttempt = now.Add(wait)
+	})
+}
+
+func (m *Manager) post(ctx context.Context, delivery *wiki_domain.Delivery, callback string, secret string) (int, time.Duration, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, callback, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "wiki-names-webhooks")
+	request.Header.Set(DeliveryHeader, delivery.ID)
+	request.Header.Set(EventHeader, descriptionChanged)
+	request.Header.Set(TimestampHeader, timestamp)
+	request.Header.Set(SignatureHeader, "sha256="+Sign(secret, timestamp, delivery.Payload))
+	response, err := m.Client.Do(request)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		seconds, _ := strconv.Atoi(response.Header.Get("Retry-After"))
+		return response.StatusCode, time.Duration(seconds) * time.Second, fmt.Errorf("unexpected status code %d", response.StatusCode)
+	}
+	return response.StatusCode, 0, nil
+}
+
+func (m *Manager) finish(delivery *wiki_domain.Delivery, update func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.inFlight, delivery.ID)
+	update()
+	m.dirty = true
+	m.trim()
+}
+
+// backoff is Backoff doubled for each attempt after the first, up to MaxBackoff
+func (m *Manager) backoff(attempts int) time.Duration {
+	wait := m.Backoff
+	for i := 1; i < attempts && wait < m.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > m.MaxBackoff {
+		wait = m.MaxBackoff
+	}
+	return wait
+}
+
+// trim keeps the LogSize newest delivered and dead deliveries, and every pending one
+func (m *Manager) trim() {
+	counts := map[string]int{}
+	kept := make([]*wiki_domain.Delivery, 0, len(m.deliveries))
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		delivery := m.deliveries[i]
+		if delivery.Status != wiki_domain.DeliveryPending {
+			counts[delivery.Status]++
+			if counts[delivery.Status] > m.LogSize {
+				continue
+			}
+		}
+		kept = append(kept, delivery)
+	}
+	for i, j := 0, len(kept)-1; i < j; i, j = i+1, j-1 {
+		kept[i], kept[j] = kept[j], kept[i]
+	}
+	m.deliveries = kept
+}
+
+// flush saves the state when it changed, it needs the lock held
+func (m *Manager) flush() {
+	if !m.dirty || m.Store == nil {
+		return
+	}
+	state := &State{Webhooks: make([]*wiki_domain.Webhook, 0, len(m.webhooks)), Deliveries: m.deliveries}
+	for _, webhook := range m.webhooks {
+		state.Webhooks = append(state.Webhooks, webhook)
+	}
+	sort.Slice(state.Webhooks, func(i, j int) bool { return state.Webhooks[i].ID < state.Webhooks[j].ID })
+	if err := m.Store.Save(state); err != nil {
+		log.Printf("Webhooks: failed to save: %s", err.Error())
+		return
+	}
+	m.dirty = false
+}
+
+func (m *Manager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func public(webhook *wiki_domain.Webhook) *wiki_domain.Webhook {
+	copied := *webhook
+	copied.Secret, copied.Seen = "", nil
+	return &copied
+}
+
+// tenantID is the ID a webhook of the tenant is stored with, empty for requests without one
+func tenantID(tenant *wiki_domain.Tenant) string {
+	if tenant == nil {
+		return ""
+	}
+	return tenant.ID
+}
+
+func cleanNames(names []string) []string {
+	cleaned := []string{}
+	for _, name := range names {
+		name = strings.ReplaceAll(strings.TrimSpace(name), " ", "_")
+		if name != "" && !contains(cleaned, name) {
+			cleaned = append(cleaned, name)
+		}
+	}
+	return cleaned
+}
+
+func newID(size int) string {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(bytes)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
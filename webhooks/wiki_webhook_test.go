@@ -0,0 +1,297 @@
+package wiki_webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	wiki_domain "wiki-names/domains"
+	wiki_provider "wiki-names/providers"
+	wiki_watch "wiki-names/watchers"
+)
+
+type providerMock struct {
+	wiki_provider.WikiProviderStruct
+	mu           sync.Mutex
+	descriptions map[string]string
+}
+
+// We are mocking the provider method "GetShortDescriptions", names missing from descriptions have none
+func (pm *providerMock) GetShortDescriptions(ctx context.Context, locale string, titles []string) (map[string]string, *wiki_domain.WikiError) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	descriptions := map[string]string{}
+	for _, title := range titles {
+		if description, ok := pm.descriptions[title]; ok {
+			descriptions[title] = description
+		}
+	}
+	return descriptions, nil
+}
+
+// We are mocking the provider method "GetCategoryMembers", Turing Award laureates come in two pages
+func (pm *providerMock) GetCategoryMembers(ctx context.Context, query wiki_domain.CategoryQuery) (*wiki_domain.CategoryMembers, *wiki_domain.WikiError) {
+	if query.Cursor == "" {
+		return &wiki_domain.CategoryMembers{Members: []wiki_domain.CategoryMember{{Title: "Alan Kay"}, {Title: "Yoshua Bengio"}}, Cursor: "page|2"}, nil
+	}
+	return &wiki_domain.CategoryMembers{Members: []wiki_domain.CategoryMember{{Title: "Geoffrey Hinton"}}}, nil
+}
+
+func (pm *providerMock) set(title string, description string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.descriptions[title] = description
+}
+
+func setupManager(t *testing.T, descriptions map[string]string) (*Manager, *wiki_watch.Hub, *providerMock) {
+	mock := &providerMock{descriptions: descriptions}
+	original := wiki_provider.WikiProvider
+	wiki_provider.WikiProvider = mock
+	t.Cleanup(func() { wiki_provider.WikiProvider = original })
+	manager := NewManager()
+	manager.Hub = wiki_watch.NewHub()
+	// The receivers listen on loopback, which the manager's own client refuses to reach
+	manager.Client = &http.Client{Timeout: time.Second}
+	return manager, manager.Hub, mock
+}
+
+// receiver records what a webhook receives, answering with the statuses given in turn and then 200
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, *[]*http.Request, *[][]byte) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ := io.ReadAll(r.Body)
+		requests, bodies = append(requests, r), append(bodies, body)
+		status := http.StatusOK
+		if len(requests) <= len(statuses) {
+			status = statuses[len(requests)-1]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &bodies
+}
+
+func pending(manager *Manager) []wiki_domain.Delivery {
+	return manager.Deliveries(wiki_domain.DeliveryQuery{Status: wiki_domain.DeliveryPending})
+}
+
+func TestDeliverSignedChange(t *testing.T) {
+	manager, hub, mock := setupManager(t, map[string]string{"Yoshua_Bengio": "Canadian computer scientist"})
+	server, requests, bodies := receiver(t)
+
+	webhook, err := manager.Create(testTenant, wiki_domain.WebhookRequest{URL: server.URL, Names: []string{"Yoshua Bengio"}, Secret: "0123456789abcdef"})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "0123456789abcdef", webhook.Secret)
+	manager.follow(context.Background(), webhook.ID)
+	hub.Interval = 10 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	// The first description seen is only recorded
+	assert.Eventually(t, func() bool {
+		manager.mu.Lock()
+		defer manager.mu.Unlock()
+		return manager.webhooks[webhook.ID].Seen["Yoshua_Bengio"] != ""
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, pending(manager))
+
+	mock.set("Yoshua_Bengio", "Canadian computer scientist and Turing Award laureate")
+	assert.Eventually(t, func() bool { return len(pending(manager)) == 1 }, time.Second, time.Millisecond)
+	for _, delivery := range manager.due() {
+		manager.attempt(context.Background(), delivery)
+	}
+
+	assert.Len(t, *requests, 1)
+	request, body := (*requests)[0], (*bodies)[0]
+	assert.EqualValues(t, "description.changed", request.Header.Get(EventHeader))
+	assert.EqualValues(t, "sha256="+Sign("0123456789abcdef", request.Header.Get(TimestampHeader), body), request.Header.Get(SignatureHeader))
+	var payload wiki_domain.WebhookPayload
+	assert.Nil(t, json.Unmarshal(body, &payload))
+	assert.EqualValues(t, request.Header.Get(DeliveryHeader), payload.ID)
+	assert.EqualValues(t, webhook.ID, payload.Webhook)
+	assert.EqualValues(t, "Canadian computer scientist", payload.Data.Previous)
+	assert.EqualValues(t, "Canadian computer scientist and Turing Award laureate", payload.Data.ShortDescription)
+
+	delivered := manager.Deliveries(wiki_domain.DeliveryQuery{Webhook: webhook.ID})
+	assert.Len(t, delivered, 1)
+	assert.EqualValues(t, wiki_domain.DeliveryDelivered, delivered[0].Status)
+	assert.EqualValues(t, 1, delivered[0].Attempts)
+	assert.EqualValues(t, http.StatusOK, delivered[0].ResponseStatus)
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	manager, _, _ := setupManager(t, map[string]string{})
+	manager.MaxAttempts, manager.Backoff = 2, time.Minute
+	server, requests, _ := receiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	webhook, _ := manager.Create(testTenant, wiki_domain.WebhookRequest{URL: server.URL, Names: []string{"a"}})
+	manager.observe(webhook.ID, wiki_domain.WatchEvent{Name: "a", Language: "en", ShortDescription: "added"})
+
+	due := manager.due()
+	assert.Len(t, due, 1)
+	manager.attempt(context.Background(), due[0])
+	delivery := pending(manager)[0]
+	assert.EqualValues(t, 1, delivery.Attempts)
+	assert.EqualValues(t, "unexpected status code 500", delivery.LastError)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), delivery.NextAttempt, 5*time.Second)
+	// Not due again before its backoff
+	assert.Empty(t, manager.due())
+
+	due[0].NextAttempt = time.Now()
+	due = manager.due()
+	manager.attempt(context.Background(), due[0])
+	assert.Len(t, *requests, 2)
+	dead := manager.Deliveries(wiki_domain.DeliveryQuery{Status: wiki_domain.DeliveryDead})
+	assert.Len(t, dead, 1)
+	assert.EqualValues(t, http.StatusServiceUnavailable, dead[0].ResponseStatus)
+
+	// Only dead letters can be retried or discarded
+	retried, err := manager.Retry(dead[0].ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 0, retried.Attempts)
+	assert.EqualValues(t, wiki_domain.DeliveryPending, retried.Status)
+	assert.EqualValues(t, http.StatusNotFound, manager.Discard(dead[0].ID).Code)
+	manager.attempt(context.Background(), manager.due()[0])
+	assert.EqualValues(t, wiki_domain.DeliveryDelivered, manager.Deliveries(wiki_domain.DeliveryQuery{})[0].Status)
+}
+
+func TestBackoff(t *testing.T) {
+	manager := NewManager()
+	manager.Backoff, manager.MaxBackoff = time.Second, 10*time.Second
+	assert.EqualValues(t, time.Second, manager.backoff(1))
+	assert.EqualValues(t, 4*time.Second, manager.backoff(3))
+	assert.EqualValues(t, 10*time.Second, manager.backoff(20))
+}
+
+func TestPersistAcrossRestarts(t *testing.T) {
+	manager, _, _ := setupManager(t, map[string]string{})
+	store := &FileStore{Path: filepath.Join(t.TempDir(), "state", "webhooks.json")}
+	manager.Store = store
+	assert.Nil(t, manager.Load())
+	webhook, _ := manager.Create(&wiki_domain.Tenant{ID: "acme"}, wiki_domain.WebhookRequest{URL: "https://example.org/hook", Names: []string{"a", "b"}})
+	manager.observe(webhook.ID, wiki_domain.WatchEvent{Name: "a", Language: "en", ShortDescription: "one", Initial: true})
+	manager.observe(webhook.ID, wiki_domain.WatchEvent{Name: "b", Language: "en", ShortDescription: "two", Previous: "zero"})
+	manager.mu.Lock()
+	manager.flush()
+	manager.mu.Unlock()
+
+	restarted := NewManager()
+	restarted.Store = store
+	assert.Nil(t, restarted.Load())
+	assert.EqualValues(t, []wiki_domain.Webhook{*public(manager.webhooks[webhook.ID])}, restarted.List("acme"))
+	assert.Empty(t, restarted.List("someone-else"))
+	assert.Len(t, pending(restarted), 1)
+	assert.EqualValues(t, webhook.Secret, restarted.webhooks[webhook.ID].Secret)
+
+	// A description that changed while we were down comes back as the hub's first, and is still delivered
+	restarted.observe(webhook.ID, wiki_domain.WatchEvent{Name: "a", Language: "en", ShortDescription: "uno", Initial: true})
+	deliveries := pending(restarted)
+	assert.Len(t, deliveries, 2)
+	var payload wiki_domain.WebhookPayload
+	assert.Nil(t, json.Unmarshal(deliveries[0].Payload, &payload))
+	assert.EqualValues(t, "one", payload.Data.Previous)
+	assert.False(t, payload.Data.Initial)
+}
+
+func TestFollowCategories(t *testing.T) {
+	manager, _, _ := setupManager(t, map[string]string{})
+	webhook, err := manager.Create(testTenant, wiki_domain.WebhookRequest{URL: "https://example.org/hook", Names: []string{"Yoshua_Bengio"}, Categories: []string{"Turing Award laureates"}})
+	assert.Nil(t, err)
+	manager.follow(context.Background(), webhook.ID)
+	assert.EqualValues(t, []string{"Yoshua_Bengio", "Alan_Kay", "Geoffrey_Hinton"}, manager.follows[webhook.ID].Names)
+
+	// Deleting stops following
+	events := manager.follows[webhook.ID].Events
+	assert.Nil(t, manager.Delete(testTenant, webhook.ID))
+	_, ok := <-events
+	assert.False(t, ok)
+	assert.EqualValues(t, wiki_domain.ErrNotFound, manager.Delete(testTenant, webhook.ID).ErrorCode)
+}
+
+func TestTenantWebhooks(t *testing.T) {
+	manager, _, _ := setupManager(t, map[string]string{})
+	acme, globex := &wiki_domain.Tenant{ID: "acme"}, &wiki_domain.Tenant{ID: "globex"}
+	webhook, err := manager.Create(acme, wiki_domain.WebhookRequest{URL: "https://example.org/hook", Names: []string{"a"}})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "acme", webhook.Tenant)
+	assert.EqualValues(t, "en", webhook.Locale)
+
+	// The locale defaults to the tenant's
+	french, err := manager.Create(&wiki_domain.Tenant{ID: "acme", DefaultLocale: "fr"}, wiki_domain.WebhookRequest{URL: "https://example.org/hook", Names: []string{"a"}})
+	assert.Nil(t, err)
+	assert.EqualValues(t, "fr", french.Locale)
+
+	// Other tenants, and requests without one, can't tell the webhook exists
+	for _, tenant := range []*wiki_domain.Tenant{globex, nil} {
+		_, err = manager.Get(tenant, webhook.ID)
+		assert.EqualValues(t, wiki_domain.ErrNotFound, err.ErrorCode)
+		assert.EqualValues(t, wiki_domain.ErrNotFound, manager.Delete(tenant, webhook.ID).ErrorCode)
+	}
+	got, err := manager.Get(acme, webhook.ID)
+	assert.Nil(t, err)
+	assert.EqualValues(t, webhook.ID, got.ID)
+	assert.Nil(t, manager.Delete(acme, webhook.ID))
+}
+
+func TestClientRefusesPrivateAddresses(t *testing.T) {
+	server, requests, _ := receiver(t)
+	client := NewManager().Client
+	for _, target := range []string{server.URL, "http://169.254.169.254/latest/meta-data/", "http://10.0.0.1/", "http://[::1]/", "http://0.0.0.0/"} {
+		_, err := client.Get(target)
+		if assert.NotNil(t, err, target) {
+			assert.Contains(t, err.Error(), "callbacks can't reach private addresses", target)
+		}
+	}
+	assert.Empty(t, *requests)
+
+	assert.True(t, publicIP(net.ParseIP("93.184.216.34")))
+	assert.True(t, publicIP(net.ParseIP("2606:2800:220:1:248:1893:25c8:1946")))
+	assert.False(t, publicIP(net.ParseIP("192.168.1.10")))
+	assert.False(t, publicIP(net.ParseIP("fd00::1")))
+	assert.False(t, publicIP(net.ParseIP("fe80::1")))
+	assert.False(t, publicIP(net.ParseIP("::ffff:127.0.0.1")))
+}
+
+// testTenant owns the webhooks of the tests that don't compare tenants
+var testTenant = &wiki_domain.Tenant{ID: "test"}
+
+func TestCreateValidation(t *testing.T) {
+	manager, _, _ := setupManager(t, map[string]string{})
+	manager.MaxWebhooks = 1
+
+	_, err := manager.Create(testTenant, wiki_domain.WebhookRequest{URL: "ftp://example.org", Names: []string{"a"}})
+	assert.EqualValues(t, wiki_domain.ErrInvalidRequest, err.ErrorCode)
+	_, err = manager.Create(testTenant, wiki_domain.WebhookRequest{URL: "https://example.org"})
+	assert.EqualValues(t, wiki_domain.ErrInvalidRequest, err.ErrorCode)
+	_, err = manager.Create(testTenant, wiki_domain.WebhookRequest{URL: "https://example.org", Names: []string{"a"}, Locale: "xx-nowhere"})
+	assert.EqualValues(t, wiki_domain.ErrInvalidLocale, err.ErrorCode)
+	_, err = manager.Create(&wiki_domain.Tenant{ID: "acme", AllowedLocales: []string{"fr"}}, wiki_domain.WebhookRequest{URL: "https://example.org", Names: []string{"a"}, Locale: "de"})
+	assert.EqualValues(t, wiki_domain.ErrNotEnabled, err.ErrorCode)
+
+	webhook, err := manager.Create(testTenant, wiki_domain.WebhookRequest{URL: "https://example.org", Names: []string{"a"}})
+	assert.Nil(t, err)
+	assert.Len(t, webhook.Secret, 64)
+	_, err = manager.Create(testTenant, wiki_domain.WebhookRequest{URL: "https://example.org", Names: []string{"b"}})
+	assert.EqualValues(t, wiki_domain.ErrTooManySubscribers, err.ErrorCode)
+	got, _ := manager.Get(testTenant, webhook.ID)
+	assert.Empty(t, got.Secret)
+
+	// The cap is per tenant, and there's no webhook without one
+	_, err = manager.Create(&wiki_domain.Tenant{ID: "globex"}, wiki_domain.WebhookRequest{URL: "https://example.org", Names: []string{"b"}})
+	assert.Nil(t, err)
+	_, err = manager.Create(nil, wiki_domain.WebhookRequest{URL: "https://example.org", Names: []string{"b"}})
+	assert.EqualValues(t, wiki_domain.ErrNotEnabled, err.ErrorCode)
+}